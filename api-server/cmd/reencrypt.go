@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/common/command"
+)
+
+type ReencryptOption struct {
+	ConfigPath string
+}
+
+func (opt *ReencryptOption) Complete(ctx context.Context, args []string, argsLenAtDash int) error {
+	return nil
+}
+
+func (opt *ReencryptOption) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Run encrypts the plain text secrets and the secrets of the rotated keys with the primary key,
+// the old keys can be removed from the keyring once it succeeded
+func (opt *ReencryptOption) Run(ctx context.Context, args []string) error {
+	err := loadConfig(opt.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	err = setupEncryption()
+	if err != nil {
+		return errors.Wrap(err, "setup encryption")
+	}
+
+	err = services.MigrateUp()
+	if err != nil {
+		return errors.Wrap(err, "migrate up db")
+	}
+
+	clusters, _, err := services.ClusterService.List(ctx, services.ListClusterOption{})
+	if err != nil {
+		return errors.Wrap(err, "list clusters")
+	}
+	reencryptedClusters := 0
+	for _, cluster := range clusters {
+		reencrypted, err := services.ClusterService.Reencrypt(ctx, cluster)
+		if err != nil {
+			return errors.Wrapf(err, "re-encrypt cluster %s", cluster.Name)
+		}
+		if reencrypted {
+			reencryptedClusters++
+		}
+	}
+
+	orgs, _, err := services.OrganizationService.List(ctx, services.ListOrganizationOption{})
+	if err != nil {
+		return errors.Wrap(err, "list organizations")
+	}
+	reencryptedOrgs := 0
+	for _, org := range orgs {
+		reencrypted, err := services.OrganizationService.Reencrypt(ctx, org)
+		if err != nil {
+			return errors.Wrapf(err, "re-encrypt organization %s", org.Name)
+		}
+		if reencrypted {
+			reencryptedOrgs++
+		}
+	}
+
+	logrus.Infof("re-encrypted %d of %d clusters and %d of %d organizations", reencryptedClusters, len(clusters), reencryptedOrgs, len(orgs))
+	return nil
+}
+
+func getReencryptCmd() *cobra.Command {
+	var opt ReencryptOption
+	cmd := &cobra.Command{
+		Use:   "reencrypt",
+		Short: "Encrypt the secrets with the primary key of the configured keyring",
+		Long:  "",
+		RunE:  command.MakeRunE(&opt),
+	}
+	cmd.Flags().StringVarP(&opt.ConfigPath, "config", "c", "./yatai-config.dev.yaml", "")
+	return cmd
+}
@@ -19,6 +19,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&command.GlobalCommandOption.Debug, "debug", "d", false, "debug mode, output verbose output")
 	rootCmd.AddCommand(getServeCmd())
 	rootCmd.AddCommand(getVersionCmd())
+	rootCmd.AddCommand(getReencryptCmd())
 }
 
 func Execute() {
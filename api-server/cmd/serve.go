@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,25 +18,73 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/bentoml/yatai/api-server/config"
+	"github.com/bentoml/yatai/api-server/controllers/controllersv1"
 	"github.com/bentoml/yatai/api-server/routes"
 	"github.com/bentoml/yatai/api-server/services"
 	"github.com/bentoml/yatai/api-server/services/tracking"
 	"github.com/bentoml/yatai/common/command"
+	"github.com/bentoml/yatai/common/envelope"
 	"github.com/bentoml/yatai/common/sync/errsgroup"
 )
 
-func addCron(ctx context.Context) {
-	c := cron.New()
+// cronJobs waits for the running jobs when it is stopped, cron.Cron.Stop only stops scheduling new ones
+type cronJobs struct {
+	c       *cron.Cron
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func (j *cronJobs) AddFunc(spec string, cmd func()) error {
+	return j.c.AddFunc(spec, func() {
+		j.mu.Lock()
+		if j.stopped {
+			j.mu.Unlock()
+			return
+		}
+		j.wg.Add(1)
+		j.mu.Unlock()
+		defer j.wg.Done()
+		cmd()
+	})
+}
+
+// Stop stops scheduling the jobs and waits for the running ones until the ctx is done
+func (j *cronJobs) Stop(ctx context.Context) error {
+	j.c.Stop()
+	j.mu.Lock()
+	j.stopped = true
+	j.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "wait for cron jobs")
+	}
+}
+
+func addCron(ctx context.Context) *cronJobs {
+	c := &cronJobs{c: cron.New()}
 	logger := logrus.New().WithField("cron", "sync env")
 
 	// Add cron for tracking lifecycle events
 	tracking.AddLifeCycleTrackingCron(ctx, c)
 
-	err := c.AddFunc("@every 1m", func() {
+	// the statuses are synced by services.DeploymentStatusReconciler on kube changes, this is a safety net for the missed ones
+	err := c.AddFunc("@every 10m", func() {
 		ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
 		defer cancel()
+		err := services.DeploymentStatusReconciler.WatchAll(ctx)
+		if err != nil {
+			logger.Errorf("watch deployment namespaces: %s", err.Error())
+		}
 		logger.Info("listing unsynced deployments")
-		deployments, err := services.DeploymentService.ListUnsynced(ctx)
+		deployments, err := services.DeploymentService.ListUnsynced(ctx, time.Minute*10)
 		if err != nil {
 			logger.Errorf("list unsynced deployments: %s", err.Error())
 		}
@@ -71,7 +122,89 @@ func addCron(ctx context.Context) {
 		logger.Errorf("cron add func failed: %s", err.Error())
 	}
 
-	c.Start()
+	err = c.AddFunc("@every 10s", func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+		defer cancel()
+		err := services.DeploymentRolloutService.AdvanceAll(ctx)
+		if err != nil {
+			logrus.WithField("cron", "deployment rollout").Errorf("advance deployment rollouts: %s", err.Error())
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	err = c.AddFunc("@every 30s", func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		err := services.KubeClientPool.ProbeAll(ctx)
+		if err != nil {
+			logrus.WithField("cron", "kube cluster probe").Errorf("probe kube clusters: %s", err.Error())
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	err = c.AddFunc("@every 1m", func() {
+		services.KubeInformerManager.EvictIdle()
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	err = c.AddFunc("@every 1m", func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		err := services.YataiComponentService.CheckHealthAll(ctx)
+		if err != nil {
+			logrus.WithField("cron", "yatai component health").Errorf("check yatai component health: %s", err.Error())
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	err = c.AddFunc("@every 1m", func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+		defer cancel()
+		err := services.DeploymentScheduleService.RunAll(ctx)
+		if err != nil {
+			logrus.WithField("cron", "deployment schedule").Errorf("run deployment schedules: %s", err.Error())
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	err = c.AddFunc("@every 1h", func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute*30)
+		defer cancel()
+		retentionLogger := logrus.New().WithField("cron", "retention")
+		retentionLogger.Info("collecting repositories by retention policies")
+		err := services.RetentionService.CollectAll(ctx)
+		if err != nil {
+			retentionLogger.Errorf("collect repositories: %s", err.Error())
+		}
+		retentionLogger.Info("collected repositories by retention policies")
+		deleted, err := services.DeploymentStatusTransitionService.Prune(ctx)
+		if err != nil {
+			retentionLogger.Errorf("prune deployment status transitions: %s", err.Error())
+		}
+		retentionLogger.Infof("pruned %d deployment status transitions", deleted)
+	})
+
+	if err != nil {
+		logger.Errorf("cron add func failed: %s", err.Error())
+	}
+
+	c.c.Start()
+	return c
 }
 
 type ServeOption struct {
@@ -97,24 +230,61 @@ func initSelfHost(ctx context.Context) error {
 	return err
 }
 
-func (opt *ServeOption) Run(ctx context.Context, args []string) error {
-	if !command.GlobalCommandOption.Debug {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	content, err := os.ReadFile(opt.ConfigPath)
+func loadConfig(configPath string) error {
+	content, err := os.ReadFile(configPath)
 	if err != nil {
-		return errors.Wrapf(err, "read config file: %s", opt.ConfigPath)
+		return errors.Wrapf(err, "read config file: %s", configPath)
 	}
 
 	err = yaml.Unmarshal(content, config.YataiConfig)
 	if err != nil {
-		return errors.Wrapf(err, "unmarshal config file: %s", opt.ConfigPath)
+		return errors.Wrapf(err, "unmarshal config file: %s", configPath)
 	}
 
 	err = config.PopulateYataiConfig()
 	if err != nil {
-		return errors.Wrapf(err, "populate config file: %s", opt.ConfigPath)
+		return errors.Wrapf(err, "populate config file: %s", configPath)
+	}
+	return nil
+}
+
+// setupEncryption enables the encryption of the secrets when a key is configured
+func setupEncryption() error {
+	encryptionConfig := config.YataiConfig.Encryption
+	if encryptionConfig == nil {
+		return nil
+	}
+	var provider envelope.KeyProvider
+	var err error
+	switch {
+	case encryptionConfig.KeyringFile != "":
+		provider, err = envelope.NewFileKeyProvider(encryptionConfig.KeyringFile)
+	case encryptionConfig.StaticKey != "":
+		provider, err = envelope.NewStaticKeyProvider(encryptionConfig.StaticKeyId, encryptionConfig.StaticKey)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	services.SecretService.SetKeyProvider(provider)
+	logrus.Infof("secrets are encrypted with key %s", provider.PrimaryKeyId())
+	return nil
+}
+
+func (opt *ServeOption) Run(ctx context.Context, args []string) error {
+	if !command.GlobalCommandOption.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	err := loadConfig(opt.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	err = setupEncryption()
+	if err != nil {
+		return errors.Wrap(err, "setup encryption")
 	}
 
 	err = services.MigrateUp()
@@ -129,7 +299,25 @@ func (opt *ServeOption) Run(ctx context.Context, args []string) error {
 		}
 	}
 
-	addCron(ctx)
+	// the signal ctx is done on SIGTERM, the jobs ctx is kept until the jobs are drained
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	jobsCtx, cancelJobs := context.WithCancel(ctx)
+	defer cancelJobs()
+
+	err = services.NotificationService.Listen(jobsCtx)
+	if err != nil {
+		return errors.Wrap(err, "listen to resource changes")
+	}
+
+	services.KubeInformerManager.Start(jobsCtx)
+
+	err = services.DeploymentStatusReconciler.Start(jobsCtx)
+	if err != nil {
+		return errors.Wrap(err, "start deployment status reconciler")
+	}
+
+	jobs := addCron(jobsCtx)
 
 	// nolint: contextcheck
 	router, err := routes.NewRouter()
@@ -149,7 +337,72 @@ func (opt *ServeOption) Run(ctx context.Context, args []string) error {
 		Handler:           router,
 		ReadHeaderTimeout: readHeaderTimeout,
 	}
-	return srv.ListenAndServe()
+	logrus.Infof("serving the metrics on 0.0.0.0:%d", config.YataiConfig.Server.MetricsPort)
+
+	metricsSrv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", config.YataiConfig.Server.MetricsPort),
+		Handler:           routes.NewMetricsRouter(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	go func() {
+		serveErr <- errors.Wrap(metricsSrv.ListenAndServe(), "serve metrics")
+	}()
+
+	select {
+	case err = <-serveErr:
+		return err
+	case <-signalCtx.Done():
+	}
+
+	services.HealthService.SetDraining()
+	// keep serving until the readiness probes and the load balancers noticed that the server is not ready anymore
+	preStopDelay := config.YataiConfig.Server.PreStopDelay
+	logrus.Infof("reporting not ready, closing the listener in %s", preStopDelay)
+	select {
+	case err = <-serveErr:
+		return err
+	case <-time.After(preStopDelay):
+	}
+
+	shutdownTimeout := config.YataiConfig.Server.ShutdownTimeout
+	logrus.Infof("shutting down, draining for at most %s", shutdownTimeout)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelDrain()
+
+	var eg errsgroup.Group
+	eg.Go(func() error {
+		// nolint: contextcheck
+		return errors.Wrap(srv.Shutdown(drainCtx), "shutdown http server")
+	})
+	eg.Go(func() error {
+		// nolint: contextcheck
+		return errors.Wrap(metricsSrv.Shutdown(drainCtx), "shutdown metrics server")
+	})
+	eg.Go(func() error {
+		// nolint: contextcheck
+		return controllersv1.CloseWebsockets(drainCtx)
+	})
+	eg.Go(func() error {
+		// nolint: contextcheck
+		return jobs.Stop(drainCtx)
+	})
+	eg.Go(func() error {
+		// nolint: contextcheck
+		return services.DeploymentStatusReconciler.Stop(drainCtx)
+	})
+	err = eg.Wait()
+	// the informers are stopped after the reconciler and the websockets removed their event handlers
+	services.KubeInformerManager.Stop()
+	cancelJobs()
+	if err != nil {
+		return errors.Wrap(err, "graceful shutdown")
+	}
+	logrus.Info("shut down gracefully")
+	return nil
 }
 
 func getServeCmd() *cobra.Command {
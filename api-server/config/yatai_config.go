@@ -11,12 +11,24 @@ import (
 )
 
 type YataiServerConfigYaml struct {
-	EnableHTTPS          bool   `yaml:"enable_https"`
-	Port                 uint   `yaml:"port"`
-	SessionSecretKey     string `yaml:"session_secret_key"`
-	MigrationDir         string `yaml:"migration_dir"`
-	ReadHeaderTimeout    int    `yaml:"read_header_timeout"`
-	TransmissionStrategy string `yaml:"transmission_strategy"`
+	EnableHTTPS                 bool          `yaml:"enable_https"`
+	Port                        uint          `yaml:"port"`
+	SessionSecretKey            string        `yaml:"session_secret_key"`
+	MigrationDir                string        `yaml:"migration_dir"`
+	ReadHeaderTimeout           int           `yaml:"read_header_timeout"`
+	TransmissionStrategy        string        `yaml:"transmission_strategy"`
+	ApiTokenRotationGracePeriod time.Duration `yaml:"api_token_rotation_grace_period"`
+	// NotificationBackend is memory or postgresql, postgresql delivers the resource changes to all the replicas
+	NotificationBackend string `yaml:"notification_backend"`
+	// ShutdownTimeout is how long the in-flight requests, the websockets and the cron jobs are drained on SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// PreStopDelay is how long the server keeps serving while reporting not ready on SIGTERM before the listener is closed,
+	// it must be longer than the readiness probe needs to notice it
+	PreStopDelay time.Duration `yaml:"pre_stop_delay"`
+	// MetricsPort is the port of the listener of the prometheus metrics, it is not the api port so that they are not exposed publicly
+	MetricsPort uint `yaml:"metrics_port"`
+	// ComponentHeartbeatWindow is how long a yatai component stays healthy without a heartbeat
+	ComponentHeartbeatWindow time.Duration `yaml:"component_heartbeat_window"`
 }
 
 type YataiPostgresqlConfigYaml struct {
@@ -53,15 +65,50 @@ type YataiDockerImageBuilderConfigYaml struct {
 	Privileged bool `yaml:"privileged"`
 }
 
+type YataiIdentityProviderConfigYaml struct {
+	Name          string   `yaml:"name"`
+	DisplayName   string   `yaml:"display_name"`
+	Type          string   `yaml:"type"`
+	Issuer        string   `yaml:"issuer"`
+	ClientId      string   `yaml:"client_id"`
+	ClientSecret  string   `yaml:"client_secret"`
+	RedirectURL   string   `yaml:"redirect_url"`
+	Scopes        []string `yaml:"scopes"`
+	UsernameClaim string   `yaml:"username_claim"`
+	GroupsClaim   string   `yaml:"groups_claim"`
+
+	RoleMappings []YataiIdentityProviderRoleMappingConfigYaml `yaml:"role_mappings"`
+}
+
+// YataiIdentityProviderRoleMappingConfigYaml grants the role on the organization, or on the cluster of the organization if cluster is set, to the members of the group
+type YataiIdentityProviderRoleMappingConfigYaml struct {
+	Group        string `yaml:"group"`
+	Organization string `yaml:"organization"`
+	Cluster      string `yaml:"cluster"`
+	Role         string `yaml:"role"`
+}
+
+// YataiEncryptionConfigYaml enables the encryption at rest of the kube configs of the clusters and the credentials of the organizations,
+// the keyring file takes precedence over the static key
+type YataiEncryptionConfigYaml struct {
+	StaticKeyId string `yaml:"static_key_id"`
+	// StaticKey is a base64 encoded AES-256 key
+	StaticKey   string `yaml:"static_key"`
+	KeyringFile string `yaml:"keyring_file"`
+}
+
 type YataiConfigYaml struct {
-	IsSaaS              bool                      `yaml:"is_saas"`
-	SaasDomainSuffix    string                    `yaml:"saas_domain_suffix"`
-	InCluster           bool                      `yaml:"in_cluster"`
-	Server              YataiServerConfigYaml     `yaml:"server"`
-	Postgresql          YataiPostgresqlConfigYaml `yaml:"postgresql"`
-	S3                  *YataiS3ConfigYaml        `yaml:"s3,omitempty"`
-	NewsURL             string                    `yaml:"news_url"`
-	InitializationToken string                    `yaml:"initialization_token"`
+	IsSaaS              bool                       `yaml:"is_saas"`
+	SaasDomainSuffix    string                     `yaml:"saas_domain_suffix"`
+	InCluster           bool                       `yaml:"in_cluster"`
+	Server              YataiServerConfigYaml      `yaml:"server"`
+	Postgresql          YataiPostgresqlConfigYaml  `yaml:"postgresql"`
+	S3                  *YataiS3ConfigYaml         `yaml:"s3,omitempty"`
+	Encryption          *YataiEncryptionConfigYaml `yaml:"encryption,omitempty"`
+	NewsURL             string                     `yaml:"news_url"`
+	InitializationToken string                     `yaml:"initialization_token"`
+
+	IdentityProviders []YataiIdentityProviderConfigYaml `yaml:"identity_providers"`
 }
 
 var YataiConfig = &YataiConfigYaml{}
@@ -143,6 +190,17 @@ func PopulateYataiConfig() error {
 	if YataiConfig.Server.Port == 0 {
 		YataiConfig.Server.Port = 7777
 	}
+	metricsPort, ok := os.LookupEnv(consts.EnvMetricsPort)
+	if ok {
+		metricsPort_, err := strconv.Atoi(metricsPort)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s from env to int", consts.EnvMetricsPort)
+		}
+		YataiConfig.Server.MetricsPort = uint(metricsPort_)
+	}
+	if YataiConfig.Server.MetricsPort == 0 {
+		YataiConfig.Server.MetricsPort = 7778
+	}
 
 	readHeaderTimeout, ok := os.LookupEnv(consts.EnvReadHeaderTimeout)
 	if ok {
@@ -158,6 +216,59 @@ func PopulateYataiConfig() error {
 		YataiConfig.Server.TransmissionStrategy = transmissionStrategy
 	}
 
+	if YataiConfig.Server.ApiTokenRotationGracePeriod == 0 {
+		YataiConfig.Server.ApiTokenRotationGracePeriod = 24 * time.Hour
+	}
+	apiTokenRotationGracePeriod, ok := os.LookupEnv(consts.EnvApiTokenRotationGracePeriod)
+	if ok {
+		apiTokenRotationGracePeriod_, err := time.ParseDuration(apiTokenRotationGracePeriod)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s from env to time.Duration", consts.EnvApiTokenRotationGracePeriod)
+		}
+		YataiConfig.Server.ApiTokenRotationGracePeriod = apiTokenRotationGracePeriod_
+	}
+
+	if YataiConfig.Server.ShutdownTimeout == 0 {
+		YataiConfig.Server.ShutdownTimeout = 25 * time.Second
+	}
+	shutdownTimeout, ok := os.LookupEnv(consts.EnvShutdownTimeout)
+	if ok {
+		shutdownTimeout_, err := time.ParseDuration(shutdownTimeout)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s from env to time.Duration", consts.EnvShutdownTimeout)
+		}
+		YataiConfig.Server.ShutdownTimeout = shutdownTimeout_
+	}
+
+	if YataiConfig.Server.PreStopDelay == 0 {
+		YataiConfig.Server.PreStopDelay = 15 * time.Second
+	}
+	preStopDelay, ok := os.LookupEnv(consts.EnvPreStopDelay)
+	if ok {
+		preStopDelay_, err := time.ParseDuration(preStopDelay)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s from env to time.Duration", consts.EnvPreStopDelay)
+		}
+		YataiConfig.Server.PreStopDelay = preStopDelay_
+	}
+
+	if YataiConfig.Server.ComponentHeartbeatWindow == 0 {
+		YataiConfig.Server.ComponentHeartbeatWindow = 5 * time.Minute
+	}
+	componentHeartbeatWindow, ok := os.LookupEnv(consts.EnvComponentHeartbeatWindow)
+	if ok {
+		componentHeartbeatWindow_, err := time.ParseDuration(componentHeartbeatWindow)
+		if err != nil {
+			return errors.Wrapf(err, "convert %s from env to time.Duration", consts.EnvComponentHeartbeatWindow)
+		}
+		YataiConfig.Server.ComponentHeartbeatWindow = componentHeartbeatWindow_
+	}
+
+	notificationBackend, ok := os.LookupEnv(consts.EnvNotificationBackend)
+	if ok {
+		YataiConfig.Server.NotificationBackend = notificationBackend
+	}
+
 	initializationToken, ok := os.LookupEnv(consts.EnvInitializationToken)
 	if ok {
 		YataiConfig.InitializationToken = initializationToken
@@ -201,5 +312,28 @@ func PopulateYataiConfig() error {
 		makesureS3IsNotNil()
 		YataiConfig.S3.BucketName = s3BucketName
 	}
+	makesureEncryptionIsNotNil := func() {
+		if YataiConfig.Encryption == nil {
+			YataiConfig.Encryption = &YataiEncryptionConfigYaml{}
+		}
+	}
+	encryptionStaticKeyId, ok := os.LookupEnv(consts.EnvEncryptionStaticKeyId)
+	if ok {
+		makesureEncryptionIsNotNil()
+		YataiConfig.Encryption.StaticKeyId = encryptionStaticKeyId
+	}
+	encryptionStaticKey, ok := os.LookupEnv(consts.EnvEncryptionStaticKey)
+	if ok {
+		makesureEncryptionIsNotNil()
+		YataiConfig.Encryption.StaticKey = encryptionStaticKey
+	}
+	encryptionKeyringFile, ok := os.LookupEnv(consts.EnvEncryptionKeyringFile)
+	if ok {
+		makesureEncryptionIsNotNil()
+		YataiConfig.Encryption.KeyringFile = encryptionKeyringFile
+	}
+	if YataiConfig.Encryption != nil && YataiConfig.Encryption.StaticKeyId == "" {
+		YataiConfig.Encryption.StaticKeyId = "static"
+	}
 	return nil
 }
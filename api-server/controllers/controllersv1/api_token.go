@@ -9,6 +9,7 @@ import (
 
 	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/config"
 	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
 	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
@@ -43,6 +44,44 @@ func (s *GetApiTokenSchema) GetApiToken(ctx context.Context) (*models.ApiToken,
 	return apiToken, nil
 }
 
+// ApiTokenWithPrefixSchema exposes the visible prefix of the secret so that users can tell their tokens apart
+type ApiTokenWithPrefixSchema struct {
+	schemasv1.ApiTokenSchema
+	TokenPrefix            string     `json:"token_prefix"`
+	RotatedAt              *time.Time `json:"rotated_at"`
+	PreviousTokenExpiredAt *time.Time `json:"previous_token_expired_at"`
+}
+
+type ApiTokenWithPrefixListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*ApiTokenWithPrefixSchema `json:"items"`
+}
+
+func toApiTokenWithPrefixSchemas(ctx context.Context, apiTokens []*models.ApiToken) ([]*ApiTokenWithPrefixSchema, error) {
+	apiTokenSchemas, err := transformersv1.ToApiTokenSchemas(ctx, apiTokens)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*ApiTokenWithPrefixSchema, 0, len(apiTokens))
+	for i, apiTokenSchema := range apiTokenSchemas {
+		res = append(res, &ApiTokenWithPrefixSchema{
+			ApiTokenSchema:         *apiTokenSchema,
+			TokenPrefix:            apiTokens[i].TokenPrefix,
+			RotatedAt:              apiTokens[i].RotatedAt,
+			PreviousTokenExpiredAt: apiTokens[i].PreviousTokenExpiredAt,
+		})
+	}
+	return res, nil
+}
+
+func toApiTokenWithPrefixSchema(ctx context.Context, apiToken *models.ApiToken) (*ApiTokenWithPrefixSchema, error) {
+	res, err := toApiTokenWithPrefixSchemas(ctx, []*models.ApiToken{apiToken})
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
 type CreateApiTokenSchema struct {
 	schemasv1.CreateApiTokenSchema
 	GetOrganizationSchema
@@ -77,7 +116,7 @@ type UpdateApiTokenSchema struct {
 	GetApiTokenSchema
 }
 
-func (c *apiTokenController) Update(ctx *gin.Context, schema *UpdateApiTokenSchema) (*schemasv1.ApiTokenSchema, error) {
+func (c *apiTokenController) Update(ctx *gin.Context, schema *UpdateApiTokenSchema) (*ApiTokenWithPrefixSchema, error) {
 	apiToken, err := schema.GetApiToken(ctx)
 	if err != nil {
 		return nil, err
@@ -98,15 +137,63 @@ func (c *apiTokenController) Update(ctx *gin.Context, schema *UpdateApiTokenSche
 	if err != nil {
 		return nil, errors.Wrap(err, "update apiToken")
 	}
-	return transformersv1.ToApiTokenSchema(ctx, apiToken)
+	return toApiTokenWithPrefixSchema(ctx, apiToken)
 }
 
-func (c *apiTokenController) Get(ctx *gin.Context, schema *GetApiTokenSchema) (*schemasv1.ApiTokenSchema, error) {
+type RotateApiTokenSchema struct {
+	GetApiTokenSchema
+	GracePeriodSeconds *uint `json:"grace_period_seconds"`
+}
+
+func (c *apiTokenController) Rotate(ctx *gin.Context, schema *RotateApiTokenSchema) (*schemasv1.ApiTokenFullSchema, error) {
 	apiToken, err := schema.GetApiToken(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return transformersv1.ToApiTokenSchema(ctx, apiToken)
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gracePeriod := config.YataiConfig.Server.ApiTokenRotationGracePeriod
+	if schema.GracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*schema.GracePeriodSeconds) * time.Second
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	apiToken, err = services.ApiTokenService.Rotate(ctx_, apiToken, gracePeriod)
+	if err != nil {
+		return nil, errors.Wrap(err, "rotate apiToken")
+	}
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	_, err = services.EventService.Create(ctx_, services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &apiToken.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeApiToken,
+		ResourceId:     apiToken.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "rotated",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create event")
+	}
+	return transformersv1.ToApiTokenFullSchema(ctx_, apiToken)
+}
+
+func (c *apiTokenController) Get(ctx *gin.Context, schema *GetApiTokenSchema) (*ApiTokenWithPrefixSchema, error) {
+	apiToken, err := schema.GetApiToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toApiTokenWithPrefixSchema(ctx, apiToken)
 }
 
 func (c *apiTokenController) Delete(ctx *gin.Context, schema *GetApiTokenSchema) (*schemasv1.ApiTokenSchema, error) {
@@ -126,7 +213,7 @@ type ListApiTokenSchema struct {
 	GetOrganizationSchema
 }
 
-func (c *apiTokenController) List(ctx *gin.Context, schema *ListApiTokenSchema) (*schemasv1.ApiTokenListSchema, error) {
+func (c *apiTokenController) List(ctx *gin.Context, schema *ListApiTokenSchema) (*ApiTokenWithPrefixListSchema, error) {
 	user, err := services.GetCurrentUser(ctx)
 	if err != nil {
 		return nil, err
@@ -149,8 +236,8 @@ func (c *apiTokenController) List(ctx *gin.Context, schema *ListApiTokenSchema)
 		return nil, errors.Wrap(err, "list apiTokens")
 	}
 
-	apiTokenSchemas, err := transformersv1.ToApiTokenSchemas(ctx, apiTokens)
-	return &schemasv1.ApiTokenListSchema{
+	apiTokenSchemas, err := toApiTokenWithPrefixSchemas(ctx, apiTokens)
+	return &ApiTokenWithPrefixListSchema{
 		BaseListSchema: schemasv1.BaseListSchema{
 			Total: total,
 			Start: schema.Start,
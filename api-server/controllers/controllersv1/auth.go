@@ -1,6 +1,7 @@
 package controllersv1
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -91,3 +92,93 @@ func (*authController) ResetPassword(ctx *gin.Context, schema *schemasv1.ResetPa
 
 	return transformersv1.ToUserSchema(ctx, user)
 }
+
+type IdentityProviderSchema struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+func (*authController) ListIdentityProviders(ctx *gin.Context) ([]*IdentityProviderSchema, error) {
+	confs := services.IdentityProviderService.ListConfigs()
+	res := make([]*IdentityProviderSchema, 0, len(confs))
+	for _, conf := range confs {
+		displayName := conf.DisplayName
+		if displayName == "" {
+			displayName = conf.Name
+		}
+		res = append(res, &IdentityProviderSchema{
+			Name:        conf.Name,
+			DisplayName: displayName,
+		})
+	}
+	return res, nil
+}
+
+// isSafeRedirect only allows redirecting to a path of yatai itself after login, otherwise the login would be an open redirect
+func isSafeRedirect(redirect string) bool {
+	return strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//") && !strings.HasPrefix(redirect, "/\\")
+}
+
+func (*authController) IdentityProviderLogin(ctx *gin.Context) {
+	provider, err := services.IdentityProviderService.Get(ctx, ctx.Param("identityProviderName"))
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	redirect := ctx.Query("redirect")
+	if !isSafeRedirect(redirect) {
+		redirect = "/"
+	}
+	state := &scookie.IdentityProviderLoginState{
+		State:    utils.RandString(32),
+		Nonce:    utils.RandString(32),
+		Redirect: redirect,
+	}
+	if err = scookie.SetIdentityProviderLoginStateToCookie(ctx, provider.GetName(), state); err != nil {
+		abortWithError(ctx, errors.Wrap(err, "set login state cookie"))
+		return
+	}
+	ctx.Redirect(http.StatusFound, provider.AuthCodeURL(state.State, state.Nonce))
+}
+
+func (*authController) IdentityProviderCallback(ctx *gin.Context) {
+	provider, err := services.IdentityProviderService.Get(ctx, ctx.Param("identityProviderName"))
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	state, err := scookie.PopIdentityProviderLoginStateFromCookie(ctx, provider.GetName())
+	if err != nil {
+		abortWithError(ctx, errors.Wrap(err, "get login state cookie"))
+		return
+	}
+	if state == nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(ctx.Query("state"))) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid login state, please login again",
+		})
+		return
+	}
+	if errCode := ctx.Query("error"); errCode != "" {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{
+			"error": strings.TrimSpace(errCode + " " + ctx.Query("error_description")),
+		})
+		return
+	}
+	identity, err := provider.Authenticate(ctx, ctx.Query("code"), state.Nonce)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{
+			"error": errors.Wrapf(err, "authenticate with identity provider %s", provider.GetName()).Error(),
+		})
+		return
+	}
+	user, err := services.IdentityProviderService.LoginUser(ctx, identity)
+	if err != nil {
+		abortWithError(ctx, errors.Wrap(err, "login user"))
+		return
+	}
+	if err = scookie.SetUsernameToCookie(ctx, user.Name); err != nil {
+		abortWithError(ctx, errors.Wrap(err, "set login cookie"))
+		return
+	}
+	ctx.Redirect(http.StatusFound, state.Redirect)
+}
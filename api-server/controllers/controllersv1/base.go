@@ -1,6 +1,14 @@
 package controllersv1
 
-import "github.com/gorilla/websocket"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
 
 // nolint: unused
 type baseController struct{}
@@ -9,3 +17,75 @@ var wsUpgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 }
+
+// wsConns tracks the open websockets, http.Server.Shutdown does not wait for hijacked connections
+var wsConns = struct {
+	sync.Mutex
+	conns   map[*websocket.Conn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}{
+	conns: make(map[*websocket.Conn]struct{}),
+}
+
+func upgradeWebsocket(ctx *gin.Context) (*websocket.Conn, error) {
+	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return nil, err
+	}
+	wsConns.Lock()
+	defer wsConns.Unlock()
+	if wsConns.closing {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"), time.Now().Add(time.Second))
+		_ = conn.Close()
+		return nil, errors.New("server is shutting down")
+	}
+	wsConns.conns[conn] = struct{}{}
+	wsConns.wg.Add(1)
+	return conn, nil
+}
+
+func closeWebsocket(conn *websocket.Conn) error {
+	wsConns.Lock()
+	if _, ok := wsConns.conns[conn]; ok {
+		delete(wsConns.conns, conn)
+		wsConns.wg.Done()
+	}
+	wsConns.Unlock()
+	return conn.Close()
+}
+
+// CloseWebsockets asks the clients of the open websockets to go away and waits for their handlers to return,
+// the websockets which are still open when the ctx is done are closed forcibly
+func CloseWebsockets(ctx context.Context) error {
+	wsConns.Lock()
+	wsConns.closing = true
+	conns := make([]*websocket.Conn, 0, len(wsConns.conns))
+	for conn := range wsConns.conns {
+		conns = append(conns, conn)
+	}
+	wsConns.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+	for _, conn := range conns {
+		_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wsConns.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	wsConns.Lock()
+	for conn := range wsConns.conns {
+		_ = conn.Close()
+	}
+	wsConns.Unlock()
+	return errors.Wrap(ctx.Err(), "wait for websockets to close")
+}
@@ -63,7 +63,6 @@ func (c *bentoController) canUpdate(ctx context.Context, bento *models.Bento) er
 	return BentoRepositoryController.canUpdate(ctx, bentoRepository)
 }
 
-// nolint: unused
 func (c *bentoController) canOperate(ctx context.Context, bento *models.Bento) error {
 	bentoRepository, err := services.BentoRepositoryService.GetAssociatedBentoRepository(ctx, bento)
 	if err != nil {
@@ -536,6 +535,57 @@ func (c *bentoController) Get(ctx *gin.Context, schema *GetBentoSchema) (*schema
 	return transformersv1.ToBentoFullSchema(ctx, bento)
 }
 
+func (c *bentoController) Delete(ctx *gin.Context, schema *GetBentoSchema) (*schemasv1.BentoSchema, error) {
+	bento, err := schema.GetBento(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, bento); err != nil {
+		return nil, err
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	// the event must be created before the deletion so that it can record the resource name
+	_, err = services.EventService.Create(ctx_, services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &org.ID,
+		ResourceType:   modelschemas.ResourceTypeBento,
+		ResourceId:     bento.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "deleted",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create event")
+	}
+	bentoSchema, err := transformersv1.ToBentoSchema(ctx_, bento)
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.BentoService.Delete(ctx_, bento)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete bento")
+	}
+	return bentoSchema, nil
+}
+
 type ListBentoDeploymentSchema struct {
 	schemasv1.ListQuerySchema
 	GetBentoSchema
@@ -10,6 +10,7 @@ import (
 	"github.com/huandu/xstrings"
 	"github.com/pkg/errors"
 
+	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai-schemas/schemasv1"
 	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
@@ -57,7 +58,6 @@ func (c *bentoRepositoryController) canUpdate(ctx context.Context, bentoReposito
 	return OrganizationController.canUpdate(ctx, organization)
 }
 
-// nolint: unused
 func (c *bentoRepositoryController) canOperate(ctx context.Context, bentoRepository *models.BentoRepository) error {
 	organization, err := services.OrganizationService.GetAssociatedOrganization(ctx, bentoRepository)
 	if err != nil {
@@ -130,6 +130,106 @@ func (c *bentoRepositoryController) Get(ctx *gin.Context, schema *GetBentoReposi
 	return transformersv1.ToBentoRepositorySchema(ctx, bentoRepository)
 }
 
+func (c *bentoRepositoryController) Delete(ctx *gin.Context, schema *GetBentoRepositorySchema) (*schemasv1.BentoRepositorySchema, error) {
+	bentoRepository, err := schema.GetBentoRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, bentoRepository); err != nil {
+		return nil, err
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	// the event must be created before the deletion so that it can record the resource name
+	_, err = services.EventService.Create(ctx_, services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &org.ID,
+		ResourceType:   modelschemas.ResourceTypeBentoRepository,
+		ResourceId:     bentoRepository.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "deleted",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create event")
+	}
+	bentoRepositorySchema, err := transformersv1.ToBentoRepositorySchema(ctx_, bentoRepository)
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.BentoRepositoryService.Delete(ctx_, bentoRepository)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete bentoRepository")
+	}
+	return bentoRepositorySchema, nil
+}
+
+func (c *bentoRepositoryController) GetRetentionPolicy(ctx *gin.Context, schema *GetBentoRepositorySchema) (*RetentionPolicySchema, error) {
+	bentoRepository, err := schema.GetBentoRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, bentoRepository); err != nil {
+		return nil, err
+	}
+	return toRetentionPolicySchema(bentoRepository.RetentionPolicy), nil
+}
+
+type UpdateBentoRepositoryRetentionPolicySchema struct {
+	RetentionPolicySchema
+	GetBentoRepositorySchema
+}
+
+func (c *bentoRepositoryController) UpdateRetentionPolicy(ctx *gin.Context, schema *UpdateBentoRepositoryRetentionPolicySchema) (*RetentionPolicySchema, error) {
+	bentoRepository, err := schema.GetBentoRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, bentoRepository); err != nil {
+		return nil, err
+	}
+	retentionPolicy := toRetentionPolicy(schema.RetentionPolicySchema)
+	bentoRepository, err = services.BentoRepositoryService.Update(ctx, bentoRepository, services.UpdateBentoRepositoryOption{
+		RetentionPolicy: &retentionPolicy,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update bentoRepository retention policy")
+	}
+	return toRetentionPolicySchema(bentoRepository.RetentionPolicy), nil
+}
+
+func (c *bentoRepositoryController) DryRunRetentionPolicy(ctx *gin.Context, schema *GetBentoRepositorySchema) ([]*schemasv1.BentoSchema, error) {
+	bentoRepository, err := schema.GetBentoRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, bentoRepository); err != nil {
+		return nil, err
+	}
+	bentos, err := services.RetentionService.ListCollectableBentos(ctx, bentoRepository)
+	if err != nil {
+		return nil, errors.Wrap(err, "list collectable bentos")
+	}
+	return transformersv1.ToBentoSchemas(ctx, bentos)
+}
+
 type ListBentoRepositoryDeploymentSchema struct {
 	schemasv1.ListQuerySchema
 	GetBentoRepositorySchema
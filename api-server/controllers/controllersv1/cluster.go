@@ -71,12 +71,57 @@ func (c *clusterController) canOperate(ctx context.Context, cluster *models.Clus
 	return services.MemberService.CanOperate(ctx, &services.ClusterMemberService, user, cluster.ID)
 }
 
+// ClusterFullSchema adds the fields which are not in the cluster schema shared with the other yatai components
+type ClusterFullSchema struct {
+	schemasv1.ClusterFullSchema
+	ApprovalConfig *models.ClusterApprovalConfig `json:"approval_config"`
+	// Health is the last probe of the kube api server of the cluster by this api server, it is null until the first probe
+	Health     *services.KubeClusterHealth     `json:"health"`
+	Components []*ClusterComponentHealthSchema `json:"components"`
+}
+
+type ClusterComponentHealthSchema struct {
+	Name              string                       `json:"name"`
+	Version           string                       `json:"version"`
+	LatestHeartbeatAt *time.Time                   `json:"latest_heartbeat_at"`
+	Health            *models.YataiComponentHealth `json:"health"`
+}
+
+func toClusterFullSchema(ctx context.Context, cluster *models.Cluster) (*ClusterFullSchema, error) {
+	s, err := transformersv1.ToClusterFullSchema(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	yataiComponents, err := services.YataiComponentService.List(ctx, services.ListYataiComponentOption{
+		ClusterId: &cluster.ID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list yatai components")
+	}
+	components := make([]*ClusterComponentHealthSchema, 0, len(yataiComponents))
+	for _, yataiComponent := range yataiComponents {
+		components = append(components, &ClusterComponentHealthSchema{
+			Name:              yataiComponent.Name,
+			Version:           yataiComponent.Version,
+			LatestHeartbeatAt: yataiComponent.LatestHeartbeatAt,
+			Health:            yataiComponent.Health,
+		})
+	}
+	return &ClusterFullSchema{
+		ClusterFullSchema: *s,
+		ApprovalConfig:    cluster.ApprovalConfig,
+		Health:            services.KubeClientPool.GetHealth(cluster.ID),
+		Components:        components,
+	}, nil
+}
+
 type CreateClusterSchema struct {
 	schemasv1.CreateClusterSchema
 	GetOrganizationSchema
+	ApprovalConfig *models.ClusterApprovalConfig `json:"approval_config"`
 }
 
-func (c *clusterController) Create(ctx *gin.Context, schema *CreateClusterSchema) (*schemasv1.ClusterFullSchema, error) {
+func (c *clusterController) Create(ctx *gin.Context, schema *CreateClusterSchema) (*ClusterFullSchema, error) {
 	user, err := services.GetCurrentUser(ctx)
 	if err != nil {
 		return nil, err
@@ -90,6 +135,10 @@ func (c *clusterController) Create(ctx *gin.Context, schema *CreateClusterSchema
 		return nil, err
 	}
 
+	if err = services.DeploymentApprovalService.ValidateConfig(schema.ApprovalConfig); err != nil {
+		return nil, err
+	}
+
 	cluster, err := services.ClusterService.Create(ctx, services.CreateClusterOption{
 		CreatorId:      user.ID,
 		OrganizationId: org.ID,
@@ -97,6 +146,7 @@ func (c *clusterController) Create(ctx *gin.Context, schema *CreateClusterSchema
 		Description:    schema.Description,
 		KubeConfig:     schema.KubeConfig,
 		Config:         schema.Config,
+		ApprovalConfig: schema.ApprovalConfig,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "create cluster")
@@ -110,15 +160,39 @@ func (c *clusterController) Create(ctx *gin.Context, schema *CreateClusterSchema
 	if err != nil {
 		return nil, errors.Wrap(err, "create cluster member")
 	}
-	return transformersv1.ToClusterFullSchema(ctx, cluster)
+	return toClusterFullSchema(ctx, cluster)
+}
+
+type PreflightClusterSchema struct {
+	GetOrganizationSchema
+	KubeConfig string                            `json:"kube_config"`
+	Config     *modelschemas.ClusterConfigSchema `json:"config"`
+}
+
+// Preflight checks a kube config before the cluster is created, nothing is saved
+func (c *clusterController) Preflight(ctx *gin.Context, schema *PreflightClusterSchema) (*services.ClusterPreflightReport, error) {
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = OrganizationController.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	ctx_, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	return services.ClusterPreflightService.Check(ctx_, services.ClusterPreflightOption{
+		KubeConfig: schema.KubeConfig,
+		Config:     schema.Config,
+	})
 }
 
 type UpdateClusterSchema struct {
 	schemasv1.UpdateClusterSchema
 	GetClusterSchema
+	ApprovalConfig **models.ClusterApprovalConfig `json:"approval_config"`
 }
 
-func (c *clusterController) Update(ctx *gin.Context, schema *UpdateClusterSchema) (*schemasv1.ClusterFullSchema, error) {
+func (c *clusterController) Update(ctx *gin.Context, schema *UpdateClusterSchema) (*ClusterFullSchema, error) {
 	cluster, err := schema.GetCluster(ctx)
 	if err != nil {
 		return nil, err
@@ -126,18 +200,24 @@ func (c *clusterController) Update(ctx *gin.Context, schema *UpdateClusterSchema
 	if err = c.canOperate(ctx, cluster); err != nil {
 		return nil, err
 	}
+	if schema.ApprovalConfig != nil {
+		if err = services.DeploymentApprovalService.ValidateConfig(*schema.ApprovalConfig); err != nil {
+			return nil, err
+		}
+	}
 	cluster, err = services.ClusterService.Update(ctx, cluster, services.UpdateClusterOption{
-		Description: schema.Description,
-		Config:      schema.Config,
-		KubeConfig:  schema.KubeConfig,
+		Description:    schema.Description,
+		Config:         schema.Config,
+		KubeConfig:     schema.KubeConfig,
+		ApprovalConfig: schema.ApprovalConfig,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "update cluster")
 	}
-	return transformersv1.ToClusterFullSchema(ctx, cluster)
+	return toClusterFullSchema(ctx, cluster)
 }
 
-func (c *clusterController) Get(ctx *gin.Context, schema *GetClusterSchema) (*schemasv1.ClusterFullSchema, error) {
+func (c *clusterController) Get(ctx *gin.Context, schema *GetClusterSchema) (*ClusterFullSchema, error) {
 	cluster, err := schema.GetCluster(ctx)
 	if err != nil {
 		return nil, err
@@ -145,7 +225,7 @@ func (c *clusterController) Get(ctx *gin.Context, schema *GetClusterSchema) (*sc
 	if err = c.canView(ctx, cluster); err != nil {
 		return nil, err
 	}
-	return transformersv1.ToClusterFullSchema(ctx, cluster)
+	return toClusterFullSchema(ctx, cluster)
 }
 
 type ListClusterSchema struct {
@@ -188,12 +268,12 @@ func (c *clusterController) List(ctx *gin.Context, schema *ListClusterSchema) (*
 
 func (c *clusterController) WsPods(ctx *gin.Context, schema *GetClusterSchema) (err error) {
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -310,7 +390,7 @@ func (c *clusterController) WsPods(ctx *gin.Context, schema *GetClusterSchema) (
 		return false
 	}
 
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	removeHandler, err := services.KubeInformerManager.AddEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if !checkPod(obj) {
 				return
@@ -330,6 +410,10 @@ func (c *clusterController) WsPods(ctx *gin.Context, schema *GetClusterSchema) (
 			send()
 		},
 	})
+	if err != nil {
+		return
+	}
+	defer removeHandler()
 
 	func() {
 		ticker := time.NewTicker(time.Second * 10)
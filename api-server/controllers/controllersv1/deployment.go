@@ -66,23 +66,79 @@ func (c *deploymentController) canView(ctx context.Context, deployment *models.D
 	if err != nil {
 		return errors.Wrap(err, "get associated cluster")
 	}
-	return ClusterController.canView(ctx, cluster)
+	err = ClusterController.canView(ctx, cluster)
+	if err == nil {
+		return nil
+	}
+	// the members of a deployment can view it without being members of its cluster
+	user, err_ := services.GetCurrentUser(ctx)
+	if err_ != nil {
+		return err_
+	}
+	if services.MemberService.CanView(ctx, &services.DeploymentMemberService, user, deployment.ID) == nil {
+		return nil
+	}
+	return err
 }
 
 func (c *deploymentController) canUpdate(ctx context.Context, deployment *models.Deployment) error {
-	cluster, err := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+	restricted, err := services.DeploymentMemberService.IsRestricted(ctx, deployment.ID)
 	if err != nil {
-		return errors.Wrap(err, "get associated cluster")
+		return errors.Wrap(err, "check deployment members")
+	}
+	if !restricted {
+		cluster, err := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+		if err != nil {
+			return errors.Wrap(err, "get associated cluster")
+		}
+		return ClusterController.canUpdate(ctx, cluster)
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return err
 	}
-	return ClusterController.canUpdate(ctx, cluster)
+	return services.MemberService.CanUpdate(ctx, &services.DeploymentMemberService, user, deployment.ID)
 }
 
 func (c *deploymentController) canOperate(ctx context.Context, deployment *models.Deployment) error {
-	cluster, err := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+	restricted, err := services.DeploymentMemberService.IsRestricted(ctx, deployment.ID)
 	if err != nil {
-		return errors.Wrap(err, "get associated cluster")
+		return errors.Wrap(err, "check deployment members")
+	}
+	if !restricted {
+		cluster, err := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+		if err != nil {
+			return errors.Wrap(err, "get associated cluster")
+		}
+		return ClusterController.canOperate(ctx, cluster)
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	return services.MemberService.CanOperate(ctx, &services.DeploymentMemberService, user, deployment.ID)
+}
+
+func (c *deploymentController) createEvent(ctx context.Context, user *models.User, organizationId uint, deployment *models.Deployment, operationName string, err error) {
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	createEventOpt := services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &organizationId,
+		ResourceType:   modelschemas.ResourceTypeDeployment,
+		ResourceId:     deployment.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  operationName,
+	}
+	if err != nil {
+		createEventOpt.Status = modelschemas.EventStatusFailed
+	}
+	if _, err_ := services.EventService.Create(ctx, createEventOpt); err_ != nil {
+		logrus.Errorf("create event failed: %v", err_)
 	}
-	return ClusterController.canOperate(ctx, cluster)
 }
 
 type CreateDeploymentSchema struct {
@@ -237,17 +293,112 @@ func (c *deploymentController) Update(ctx *gin.Context, schema *UpdateDeployment
 	return deploymentSchema, err
 }
 
-func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.UpdateDeploymentSchema, org *models.Organization, deployment *models.Deployment) (*schemasv1.DeploymentSchema, error) {
-	user, err := services.GetCurrentUser(ctx)
+// DryRunCreate renders and validates a new deployment against the cluster without creating it
+func (c *deploymentController) DryRunCreate(ctx *gin.Context, schema *CreateDeploymentSchema) (*services.DeploymentDryRunResult, error) {
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = ClusterController.canUpdate(ctx, cluster); err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
 	if err != nil {
 		return nil, err
 	}
-	bentoRepositoryNames := make([]string, 0, len(schema.Targets))
-	bentoRepositoryNamesSeen := make(map[string]struct{}, len(schema.Targets))
 
-	bentoVersionsMapping := make(map[string][]string, len(schema.Targets))
+	kubeNamespace := strings.TrimSpace(schema.KubeNamespace)
+	if kubeNamespace == "" {
+		kubeNamespace = services.ClusterService.GetDeploymentKubeNamespace(cluster)
+	}
+
+	_, err = services.DeploymentService.GetByName(ctx, cluster.ID, kubeNamespace, schema.Name)
+	if err == nil {
+		return nil, errors.Errorf("deployment %s already exists in namespace %s", schema.Name, kubeNamespace)
+	}
+	if !utils.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "get deployment %s", schema.Name)
+	}
+
+	deployment := &models.Deployment{
+		ResourceMixin: models.ResourceMixin{
+			Name: schema.Name,
+		},
+		ClusterAssociate: models.ClusterAssociate{
+			ClusterId:              cluster.ID,
+			AssociatedClusterCache: cluster,
+		},
+		KubeNamespace: kubeNamespace,
+	}
+	return c.doDryRun(ctx, schema.UpdateDeploymentSchema, org, deployment)
+}
 
+// DryRunUpdate renders and validates an update of the deployment against the cluster without creating a revision
+func (c *deploymentController) DryRunUpdate(ctx *gin.Context, schema *UpdateDeploymentSchema) (*services.DeploymentDryRunResult, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.doDryRun(ctx, schema.UpdateDeploymentSchema, org, deployment)
+}
+
+func (c *deploymentController) doDryRun(ctx context.Context, schema schemasv1.UpdateDeploymentSchema, org *models.Organization, deployment *models.Deployment) (*services.DeploymentDryRunResult, error) {
+	bentosMapping, err := c.getBentosMapping(ctx, org, schema.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentTargets := make([]*models.DeploymentTarget, 0, len(schema.Targets))
 	for _, createDeploymentTargetSchema := range schema.Targets {
+		bento := bentosMapping[fmt.Sprintf("%s:%s", createDeploymentTargetSchema.BentoRepository, createDeploymentTargetSchema.Bento)]
+		if bento == nil {
+			return nil, errors.Errorf("can't find bento: %s:%s", createDeploymentTargetSchema.BentoRepository, createDeploymentTargetSchema.Bento)
+		}
+		var config *modelschemas.DeploymentTargetConfig
+		if createDeploymentTargetSchema.Config != nil {
+			config_ := *createDeploymentTargetSchema.Config
+			config_.KubeResourceUid = ""
+			config_.KubeResourceVersion = ""
+			config = &config_
+		}
+		// the targets are not persisted, the associated caches stand in for the records
+		deploymentTargets = append(deploymentTargets, &models.DeploymentTarget{
+			DeploymentAssociate: models.DeploymentAssociate{
+				DeploymentId:              deployment.ID,
+				AssociatedDeploymentCache: deployment,
+			},
+			BentoAssociate: models.BentoAssociate{
+				BentoId:              bento.ID,
+				AssociatedBentoCache: bento,
+			},
+			Type:        createDeploymentTargetSchema.Type,
+			CanaryRules: createDeploymentTargetSchema.CanaryRules,
+			Config:      config,
+		})
+	}
+
+	res, err := services.DeploymentService.DryRun(ctx, deployment, deploymentTargets)
+	if err != nil {
+		return nil, errors.Wrap(err, "dry run deployment")
+	}
+	return res, nil
+}
+
+// getBentosMapping finds the bentos of the targets, keyed by repository:version
+func (c *deploymentController) getBentosMapping(ctx context.Context, org *models.Organization, targets []*schemasv1.CreateDeploymentTargetSchema) (map[string]*models.Bento, error) {
+	bentoRepositoryNames := make([]string, 0, len(targets))
+	bentoRepositoryNamesSeen := make(map[string]struct{}, len(targets))
+
+	bentoVersionsMapping := make(map[string][]string, len(targets))
+
+	for _, createDeploymentTargetSchema := range targets {
 		if _, ok := bentoRepositoryNamesSeen[createDeploymentTargetSchema.BentoRepository]; !ok {
 			bentoRepositoryNames = append(bentoRepositoryNames, createDeploymentTargetSchema.BentoRepository)
 			bentoRepositoryNamesSeen[createDeploymentTargetSchema.BentoRepository] = struct{}{}
@@ -287,6 +438,19 @@ func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.Up
 		}
 	}
 
+	return bentosMapping, nil
+}
+
+func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.UpdateDeploymentSchema, org *models.Organization, deployment *models.Deployment) (*schemasv1.DeploymentSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bentosMapping, err := c.getBentosMapping(ctx, org, schema.Targets)
+	if err != nil {
+		return nil, err
+	}
+
 	status_ := modelschemas.DeploymentRevisionStatusActive
 	deploymentRevisions, _, err := services.DeploymentRevisionService.List(ctx, services.ListDeploymentRevisionOption{
 		DeploymentId: utils.UintPtr(deployment.ID),
@@ -340,7 +504,21 @@ func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.Up
 				}
 			}
 		}
-	} else {
+	}
+
+	// only the synchronization of the kube resource versions of the existing targets above is not gated,
+	// the other revisions wait for an approval when the cluster is protected, even if they are not deployed
+	deploymentRevisionStatus := modelschemas.DeploymentRevisionStatusActive
+	requiresApproval, err := services.DeploymentApprovalService.RequiresApproval(ctx, deployment, user)
+	if err != nil {
+		return nil, errors.Wrap(err, "check deployment approval")
+	}
+	if requiresApproval {
+		deploymentRevisionStatus = models.DeploymentRevisionStatusPending
+	}
+
+	if !schema.DoNotDeploy || requiresApproval {
+		// the pending revisions are deployed once approved
 		for _, createDeploymentTargetSchema := range schema.Targets {
 			if createDeploymentTargetSchema.Config != nil {
 				createDeploymentTargetSchema.Config.KubeResourceVersion = ""
@@ -352,7 +530,7 @@ func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.Up
 	deploymentRevision, err := services.DeploymentRevisionService.Create(ctx, services.CreateDeploymentRevisionOption{
 		CreatorId:    user.ID,
 		DeploymentId: deployment.ID,
-		Status:       modelschemas.DeploymentRevisionStatusActive,
+		Status:       deploymentRevisionStatus,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "create deployment revision")
@@ -380,7 +558,13 @@ func (c *deploymentController) doUpdate(ctx context.Context, schema schemasv1.Up
 		deploymentTargets = append(deploymentTargets, deploymentTarget)
 	}
 
-	if !schema.DoNotDeploy {
+	if deploymentRevisionStatus == models.DeploymentRevisionStatusPending {
+		err = services.DeploymentApprovalService.Submit(ctx, deploymentRevision)
+		if err != nil {
+			return nil, errors.Wrap(err, "submit deployment revision")
+		}
+		c.createEvent(ctx, user, org.ID, deployment, "requested approval", nil)
+	} else if !schema.DoNotDeploy {
 		err = services.DeploymentRevisionService.Deploy(ctx, deploymentRevision, deploymentTargets, false)
 		if err != nil {
 			return nil, errors.Wrap(err, "deploy deployment revision")
@@ -885,12 +1069,12 @@ type connWrapper struct {
 
 func (c *deploymentController) WsPods(ctx *gin.Context, schema *GetDeploymentSchema) (err error) {
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -1124,7 +1308,7 @@ func (c *deploymentController) WsPods(ctx *gin.Context, schema *GetDeploymentSch
 		return true
 	}
 
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	removeHandler, err := services.KubeInformerManager.AddEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if !checkPod(obj) {
 				return
@@ -1144,6 +1328,10 @@ func (c *deploymentController) WsPods(ctx *gin.Context, schema *GetDeploymentSch
 			send_()
 		},
 	})
+	if err != nil {
+		return err
+	}
+	defer removeHandler()
 
 	func() {
 		ticker := time.NewTicker(time.Second * 10)
@@ -0,0 +1,155 @@
+package controllersv1
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentManifestController struct {
+	deploymentController
+}
+
+var DeploymentManifestController = deploymentManifestController{}
+
+func (c *deploymentManifestController) export(ctx context.Context, schema *GetDeploymentSchema) (*services.DeploymentManifest, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	manifest, err := services.DeploymentManifestService.Export(ctx, deployment)
+	return manifest, errors.Wrap(err, "export deployment manifest")
+}
+
+// Export returns the manifest of the active revision of the deployment
+func (c *deploymentManifestController) Export(ctx *gin.Context, schema *GetDeploymentSchema) (*services.DeploymentManifest, error) {
+	return c.export(ctx, schema)
+}
+
+// ExportYAML returns the same manifest as Export as a YAML file to be committed to git
+func (c *deploymentManifestController) ExportYAML(ctx *gin.Context) {
+	schema := &GetDeploymentSchema{}
+	schema.ClusterName = ctx.Param("clusterName")
+	schema.KubeNamespace = ctx.Param("kubeNamespace")
+	schema.DeploymentName = ctx.Param("deploymentName")
+
+	var err error
+
+	defer func() {
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	manifest, err := c.export(ctx, schema)
+	if err != nil {
+		return
+	}
+	content, err := services.MarshalDeploymentManifestYAML(manifest)
+	if err != nil {
+		err = errors.Wrap(err, "marshal deployment manifest")
+		return
+	}
+	ctx.Data(http.StatusOK, "application/yaml", content)
+}
+
+type ApplyDeploymentManifestSchema struct {
+	GetOrganizationSchema
+	// Manifest is the YAML or JSON content of the manifest
+	Manifest string `json:"manifest"`
+	Prune    bool   `json:"prune"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+type DeploymentManifestApplySchema struct {
+	services.DeploymentManifestApplyResult
+	// Deployment is nil when a dry run would create the deployment
+	Deployment *schemasv1.DeploymentSchema `json:"deployment"`
+}
+
+// Apply creates or updates the deployment of the manifest, nothing is deployed when the manifest matches the active revision
+func (c *deploymentManifestController) Apply(ctx *gin.Context, schema *ApplyDeploymentManifestSchema) (*DeploymentManifestApplySchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := services.ParseDeploymentManifest([]byte(schema.Manifest))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse deployment manifest")
+	}
+	cluster, err := services.ClusterService.GetByName(ctx, org.ID, manifest.Metadata.Cluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get cluster %s", manifest.Metadata.Cluster)
+	}
+	manifest.Metadata.KubeNamespace = strings.TrimSpace(manifest.Metadata.KubeNamespace)
+	if manifest.Metadata.KubeNamespace == "" {
+		manifest.Metadata.KubeNamespace = services.ClusterService.GetDeploymentKubeNamespace(cluster)
+	}
+
+	deployment, err := services.DeploymentService.GetByName(ctx, cluster.ID, manifest.Metadata.KubeNamespace, manifest.Metadata.Name)
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			return nil, err
+		}
+		deployment = nil
+		if err = ClusterController.canUpdate(ctx, cluster); err != nil {
+			return nil, err
+		}
+	} else if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	deployment, result, err := services.DeploymentManifestService.Apply(ctx_, services.ApplyDeploymentManifestOption{
+		CreatorId:  user.ID,
+		Cluster:    cluster,
+		Deployment: deployment,
+		Manifest:   manifest,
+		Prune:      schema.Prune,
+		DryRun:     schema.DryRun,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "apply deployment manifest")
+	}
+
+	res := &DeploymentManifestApplySchema{
+		DeploymentManifestApplyResult: *result,
+	}
+	if deployment == nil {
+		return res, nil
+	}
+	if !schema.DryRun && result.Changed {
+		if result.Created {
+			c.createEvent(ctx_, user, org.ID, deployment, "created", nil)
+		}
+		c.createEvent(ctx_, user, org.ID, deployment, "applied manifest", nil)
+		if result.PendingApproval {
+			c.createEvent(ctx_, user, org.ID, deployment, "requested approval", nil)
+		}
+	}
+	res.Deployment, err = transformersv1.ToDeploymentSchema(ctx_, deployment)
+	return res, err
+}
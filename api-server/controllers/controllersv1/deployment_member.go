@@ -0,0 +1,257 @@
+package controllersv1
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentMemberController struct {
+	deploymentController
+}
+
+var DeploymentMemberController = deploymentMemberController{}
+
+type DeploymentMemberSchema struct {
+	Creator *schemasv1.UserSchema   `json:"creator"`
+	User    schemasv1.UserSchema    `json:"user"`
+	Role    modelschemas.MemberRole `json:"role"`
+}
+
+func toDeploymentMemberSchemas(ctx context.Context, members []*models.DeploymentMember) ([]*DeploymentMemberSchema, error) {
+	res := make([]*DeploymentMemberSchema, 0, len(members))
+	for _, member := range members {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, member)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment member associated creator")
+		}
+		creatorSchema, err := transformersv1.ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		user, err := services.UserService.GetAssociatedUser(ctx, member)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment member associated user")
+		}
+		userSchema, err := transformersv1.ToUserSchema(ctx, user)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		res = append(res, &DeploymentMemberSchema{
+			Creator: creatorSchema,
+			User:    *userSchema,
+			Role:    member.Role,
+		})
+	}
+	return res, nil
+}
+
+type CreateDeploymentMembersSchema struct {
+	schemasv1.CreateMembersSchema
+	GetDeploymentSchema
+}
+
+// keepOperatorAccess makes the operator an admin member of the deployment, the first members restrict the deployment to its members
+func (c *deploymentMemberController) keepOperatorAccess(ctx context.Context, deployment *models.Deployment, operator *models.User) error {
+	isMember, err := services.DeploymentMemberService.CheckRoles(ctx, operator.ID, deployment.ID, []modelschemas.MemberRole{modelschemas.MemberRoleAdmin})
+	if err != nil {
+		return errors.Wrap(err, "check deployment member roles")
+	}
+	if isMember {
+		return nil
+	}
+	_, err = services.DeploymentMemberService.Create(ctx, operator.ID, services.CreateDeploymentMemberOption{
+		CreatorId:    operator.ID,
+		UserId:       operator.ID,
+		DeploymentId: deployment.ID,
+		Role:         modelschemas.MemberRoleAdmin,
+	})
+	return errors.Wrap(err, "create deployment admin member")
+}
+
+func (c *deploymentMemberController) Create(ctx *gin.Context, schema *CreateDeploymentMembersSchema) ([]*DeploymentMemberSchema, error) {
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	restricted, err := services.DeploymentMemberService.IsRestricted(ctx, deployment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "check deployment members")
+	}
+	users, err := services.UserService.ListByNames(ctx, schema.Usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	members := make([]*models.DeploymentMember, 0, len(users))
+	for _, u := range users {
+		var member *models.DeploymentMember
+		member, err = services.DeploymentMemberService.Create(ctx_, currentUser.ID, services.CreateDeploymentMemberOption{
+			CreatorId:    currentUser.ID,
+			UserId:       u.ID,
+			DeploymentId: deployment.ID,
+			Role:         schema.Role,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "create deployment member")
+		}
+		members = append(members, member)
+	}
+	if !restricted {
+		err = c.keepOperatorAccess(ctx_, deployment, currentUser)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return toDeploymentMemberSchemas(ctx_, members)
+}
+
+func (c *deploymentMemberController) List(ctx *gin.Context, schema *GetDeploymentSchema) ([]*DeploymentMemberSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	members, err := services.DeploymentMemberService.List(ctx, services.ListDeploymentMemberOption{
+		DeploymentId: utils.UintPtr(deployment.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment members")
+	}
+	return toDeploymentMemberSchemas(ctx, members)
+}
+
+type DeleteDeploymentMemberSchema struct {
+	schemasv1.DeleteMemberSchema
+	GetDeploymentSchema
+}
+
+func (c *deploymentMemberController) Delete(ctx *gin.Context, schema *DeleteDeploymentMemberSchema) (*DeploymentMemberSchema, error) {
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	user, err := services.UserService.GetByName(ctx, schema.Username)
+	if err != nil {
+		return nil, err
+	}
+	member, err := services.DeploymentMemberService.GetBy(ctx, user.ID, deployment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get member")
+	}
+	deploymentMember, err := services.DeploymentMemberService.Delete(ctx, member, currentUser.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete deployment member")
+	}
+	ss, err := toDeploymentMemberSchemas(ctx, []*models.DeploymentMember{deploymentMember})
+	if err != nil {
+		return nil, err
+	}
+	return ss[0], nil
+}
+
+type CreateDeploymentUserGroupMembersSchema struct {
+	CreateUserGroupMembersSchema
+	GetDeploymentSchema
+}
+
+func (c *deploymentMemberController) CreateUserGroupMembers(ctx *gin.Context, schema *CreateDeploymentUserGroupMembersSchema) ([]*UserGroupMemberSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	org, err := services.DeploymentMemberService.GetOrganization(ctx, deployment.ID)
+	if err != nil {
+		return nil, err
+	}
+	restricted, err := services.DeploymentMemberService.IsRestricted(ctx, deployment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "check deployment members")
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	memberSchemas, err := createUserGroupMembers(ctx_, org, deployment, &schema.CreateUserGroupMembersSchema)
+	if err != nil {
+		return nil, err
+	}
+	if !restricted {
+		err = c.keepOperatorAccess(ctx_, deployment, currentUser)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return memberSchemas, nil
+}
+
+func (c *deploymentMemberController) ListUserGroupMembers(ctx *gin.Context, schema *GetDeploymentSchema) ([]*UserGroupMemberSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	return listUserGroupMembers(ctx, deployment)
+}
+
+type DeleteDeploymentUserGroupMemberSchema struct {
+	DeleteUserGroupMemberSchema
+	GetDeploymentSchema
+}
+
+func (c *deploymentMemberController) DeleteUserGroupMember(ctx *gin.Context, schema *DeleteDeploymentUserGroupMemberSchema) (*UserGroupMemberSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	org, err := services.DeploymentMemberService.GetOrganization(ctx, deployment.ID)
+	if err != nil {
+		return nil, err
+	}
+	return deleteUserGroupMember(ctx, org, deployment, &schema.DeleteUserGroupMemberSchema)
+}
@@ -0,0 +1,244 @@
+package controllersv1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentPromotionController struct {
+	deploymentController
+}
+
+var DeploymentPromotionController = deploymentPromotionController{}
+
+type DeploymentPromotionEndSchema struct {
+	ClusterName           string `json:"cluster_name"`
+	KubeNamespace         string `json:"kube_namespace"`
+	DeploymentName        string `json:"deployment_name"`
+	DeploymentUid         string `json:"deployment_uid"`
+	DeploymentRevisionUid string `json:"deployment_revision_uid"`
+}
+
+type DeploymentPromotionSchema struct {
+	schemasv1.BaseSchema
+	ResourceType modelschemas.ResourceType            `json:"resource_type"`
+	Creator      *schemasv1.UserSchema                `json:"creator"`
+	Source       *DeploymentPromotionEndSchema        `json:"source"`
+	Target       *DeploymentPromotionEndSchema        `json:"target"`
+	Overrides    *models.DeploymentPromotionOverrides `json:"overrides"`
+}
+
+func toDeploymentPromotionEndSchema(ctx context.Context, deploymentId, deploymentRevisionId uint) (*DeploymentPromotionEndSchema, error) {
+	deployment, err := services.DeploymentService.Get(ctx, deploymentId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment")
+	}
+	cluster, err := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment associated cluster")
+	}
+	deploymentRevision, err := services.DeploymentRevisionService.Get(ctx, deploymentRevisionId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment revision")
+	}
+	return &DeploymentPromotionEndSchema{
+		ClusterName:           cluster.Name,
+		KubeNamespace:         deployment.KubeNamespace,
+		DeploymentName:        deployment.Name,
+		DeploymentUid:         deployment.Uid,
+		DeploymentRevisionUid: deploymentRevision.Uid,
+	}, nil
+}
+
+func toDeploymentPromotionSchemas(ctx context.Context, promotions []*models.DeploymentPromotion) ([]*DeploymentPromotionSchema, error) {
+	res := make([]*DeploymentPromotionSchema, 0, len(promotions))
+	for _, promotion := range promotions {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, promotion)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment promotion associated creator")
+		}
+		creatorSchema, err := transformersv1.ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		source, err := toDeploymentPromotionEndSchema(ctx, promotion.SourceDeploymentId, promotion.SourceDeploymentRevisionId)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment promotion source")
+		}
+		target, err := toDeploymentPromotionEndSchema(ctx, promotion.TargetDeploymentId, promotion.TargetDeploymentRevisionId)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment promotion target")
+		}
+		res = append(res, &DeploymentPromotionSchema{
+			BaseSchema:   transformersv1.ToBaseSchema(promotion),
+			ResourceType: promotion.GetResourceType(),
+			Creator:      creatorSchema,
+			Source:       source,
+			Target:       target,
+			Overrides:    promotion.Overrides,
+		})
+	}
+	return res, nil
+}
+
+type DeploymentPromotionListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*DeploymentPromotionSchema `json:"items"`
+}
+
+type PromoteDeploymentRevisionSchema struct {
+	GetDeploymentRevisionSchema
+	TargetClusterName string `json:"target_cluster_name"`
+	// TargetKubeNamespace defaults to the deployment namespace of the target cluster
+	TargetKubeNamespace string `json:"target_kube_namespace"`
+	// TargetDeploymentName defaults to the name of the source deployment
+	TargetDeploymentName string                               `json:"target_deployment_name"`
+	Overrides            *models.DeploymentPromotionOverrides `json:"overrides"`
+}
+
+// Promote deploys the revision with the overrides to a deployment of another cluster of the organization,
+// the target deployment is created when it does not exist
+func (c *deploymentPromotionController) Promote(ctx *gin.Context, schema *PromoteDeploymentRevisionSchema) (*DeploymentPromotionSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	deploymentRevision, err := services.DeploymentRevisionService.GetByUid(ctx, schema.RevisionUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment revision")
+	}
+	if deploymentRevision.DeploymentId != deployment.ID {
+		return nil, errors.New("deployment revision not found")
+	}
+	deploymentRevision.SetAssociatedDeploymentCache(deployment)
+
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	targetClusterName := strings.TrimSpace(schema.TargetClusterName)
+	if targetClusterName == "" {
+		return nil, errors.New("target_cluster_name is required")
+	}
+	targetCluster, err := services.ClusterService.GetByName(ctx, org.ID, targetClusterName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get cluster %s", targetClusterName)
+	}
+	targetKubeNamespace := strings.TrimSpace(schema.TargetKubeNamespace)
+	if targetKubeNamespace == "" {
+		targetKubeNamespace = services.ClusterService.GetDeploymentKubeNamespace(targetCluster)
+	}
+	targetDeploymentName := strings.TrimSpace(schema.TargetDeploymentName)
+	if targetDeploymentName == "" {
+		targetDeploymentName = deployment.Name
+	}
+
+	targetDeployment, err := services.DeploymentService.GetByName(ctx, targetCluster.ID, targetKubeNamespace, targetDeploymentName)
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			return nil, err
+		}
+		if err = ClusterController.canUpdate(ctx, targetCluster); err != nil {
+			return nil, err
+		}
+	} else if err = c.canUpdate(ctx, targetDeployment); err != nil {
+		return nil, err
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	defer func() {
+		c.createEvent(ctx_, user, org.ID, deployment, "promoted", err)
+	}()
+
+	promotion, targetDeploymentCreated, err := services.DeploymentPromotionService.Promote(ctx_, services.PromoteDeploymentRevisionOption{
+		CreatorId:                user.ID,
+		SourceDeploymentRevision: deploymentRevision,
+		TargetClusterId:          targetCluster.ID,
+		TargetKubeNamespace:      targetKubeNamespace,
+		TargetDeploymentName:     targetDeploymentName,
+		Overrides:                schema.Overrides,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "promote deployment revision")
+	}
+
+	targetDeployment, err = services.DeploymentService.Get(ctx_, promotion.TargetDeploymentId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get target deployment")
+	}
+	if targetDeploymentCreated {
+		c.createEvent(ctx_, user, org.ID, targetDeployment, "created", nil)
+	}
+	c.createEvent(ctx_, user, org.ID, targetDeployment, "received promotion", nil)
+	targetDeploymentRevision, err := services.DeploymentRevisionService.Get(ctx_, promotion.TargetDeploymentRevisionId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get target deployment revision")
+	}
+	if targetDeploymentRevision.Status == models.DeploymentRevisionStatusPending {
+		c.createEvent(ctx_, user, org.ID, targetDeployment, "requested approval", nil)
+	}
+
+	ss, err := toDeploymentPromotionSchemas(ctx_, []*models.DeploymentPromotion{promotion})
+	if err != nil {
+		return nil, err
+	}
+	return ss[0], nil
+}
+
+type ListDeploymentPromotionSchema struct {
+	schemasv1.ListQuerySchema
+	GetDeploymentSchema
+}
+
+// List returns the promotions from and to the deployment, the latest first
+func (c *deploymentPromotionController) List(ctx *gin.Context, schema *ListDeploymentPromotionSchema) (*DeploymentPromotionListSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	promotions, total, err := services.DeploymentPromotionService.List(ctx, services.ListDeploymentPromotionOption{
+		BaseListOption: services.BaseListOption{
+			Start: utils.UintPtr(schema.Start),
+			Count: utils.UintPtr(schema.Count),
+		},
+		DeploymentId: utils.UintPtr(deployment.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment promotions")
+	}
+	promotionSchemas, err := toDeploymentPromotionSchemas(ctx, promotions)
+	return &DeploymentPromotionListSchema{
+		BaseListSchema: schemasv1.BaseListSchema{
+			Total: total,
+			Start: schema.Start,
+			Count: schema.Count,
+		},
+		Items: promotionSchemas,
+	}, err
+}
@@ -1,11 +1,18 @@
 package controllersv1
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
+	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/services/tracking"
 	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
 	"github.com/bentoml/yatai/common/utils"
 )
@@ -81,3 +88,227 @@ func (c *deploymentRevisionController) Get(ctx *gin.Context, schema *GetDeployme
 
 	return transformersv1.ToDeploymentRevisionSchema(ctx, deploymentRevision)
 }
+
+func (c *deploymentRevisionController) Rollback(ctx *gin.Context, schema *GetDeploymentRevisionSchema) (*schemasv1.DeploymentSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = DeploymentController.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	deploymentRevision, err := services.DeploymentRevisionService.GetByUid(ctx, schema.RevisionUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deploymentRevision")
+	}
+
+	if deploymentRevision.DeploymentId != deployment.ID {
+		return nil, errors.New("deploymentRevision not found")
+	}
+
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	defer func() {
+		apiTokenName := ""
+		if user.ApiToken != nil {
+			apiTokenName = user.ApiToken.Name
+		}
+		createEventOpt := services.CreateEventOption{
+			CreatorId:      user.ID,
+			ApiTokenName:   apiTokenName,
+			OrganizationId: &cluster.OrganizationId,
+			ResourceType:   modelschemas.ResourceTypeDeployment,
+			ResourceId:     deployment.ID,
+			Status:         modelschemas.EventStatusSuccess,
+			OperationName:  "rolled back",
+		}
+		if err != nil {
+			createEventOpt.Status = modelschemas.EventStatusFailed
+		}
+
+		if _, err_ := services.EventService.Create(ctx_, createEventOpt); err_ != nil {
+			logrus.Errorf("create event failed: %v", err_)
+		}
+	}()
+
+	newDeploymentRevision, err := services.DeploymentRevisionService.Rollback(ctx_, deploymentRevision, user.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "rollback deployment revision")
+	}
+	if newDeploymentRevision.Status == models.DeploymentRevisionStatusPending {
+		DeploymentController.createEvent(ctx_, user, cluster.OrganizationId, deployment, "requested approval", nil)
+	}
+
+	deploymentSchema, err := transformersv1.ToDeploymentSchema(ctx_, deployment)
+	go tracking.TrackDeploymentEvent(ctx, deploymentSchema, tracking.YataiDeploymentUpdate)
+	return deploymentSchema, err
+}
+
+type DiffDeploymentRevisionSchema struct {
+	GetDeploymentRevisionSchema
+	WithRevisionUid string `query:"with_revision_uid"`
+}
+
+type DeploymentRevisionDiffSchema struct {
+	RevisionUid string `json:"revision_uid"`
+	// WithRevisionUid is empty when the revision is diffed against the live deployment
+	WithRevisionUid string            `json:"with_revision_uid"`
+	Items           []*utils.DiffItem `json:"items"`
+}
+
+// Diff compares the revision with the revision of with_revision_uid, or with the live deployment when it is empty
+func (c *deploymentRevisionController) Diff(ctx *gin.Context, schema *DiffDeploymentRevisionSchema) (*DeploymentRevisionDiffSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = DeploymentController.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	deploymentRevision, err := services.DeploymentRevisionService.GetByUid(ctx, schema.RevisionUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deploymentRevision")
+	}
+
+	if deploymentRevision.DeploymentId != deployment.ID {
+		return nil, errors.New("deploymentRevision not found")
+	}
+
+	var items []*utils.DiffItem
+	if schema.WithRevisionUid == "" {
+		items, err = services.DeploymentRevisionService.DiffLive(ctx, deploymentRevision)
+		if err != nil {
+			return nil, errors.Wrap(err, "diff deploymentRevision with the live deployment")
+		}
+	} else {
+		withDeploymentRevision, err := services.DeploymentRevisionService.GetByUid(ctx, schema.WithRevisionUid)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deploymentRevision")
+		}
+		if withDeploymentRevision.DeploymentId != deployment.ID {
+			return nil, errors.New("deploymentRevision not found")
+		}
+		items, err = services.DeploymentRevisionService.Diff(ctx, deploymentRevision, withDeploymentRevision)
+		if err != nil {
+			return nil, errors.Wrap(err, "diff deploymentRevisions")
+		}
+	}
+
+	return &DeploymentRevisionDiffSchema{
+		RevisionUid:     deploymentRevision.Uid,
+		WithRevisionUid: schema.WithRevisionUid,
+		Items:           items,
+	}, nil
+}
+
+type ReviewDeploymentRevisionSchema struct {
+	GetDeploymentRevisionSchema
+	Comment string `json:"comment"`
+}
+
+type DeploymentRevisionReviewSchema struct {
+	schemasv1.DeploymentRevisionSchema
+	Reviewer      *schemasv1.UserSchema `json:"reviewer"`
+	ReviewedAt    *time.Time            `json:"reviewed_at"`
+	ReviewComment string                `json:"review_comment"`
+}
+
+func toDeploymentRevisionReviewSchema(ctx context.Context, deploymentRevision *models.DeploymentRevision) (*DeploymentRevisionReviewSchema, error) {
+	deploymentRevisionSchema, err := transformersv1.ToDeploymentRevisionSchema(ctx, deploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	res := &DeploymentRevisionReviewSchema{
+		DeploymentRevisionSchema: *deploymentRevisionSchema,
+		ReviewedAt:               deploymentRevision.ReviewedAt,
+		ReviewComment:            deploymentRevision.ReviewComment,
+	}
+	if deploymentRevision.ReviewerId != nil {
+		reviewer, err := services.UserService.Get(ctx, *deploymentRevision.ReviewerId)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment revision reviewer")
+		}
+		res.Reviewer, err = transformersv1.ToUserSchema(ctx, reviewer)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+	}
+	return res, nil
+}
+
+func (c *deploymentRevisionController) review(ctx *gin.Context, schema *ReviewDeploymentRevisionSchema, operationName string, review func(context.Context, *models.DeploymentRevision, *models.User, string) (*models.DeploymentRevision, error)) (*DeploymentRevisionReviewSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = DeploymentController.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	deploymentRevision, err := services.DeploymentRevisionService.GetByUid(ctx, schema.RevisionUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deploymentRevision")
+	}
+
+	if deploymentRevision.DeploymentId != deployment.ID {
+		return nil, errors.New("deploymentRevision not found")
+	}
+
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	defer func() {
+		DeploymentController.createEvent(ctx_, user, cluster.OrganizationId, deployment, operationName, err)
+	}()
+
+	deploymentRevision, err = review(ctx_, deploymentRevision, user, schema.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDeploymentRevisionReviewSchema(ctx_, deploymentRevision)
+}
+
+// Approve deploys the pending revision, the approver role is configured in the approval config of the cluster
+func (c *deploymentRevisionController) Approve(ctx *gin.Context, schema *ReviewDeploymentRevisionSchema) (*DeploymentRevisionReviewSchema, error) {
+	return c.review(ctx, schema, "approved revision", services.DeploymentApprovalService.Approve)
+}
+
+// Reject closes the pending revision without deploying it
+func (c *deploymentRevisionController) Reject(ctx *gin.Context, schema *ReviewDeploymentRevisionSchema) (*DeploymentRevisionReviewSchema, error) {
+	return c.review(ctx, schema, "rejected revision", services.DeploymentApprovalService.Reject)
+}
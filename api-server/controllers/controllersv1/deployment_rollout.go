@@ -0,0 +1,211 @@
+package controllersv1
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	apischemasv1 "github.com/bentoml/yatai/api-server/schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentRolloutController struct {
+	deploymentController
+}
+
+var DeploymentRolloutController = deploymentRolloutController{}
+
+type CreateDeploymentRolloutSchema struct {
+	GetDeploymentSchema
+	Steps models.DeploymentRolloutSteps `json:"steps"`
+}
+
+type GetDeploymentRolloutSchema struct {
+	GetDeploymentSchema
+	RolloutUid string `path:"rolloutUid"`
+}
+
+func (s *GetDeploymentRolloutSchema) GetDeploymentRollout(ctx context.Context, deployment *models.Deployment) (*models.DeploymentRollout, error) {
+	rollout, err := services.DeploymentRolloutService.GetByUid(ctx, s.RolloutUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment rollout")
+	}
+	if rollout.DeploymentId != deployment.ID {
+		return nil, errors.New("deployment rollout not found")
+	}
+	return rollout, nil
+}
+
+func (c *deploymentRolloutController) createEvent(ctx context.Context, user *models.User, deployment *models.Deployment, operationName string, err error) {
+	cluster, err_ := services.ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err_ != nil {
+		logrus.Errorf("get associated cluster failed: %v", err_)
+		return
+	}
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	createEventOpt := services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &cluster.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeDeployment,
+		ResourceId:     deployment.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  operationName,
+	}
+	if err != nil {
+		createEventOpt.Status = modelschemas.EventStatusFailed
+	}
+	if _, err_ := services.EventService.Create(ctx, createEventOpt); err_ != nil {
+		logrus.Errorf("create event failed: %v", err_)
+	}
+}
+
+// Create starts a canary rollout of the active revision of the deployment
+func (c *deploymentRolloutController) Create(ctx *gin.Context, schema *CreateDeploymentRolloutSchema) (*apischemasv1.DeploymentRolloutSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	deploymentRevisions, _, err := services.DeploymentRevisionService.List(ctx, services.ListDeploymentRevisionOption{
+		DeploymentId: utils.UintPtr(deployment.ID),
+		Status:       modelschemas.DeploymentRevisionStatusPtr(modelschemas.DeploymentRevisionStatusActive),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list active deployment revisions")
+	}
+	if len(deploymentRevisions) == 0 {
+		return nil, errors.Errorf("deployment %s has no active revision", deployment.Name)
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	defer func() {
+		c.createEvent(ctx_, user, deployment, "started rollout", err)
+	}()
+
+	rollout, err := services.DeploymentRolloutService.Create(ctx_, services.CreateDeploymentRolloutOption{
+		CreatorId:          user.ID,
+		DeploymentRevision: deploymentRevisions[0],
+		Steps:              schema.Steps,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create deployment rollout")
+	}
+	return transformersv1.ToDeploymentRolloutSchema(ctx_, rollout)
+}
+
+type ListDeploymentRolloutSchema struct {
+	schemasv1.ListQuerySchema
+	GetDeploymentSchema
+}
+
+func (c *deploymentRolloutController) List(ctx *gin.Context, schema *ListDeploymentRolloutSchema) (*apischemasv1.DeploymentRolloutListSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	rollouts, total, err := services.DeploymentRolloutService.List(ctx, services.ListDeploymentRolloutOption{
+		BaseListOption: services.BaseListOption{
+			Start: utils.UintPtr(schema.Start),
+			Count: utils.UintPtr(schema.Count),
+		},
+		DeploymentId: utils.UintPtr(deployment.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment rollouts")
+	}
+	rolloutSchemas, err := transformersv1.ToDeploymentRolloutSchemas(ctx, rollouts)
+	return &apischemasv1.DeploymentRolloutListSchema{
+		BaseListSchema: schemasv1.BaseListSchema{
+			Total: total,
+			Start: schema.Start,
+			Count: schema.Count,
+		},
+		Items: rolloutSchemas,
+	}, err
+}
+
+func (c *deploymentRolloutController) Get(ctx *gin.Context, schema *GetDeploymentRolloutSchema) (*apischemasv1.DeploymentRolloutSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	rollout, err := schema.GetDeploymentRollout(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	return transformersv1.ToDeploymentRolloutSchema(ctx, rollout)
+}
+
+// Promote ends the rollout by making the canary the stable target without waiting for the remaining steps
+func (c *deploymentRolloutController) Promote(ctx *gin.Context, schema *GetDeploymentRolloutSchema) (*apischemasv1.DeploymentRolloutSchema, error) {
+	return c.finish(ctx, schema, "promoted rollout", services.DeploymentRolloutService.Promote)
+}
+
+// Abort ends the rollout by removing the canary
+func (c *deploymentRolloutController) Abort(ctx *gin.Context, schema *GetDeploymentRolloutSchema) (*apischemasv1.DeploymentRolloutSchema, error) {
+	return c.finish(ctx, schema, "aborted rollout", services.DeploymentRolloutService.Abort)
+}
+
+func (c *deploymentRolloutController) finish(ctx *gin.Context, schema *GetDeploymentRolloutSchema, operationName string, finish func(context.Context, *models.DeploymentRollout, uint) error) (*apischemasv1.DeploymentRolloutSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	rollout, err := schema.GetDeploymentRollout(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint: ineffassign, staticcheck
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	defer func() {
+		c.createEvent(ctx_, user, deployment, operationName, err)
+	}()
+
+	err = finish(ctx_, rollout, user.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, operationName)
+	}
+	return transformersv1.ToDeploymentRolloutSchema(ctx_, rollout)
+}
@@ -0,0 +1,239 @@
+package controllersv1
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentScheduleController struct {
+	deploymentController
+}
+
+var DeploymentScheduleController = deploymentScheduleController{}
+
+type DeploymentScheduleSchema struct {
+	schemasv1.BaseSchema
+	Name         string                         `json:"name"`
+	ResourceType modelschemas.ResourceType      `json:"resource_type"`
+	Creator      *schemasv1.UserSchema          `json:"creator"`
+	SleepCron    string                         `json:"sleep_cron"`
+	WakeCron     string                         `json:"wake_cron"`
+	Timezone     string                         `json:"timezone"`
+	Enabled      bool                           `json:"enabled"`
+	State        models.DeploymentScheduleState `json:"state"`
+	NextSleepAt  time.Time                      `json:"next_sleep_at"`
+	NextWakeAt   time.Time                      `json:"next_wake_at"`
+	LastRunAt    *time.Time                     `json:"last_run_at"`
+	Message      string                         `json:"message"`
+}
+
+func toDeploymentScheduleSchemas(ctx context.Context, schedules []*models.DeploymentSchedule) ([]*DeploymentScheduleSchema, error) {
+	res := make([]*DeploymentScheduleSchema, 0, len(schedules))
+	for _, schedule := range schedules {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, schedule)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment schedule associated creator")
+		}
+		creatorSchema, err := transformersv1.ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		res = append(res, &DeploymentScheduleSchema{
+			BaseSchema:   transformersv1.ToBaseSchema(schedule),
+			Name:         schedule.Name,
+			ResourceType: schedule.GetResourceType(),
+			Creator:      creatorSchema,
+			SleepCron:    schedule.SleepCron,
+			WakeCron:     schedule.WakeCron,
+			Timezone:     schedule.Timezone,
+			Enabled:      schedule.Enabled,
+			State:        schedule.State,
+			NextSleepAt:  schedule.NextSleepAt,
+			NextWakeAt:   schedule.NextWakeAt,
+			LastRunAt:    schedule.LastRunAt,
+			Message:      schedule.Message,
+		})
+	}
+	return res, nil
+}
+
+func toDeploymentScheduleSchema(ctx context.Context, schedule *models.DeploymentSchedule) (*DeploymentScheduleSchema, error) {
+	ss, err := toDeploymentScheduleSchemas(ctx, []*models.DeploymentSchedule{schedule})
+	if err != nil {
+		return nil, err
+	}
+	return ss[0], nil
+}
+
+type DeploymentScheduleListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*DeploymentScheduleSchema `json:"items"`
+}
+
+type CreateDeploymentScheduleSchema struct {
+	GetDeploymentSchema
+	Name      string `json:"name"`
+	SleepCron string `json:"sleep_cron"`
+	WakeCron  string `json:"wake_cron"`
+	Timezone  string `json:"timezone"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+type GetDeploymentScheduleSchema struct {
+	GetDeploymentSchema
+	ScheduleUid string `path:"scheduleUid"`
+}
+
+func (s *GetDeploymentScheduleSchema) GetDeploymentSchedule(ctx context.Context, deployment *models.Deployment) (*models.DeploymentSchedule, error) {
+	schedule, err := services.DeploymentScheduleService.GetByUid(ctx, s.ScheduleUid)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment schedule")
+	}
+	if schedule.DeploymentId != deployment.ID {
+		return nil, errors.New("deployment schedule not found")
+	}
+	return schedule, nil
+}
+
+type UpdateDeploymentScheduleSchema struct {
+	GetDeploymentScheduleSchema
+	SleepCron *string `json:"sleep_cron"`
+	WakeCron  *string `json:"wake_cron"`
+	Timezone  *string `json:"timezone"`
+	Enabled   *bool   `json:"enabled"`
+}
+
+// Create adds a schedule which scales the deployment to zero on the sleep cron and restores it on the wake cron
+func (c *deploymentScheduleController) Create(ctx *gin.Context, schema *CreateDeploymentScheduleSchema) (*DeploymentScheduleSchema, error) {
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	enabled := true
+	if schema.Enabled != nil {
+		enabled = *schema.Enabled
+	}
+	schedule, err := services.DeploymentScheduleService.Create(ctx, services.CreateDeploymentScheduleOption{
+		CreatorId:    user.ID,
+		DeploymentId: deployment.ID,
+		Name:         schema.Name,
+		SleepCron:    schema.SleepCron,
+		WakeCron:     schema.WakeCron,
+		Timezone:     schema.Timezone,
+		Enabled:      enabled,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create deployment schedule")
+	}
+	return toDeploymentScheduleSchema(ctx, schedule)
+}
+
+func (c *deploymentScheduleController) Update(ctx *gin.Context, schema *UpdateDeploymentScheduleSchema) (*DeploymentScheduleSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	schedule, err := schema.GetDeploymentSchedule(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err = services.DeploymentScheduleService.Update(ctx, schedule, services.UpdateDeploymentScheduleOption{
+		SleepCron: schema.SleepCron,
+		WakeCron:  schema.WakeCron,
+		Timezone:  schema.Timezone,
+		Enabled:   schema.Enabled,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update deployment schedule")
+	}
+	return toDeploymentScheduleSchema(ctx, schedule)
+}
+
+func (c *deploymentScheduleController) Get(ctx *gin.Context, schema *GetDeploymentScheduleSchema) (*DeploymentScheduleSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	schedule, err := schema.GetDeploymentSchedule(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	return toDeploymentScheduleSchema(ctx, schedule)
+}
+
+type ListDeploymentScheduleSchema struct {
+	schemasv1.ListQuerySchema
+	GetDeploymentSchema
+}
+
+func (c *deploymentScheduleController) List(ctx *gin.Context, schema *ListDeploymentScheduleSchema) (*DeploymentScheduleListSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	schedules, total, err := services.DeploymentScheduleService.List(ctx, services.ListDeploymentScheduleOption{
+		BaseListOption: services.BaseListOption{
+			Start: utils.UintPtr(schema.Start),
+			Count: utils.UintPtr(schema.Count),
+		},
+		DeploymentId: utils.UintPtr(deployment.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment schedules")
+	}
+	scheduleSchemas, err := toDeploymentScheduleSchemas(ctx, schedules)
+	return &DeploymentScheduleListSchema{
+		BaseListSchema: schemasv1.BaseListSchema{
+			Total: total,
+			Start: schema.Start,
+			Count: schema.Count,
+		},
+		Items: scheduleSchemas,
+	}, err
+}
+
+// Delete removes the schedule, an asleep deployment stays scaled to zero until it is updated
+func (c *deploymentScheduleController) Delete(ctx *gin.Context, schema *GetDeploymentScheduleSchema) (*DeploymentScheduleSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canUpdate(ctx, deployment); err != nil {
+		return nil, err
+	}
+	schedule, err := schema.GetDeploymentSchedule(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err = services.DeploymentScheduleService.Delete(ctx, schedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete deployment schedule")
+	}
+	return toDeploymentScheduleSchema(ctx, schedule)
+}
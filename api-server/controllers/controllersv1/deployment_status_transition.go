@@ -0,0 +1,97 @@
+package controllersv1
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentStatusTransitionController struct {
+	deploymentController
+}
+
+var DeploymentStatusTransitionController = deploymentStatusTransitionController{}
+
+type DeploymentStatusTransitionSchema struct {
+	schemasv1.BaseSchema
+	ResourceType  modelschemas.ResourceType        `json:"resource_type"`
+	DeploymentUid string                           `json:"deployment_uid"`
+	FromStatus    modelschemas.DeploymentStatus    `json:"from_status"`
+	ToStatus      modelschemas.DeploymentStatus    `json:"to_status"`
+	Evidence      *models.DeploymentStatusEvidence `json:"evidence"`
+}
+
+func toDeploymentStatusTransitionSchemas(ctx context.Context, transitions []*models.DeploymentStatusTransition) ([]*DeploymentStatusTransitionSchema, error) {
+	res := make([]*DeploymentStatusTransitionSchema, 0, len(transitions))
+	for _, transition := range transitions {
+		deployment, err := services.DeploymentService.GetAssociatedDeployment(ctx, transition)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment status transition associated deployment")
+		}
+		res = append(res, &DeploymentStatusTransitionSchema{
+			BaseSchema:    transformersv1.ToBaseSchema(transition),
+			ResourceType:  transition.GetResourceType(),
+			DeploymentUid: deployment.Uid,
+			FromStatus:    transition.FromStatus,
+			ToStatus:      transition.ToStatus,
+			Evidence:      transition.Evidence,
+		})
+	}
+	return res, nil
+}
+
+type DeploymentStatusTransitionListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*DeploymentStatusTransitionSchema `json:"items"`
+}
+
+type ListDeploymentStatusTransitionSchema struct {
+	schemasv1.ListQuerySchema
+	GetDeploymentSchema
+	Since *time.Time `query:"since"`
+	Until *time.Time `query:"until"`
+}
+
+// List returns the status timeline of the deployment, the latest transitions first
+func (c *deploymentStatusTransitionController) List(ctx *gin.Context, schema *ListDeploymentStatusTransitionSchema) (*DeploymentStatusTransitionListSchema, error) {
+	deployment, err := schema.GetDeployment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, deployment); err != nil {
+		return nil, err
+	}
+	transitions, total, err := services.DeploymentStatusTransitionService.List(ctx, services.ListDeploymentStatusTransitionOption{
+		BaseListOption: services.BaseListOption{
+			Start: utils.UintPtr(schema.Start),
+			Count: utils.UintPtr(schema.Count),
+		},
+		DeploymentId: utils.UintPtr(deployment.ID),
+		Since:        schema.Since,
+		Until:        schema.Until,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment status transitions")
+	}
+	for _, transition := range transitions {
+		transition.SetAssociatedDeploymentCache(deployment)
+	}
+	transitionSchemas, err := toDeploymentStatusTransitionSchemas(ctx, transitions)
+	return &DeploymentStatusTransitionListSchema{
+		BaseListSchema: schemasv1.BaseListSchema{
+			Total: total,
+			Start: schema.Start,
+			Count: schema.Count,
+		},
+		Items: transitionSchemas,
+	}, err
+}
@@ -35,12 +35,12 @@ func (c *kubeController) GetPodKubeEvents(ctx *gin.Context, schema *GetClusterSc
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -203,7 +203,7 @@ func (c *kubeController) GetPodKubeEvents(ctx *gin.Context, schema *GetClusterSc
 
 	send()
 
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	removeHandler, err := services.KubeInformerManager.AddEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if event, ok := obj.(*corev1.Event); ok {
 				if !filter(event) {
@@ -229,6 +229,11 @@ func (c *kubeController) GetPodKubeEvents(ctx *gin.Context, schema *GetClusterSc
 			}
 		},
 	})
+	if err != nil {
+		err = errors.Wrap(err, "add event handler")
+		return err
+	}
+	defer removeHandler()
 
 	func() {
 		ticker := time.NewTicker(time.Second * 10)
@@ -257,12 +262,12 @@ func (c *kubeController) GetDeploymentKubeEvents(ctx *gin.Context, schema *GetDe
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -433,7 +438,7 @@ func (c *kubeController) GetDeploymentKubeEvents(ctx *gin.Context, schema *GetDe
 
 	send()
 
-	eventInformer_.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	removeHandler, err := services.KubeInformerManager.AddEventHandler(eventInformer_, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if event, ok := obj.(*corev1.Event); ok {
 				if !eventFilter(event) {
@@ -459,6 +464,11 @@ func (c *kubeController) GetDeploymentKubeEvents(ctx *gin.Context, schema *GetDe
 			}
 		},
 	})
+	if err != nil {
+		err = errors.Wrap(err, "add event handler")
+		return err
+	}
+	defer removeHandler()
 
 	func() {
 		ticker := time.NewTicker(time.Second * 10)
@@ -300,12 +300,12 @@ func (c *logController) TailDeploymentPodLog(ctx *gin.Context, schema *GetDeploy
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -364,12 +364,12 @@ func (c *logController) TailClusterPodLog(ctx *gin.Context, schema *GetClusterSc
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -61,7 +61,6 @@ func (c *modelController) canUpdate(ctx context.Context, model *models.Model) er
 	return ModelRepositoryController.canUpdate(ctx, modelRepository)
 }
 
-// nolint: unused
 func (c *modelController) canOperate(ctx context.Context, model *models.Model) error {
 	modelRepository, err := services.ModelRepositoryService.GetAssociatedModelRepository(ctx, model)
 	if err != nil {
@@ -500,6 +499,57 @@ func (c *modelController) Get(ctx *gin.Context, schema *GetModelSchema) (*schema
 	return transformersv1.ToModelFullSchema(ctx, model)
 }
 
+func (c *modelController) Delete(ctx *gin.Context, schema *GetModelSchema) (*schemasv1.ModelSchema, error) {
+	model, err := schema.GetModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, model); err != nil {
+		return nil, err
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	// the event must be created before the deletion so that it can record the resource name
+	_, err = services.EventService.Create(ctx_, services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &org.ID,
+		ResourceType:   modelschemas.ResourceTypeModel,
+		ResourceId:     model.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "deleted",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create event")
+	}
+	modelSchema, err := transformersv1.ToModelSchema(ctx_, model)
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.ModelService.Delete(ctx_, model)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete model")
+	}
+	return modelSchema, nil
+}
+
 type ListModelDeploymentSchema struct {
 	schemasv1.ListQuerySchema
 	GetModelSchema
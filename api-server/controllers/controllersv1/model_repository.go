@@ -9,6 +9,7 @@ import (
 	"github.com/huandu/xstrings"
 	"github.com/pkg/errors"
 
+	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai-schemas/schemasv1"
 	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
@@ -56,7 +57,6 @@ func (c *modelRepositoryController) canUpdate(ctx context.Context, modelReposito
 	return OrganizationController.canUpdate(ctx, organization)
 }
 
-// nolint: unused
 func (c *modelRepositoryController) canOperate(ctx context.Context, modelRepository *models.ModelRepository) error {
 	organization, err := services.OrganizationService.GetAssociatedOrganization(ctx, modelRepository)
 	if err != nil {
@@ -128,6 +128,106 @@ func (c *modelRepositoryController) Get(ctx *gin.Context, schema *GetModelReposi
 	return transformersv1.ToModelRepositorySchema(ctx, modelRepository)
 }
 
+func (c *modelRepositoryController) Delete(ctx *gin.Context, schema *GetModelRepositorySchema) (*schemasv1.ModelRepositorySchema, error) {
+	modelRepository, err := schema.GetModelRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, modelRepository); err != nil {
+		return nil, err
+	}
+	user, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	apiTokenName := ""
+	if user.ApiToken != nil {
+		apiTokenName = user.ApiToken.Name
+	}
+	// the event must be created before the deletion so that it can record the resource name
+	_, err = services.EventService.Create(ctx_, services.CreateEventOption{
+		CreatorId:      user.ID,
+		ApiTokenName:   apiTokenName,
+		OrganizationId: &org.ID,
+		ResourceType:   modelschemas.ResourceTypeModelRepository,
+		ResourceId:     modelRepository.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "deleted",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create event")
+	}
+	modelRepositorySchema, err := transformersv1.ToModelRepositorySchema(ctx_, modelRepository)
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.ModelRepositoryService.Delete(ctx_, modelRepository)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete modelRepository")
+	}
+	return modelRepositorySchema, nil
+}
+
+func (c *modelRepositoryController) GetRetentionPolicy(ctx *gin.Context, schema *GetModelRepositorySchema) (*RetentionPolicySchema, error) {
+	modelRepository, err := schema.GetModelRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, modelRepository); err != nil {
+		return nil, err
+	}
+	return toRetentionPolicySchema(modelRepository.RetentionPolicy), nil
+}
+
+type UpdateModelRepositoryRetentionPolicySchema struct {
+	RetentionPolicySchema
+	GetModelRepositorySchema
+}
+
+func (c *modelRepositoryController) UpdateRetentionPolicy(ctx *gin.Context, schema *UpdateModelRepositoryRetentionPolicySchema) (*RetentionPolicySchema, error) {
+	modelRepository, err := schema.GetModelRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, modelRepository); err != nil {
+		return nil, err
+	}
+	retentionPolicy := toRetentionPolicy(schema.RetentionPolicySchema)
+	modelRepository, err = services.ModelRepositoryService.Update(ctx, modelRepository, services.UpdateModelRepositoryOption{
+		RetentionPolicy: &retentionPolicy,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update modelRepository retention policy")
+	}
+	return toRetentionPolicySchema(modelRepository.RetentionPolicy), nil
+}
+
+func (c *modelRepositoryController) DryRunRetentionPolicy(ctx *gin.Context, schema *GetModelRepositorySchema) ([]*schemasv1.ModelSchema, error) {
+	modelRepository, err := schema.GetModelRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, modelRepository); err != nil {
+		return nil, err
+	}
+	models, err := services.RetentionService.ListCollectableModels(ctx, modelRepository)
+	if err != nil {
+		return nil, errors.Wrap(err, "list collectable models")
+	}
+	return transformersv1.ToModelSchemas(ctx, models)
+}
+
 type ListModelRepositorySchema struct {
 	schemasv1.ListQuerySchema
 	GetOrganizationSchema
@@ -0,0 +1,32 @@
+package controllersv1
+
+import (
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+type RetentionPolicySchema struct {
+	KeepLastN      *uint `json:"keep_last_n"`
+	KeepWithinDays *uint `json:"keep_within_days"`
+}
+
+func toRetentionPolicySchema(policy *models.RetentionPolicy) *RetentionPolicySchema {
+	if policy == nil {
+		return &RetentionPolicySchema{}
+	}
+	return &RetentionPolicySchema{
+		KeepLastN:      policy.KeepLastN,
+		KeepWithinDays: policy.KeepWithinDays,
+	}
+}
+
+// toRetentionPolicy returns nil for a schema without any rule, which disables the garbage collection
+func toRetentionPolicy(schema RetentionPolicySchema) *models.RetentionPolicy {
+	policy := &models.RetentionPolicy{
+		KeepLastN:      schema.KeepLastN,
+		KeepWithinDays: schema.KeepWithinDays,
+	}
+	if policy.IsEmpty() {
+		return nil
+	}
+	return policy
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"reflect"
 	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -13,6 +12,7 @@ import (
 
 	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
 	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
 )
@@ -26,12 +26,12 @@ var SubscriptionController = subscriptionController{}
 
 func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	currentUser, err := services.GetCurrentUser(ctx)
 	if err != nil {
@@ -42,9 +42,14 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 	resourceUidsMap := make(map[modelschemas.ResourceType][]string)
 	schemasCache := make(map[string]interface{})
 
-	pollingCtx, cancel := context.WithCancel(ctx)
+	subscriptionCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	subscription := services.NotificationService.Subscribe()
+	defer services.NotificationService.Unsubscribe(subscription)
+
+	subscribed := make(chan struct{}, 1)
+
 	go func() {
 		for {
 			var req schemasv1.SubscriptionReqSchema
@@ -127,6 +132,38 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 						}
 						actualUids = append(actualUids, deployment.Uid)
 					}
+				case models.ResourceTypeDeploymentRollout:
+					rollouts, err := services.DeploymentRolloutService.ListByUids(ctx, req.Payload.ResourceUids)
+					if err != nil {
+						writeWsError(conn, err)
+						continue
+					}
+					for _, rollout := range rollouts {
+						deployment, err := services.DeploymentService.GetAssociatedDeployment(ctx, rollout)
+						if err != nil {
+							writeWsError(conn, err)
+							continue
+						}
+						if err = DeploymentController.canView(ctx, deployment); err != nil {
+							writeWsError(conn, err)
+							continue
+						}
+						actualUids = append(actualUids, rollout.Uid)
+					}
+				case models.ResourceTypeDeploymentStatusTransition:
+					// the transitions are subscribed to with the uids of their deployments
+					deployments, err := services.DeploymentService.ListByUids(ctx, req.Payload.ResourceUids)
+					if err != nil {
+						writeWsError(conn, err)
+						continue
+					}
+					for _, deployment := range deployments {
+						if err = DeploymentController.canView(ctx, deployment); err != nil {
+							writeWsError(conn, err)
+							continue
+						}
+						actualUids = append(actualUids, deployment.Uid)
+					}
 				default:
 					continue
 				}
@@ -138,6 +175,10 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 				uids = append(uids, actualUids...)
 				resourceUidsMap[req.Payload.ResourceType] = uids
 				mu.Unlock()
+				select {
+				case subscribed <- struct{}{}:
+				default:
+				}
 			case schemasv1.SubscriptionActionUnsubscribe:
 				mu.Lock()
 				uids, ok := resourceUidsMap[req.Payload.ResourceType]
@@ -160,8 +201,33 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 		}
 	}()
 
-	send := func() error {
+	// send pushes the subscribed resources which have changed, all of them when changes is nil
+	send := func(changes []services.ResourceChange) error {
+		mu.RLock()
+		resourceUidsMap_ := make(map[modelschemas.ResourceType][]string, len(resourceUidsMap))
 		for resourceType, uids := range resourceUidsMap {
+			resourceUidsMap_[resourceType] = uids
+		}
+		mu.RUnlock()
+		if changes != nil {
+			changedUids := make(map[services.ResourceChange]struct{}, len(changes))
+			for _, change := range changes {
+				changedUids[change] = struct{}{}
+			}
+			for resourceType, uids := range resourceUidsMap_ {
+				uids_ := make([]string, 0)
+				for _, uid := range uids {
+					if _, ok := changedUids[services.ResourceChange{ResourceType: resourceType, ResourceUid: uid}]; ok {
+						uids_ = append(uids_, uid)
+					}
+				}
+				resourceUidsMap_[resourceType] = uids_
+			}
+		}
+		for resourceType, uids := range resourceUidsMap_ {
+			if len(uids) == 0 {
+				continue
+			}
 			// nolint: exhaustive
 			switch resourceType {
 			case modelschemas.ResourceTypeBento:
@@ -278,6 +344,90 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 						return err
 					}
 				}
+			case models.ResourceTypeDeploymentRollout:
+				rollouts, err := services.DeploymentRolloutService.ListByUids(ctx, uids)
+				if err != nil {
+					return err
+				}
+				rolloutSchemas, err := transformersv1.ToDeploymentRolloutSchemas(ctx, rollouts)
+				if err != nil {
+					return err
+				}
+				for _, rolloutSchema := range rolloutSchemas {
+					isEqual := func() bool {
+						mu.Lock()
+						defer func() {
+							schemasCache[rolloutSchema.Uid] = rolloutSchema
+						}()
+						defer mu.Unlock()
+						if oldSchema, ok := schemasCache[rolloutSchema.Uid]; ok {
+							return reflect.DeepEqual(oldSchema, rolloutSchema)
+						}
+						return false
+					}()
+
+					if isEqual {
+						continue
+					}
+
+					err = conn.WriteJSON(&schemasv1.WsRespSchema{
+						Type:    schemasv1.WsRespTypeSuccess,
+						Message: "",
+						Payload: &schemasv1.SubscriptionRespSchema{
+							ResourceType: rolloutSchema.ResourceType,
+							Payload:      rolloutSchema,
+						},
+					})
+					if err != nil {
+						return err
+					}
+				}
+			case models.ResourceTypeDeploymentStatusTransition:
+				deployments, err := services.DeploymentService.ListByUids(ctx, uids)
+				if err != nil {
+					return err
+				}
+				deploymentIds := make([]uint, 0, len(deployments))
+				for _, deployment := range deployments {
+					deploymentIds = append(deploymentIds, deployment.ID)
+				}
+				transitions, err := services.DeploymentStatusTransitionService.ListLatestByDeploymentIds(ctx, deploymentIds)
+				if err != nil {
+					return err
+				}
+				transitionSchemas, err := toDeploymentStatusTransitionSchemas(ctx, transitions)
+				if err != nil {
+					return err
+				}
+				for _, transitionSchema := range transitionSchemas {
+					// a transition never changes, it is only pushed once and the last one pushed per deployment is cached
+					isPushed := func() bool {
+						mu.Lock()
+						defer mu.Unlock()
+						cacheKey := string(models.ResourceTypeDeploymentStatusTransition) + ":" + transitionSchema.DeploymentUid
+						if oldSchema, ok := schemasCache[cacheKey]; ok && oldSchema.(*DeploymentStatusTransitionSchema).Uid == transitionSchema.Uid {
+							return true
+						}
+						schemasCache[cacheKey] = transitionSchema
+						return false
+					}()
+
+					if isPushed {
+						continue
+					}
+
+					err = conn.WriteJSON(&schemasv1.WsRespSchema{
+						Type:    schemasv1.WsRespTypeSuccess,
+						Message: "",
+						Payload: &schemasv1.SubscriptionRespSchema{
+							ResourceType: transitionSchema.ResourceType,
+							Payload:      transitionSchema,
+						},
+					})
+					if err != nil {
+						return err
+					}
+				}
 			default:
 				continue
 			}
@@ -286,24 +436,21 @@ func (c *subscriptionController) SubscribeResource(ctx *gin.Context) error {
 		return nil
 	}
 
-	func() {
-		ticker := time.NewTicker(time.Second * 10)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-pollingCtx.Done():
-				return
-			default:
-				err = send()
-				if err != nil {
-					writeWsError(conn, err)
-				}
+	for {
+		select {
+		case <-subscriptionCtx.Done():
+			return nil
+		case <-subscribed:
+			err = send(nil)
+		case <-subscription.Notify():
+			changes, resync := subscription.Pop()
+			if resync {
+				changes = nil
 			}
-
-			<-ticker.C
+			err = send(changes)
 		}
-	}()
-
-	return nil
+		if err != nil {
+			writeWsError(conn, err)
+		}
+	}
 }
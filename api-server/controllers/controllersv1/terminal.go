@@ -406,12 +406,12 @@ func (c *terminalController) GetDeploymentPodTerminal(ctx *gin.Context, schema *
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -502,12 +502,12 @@ func (c *terminalController) GetClusterPodTerminal(ctx *gin.Context, schema *Get
 	var err error
 
 	ctx.Request.Header.Del("Origin")
-	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	conn, err := upgradeWebsocket(ctx)
 	if err != nil {
 		logrus.Errorf("ws connect failed: %q", err.Error())
 		return err
 	}
-	defer conn.Close()
+	defer closeWebsocket(conn)
 
 	defer func() {
 		writeWsError(conn, err)
@@ -0,0 +1,311 @@
+package controllersv1
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type userGroupController struct {
+	organizationController
+}
+
+var UserGroupController = userGroupController{}
+
+type UserGroupSchema struct {
+	schemasv1.ResourceSchema
+	Creator *schemasv1.UserSchema `json:"creator"`
+}
+
+type UserGroupListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*UserGroupSchema `json:"items"`
+}
+
+func toUserGroupSchemas(ctx context.Context, userGroups []*models.UserGroup) ([]*UserGroupSchema, error) {
+	resources := make([]models.IResource, 0, len(userGroups))
+	for _, userGroup := range userGroups {
+		resources = append(resources, userGroup)
+	}
+	resourceSchemasMap, err := transformersv1.ToResourceSchemasMap(ctx, resources)
+	if err != nil {
+		return nil, errors.Wrap(err, "ToResourceSchemasMap")
+	}
+	res := make([]*UserGroupSchema, 0, len(userGroups))
+	for _, userGroup := range userGroups {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, userGroup)
+		if err != nil {
+			return nil, errors.Wrap(err, "get user group associated creator")
+		}
+		creatorSchema, err := transformersv1.ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		res = append(res, &UserGroupSchema{
+			ResourceSchema: resourceSchemasMap[userGroup.GetUid()],
+			Creator:        creatorSchema,
+		})
+	}
+	return res, nil
+}
+
+func toUserGroupSchema(ctx context.Context, userGroup *models.UserGroup) (*UserGroupSchema, error) {
+	ss, err := toUserGroupSchemas(ctx, []*models.UserGroup{userGroup})
+	if err != nil {
+		return nil, err
+	}
+	return ss[0], nil
+}
+
+type GetUserGroupSchema struct {
+	GetOrganizationSchema
+	UserGroupName string `path:"userGroupName"`
+}
+
+func (s *GetUserGroupSchema) GetUserGroup(ctx context.Context) (*models.UserGroup, error) {
+	org, err := s.GetOrganization(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get organization %s", s.OrgName)
+	}
+	return services.UserGroupService.GetByName(ctx, org.ID, s.UserGroupName)
+}
+
+type CreateUserGroupSchema struct {
+	GetOrganizationSchema
+	Name      string   `json:"name"`
+	Usernames []string `json:"usernames"`
+}
+
+func (c *userGroupController) Create(ctx *gin.Context, schema *CreateUserGroupSchema) (*UserGroupSchema, error) {
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	users, err := services.UserService.ListByNames(ctx, schema.Usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ctx_, df, err := services.StartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	userGroup, err := services.UserGroupService.Create(ctx_, services.CreateUserGroupOption{
+		CreatorId:      currentUser.ID,
+		OrganizationId: org.ID,
+		Name:           schema.Name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create user group")
+	}
+	userIds := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.ID)
+	}
+	err = services.UserGroupService.AddUsers(ctx_, userGroup, currentUser.ID, userIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "add users to user group")
+	}
+	return toUserGroupSchema(ctx_, userGroup)
+}
+
+type ListUserGroupSchema struct {
+	schemasv1.ListQuerySchema
+	GetOrganizationSchema
+}
+
+func (c *userGroupController) List(ctx *gin.Context, schema *ListUserGroupSchema) (*UserGroupListSchema, error) {
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, org); err != nil {
+		return nil, err
+	}
+	userGroups, total, err := services.UserGroupService.List(ctx, services.ListUserGroupOption{
+		BaseListOption: services.BaseListOption{
+			Start:  utils.UintPtr(schema.Start),
+			Count:  utils.UintPtr(schema.Count),
+			Search: schema.Search,
+		},
+		OrganizationId: utils.UintPtr(org.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list user groups")
+	}
+	userGroupSchemas, err := toUserGroupSchemas(ctx, userGroups)
+	if err != nil {
+		return nil, err
+	}
+	return &UserGroupListSchema{
+		BaseListSchema: schemasv1.BaseListSchema{
+			Total: total,
+			Start: schema.Start,
+			Count: schema.Count,
+		},
+		Items: userGroupSchemas,
+	}, nil
+}
+
+func (c *userGroupController) Get(ctx *gin.Context, schema *GetUserGroupSchema) (*UserGroupSchema, error) {
+	userGroup, err := schema.GetUserGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, userGroup)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, org); err != nil {
+		return nil, err
+	}
+	return toUserGroupSchema(ctx, userGroup)
+}
+
+type UpdateUserGroupSchema struct {
+	GetUserGroupSchema
+	Name *string `json:"name"`
+}
+
+func (c *userGroupController) Update(ctx *gin.Context, schema *UpdateUserGroupSchema) (*UserGroupSchema, error) {
+	userGroup, err := schema.GetUserGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, userGroup)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	userGroup, err = services.UserGroupService.Update(ctx, userGroup, services.UpdateUserGroupOption{
+		Name: schema.Name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update user group")
+	}
+	return toUserGroupSchema(ctx, userGroup)
+}
+
+func (c *userGroupController) Delete(ctx *gin.Context, schema *GetUserGroupSchema) (*UserGroupSchema, error) {
+	userGroup, err := schema.GetUserGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, userGroup)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	userGroupSchema, err := toUserGroupSchema(ctx, userGroup)
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.UserGroupService.Delete(ctx, userGroup)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete user group")
+	}
+	return userGroupSchema, nil
+}
+
+func (c *userGroupController) ListUsers(ctx *gin.Context, schema *GetUserGroupSchema) ([]*schemasv1.UserSchema, error) {
+	userGroup, err := schema.GetUserGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, userGroup)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, org); err != nil {
+		return nil, err
+	}
+	users, err := services.UserGroupService.ListUsers(ctx, userGroup)
+	if err != nil {
+		return nil, errors.Wrap(err, "list user group users")
+	}
+	return transformersv1.ToUserSchemas(ctx, users)
+}
+
+type UserGroupUsersSchema struct {
+	GetUserGroupSchema
+	Usernames []string `json:"usernames"`
+}
+
+func (c *userGroupController) getUserIdsForUpdateUsers(ctx context.Context, schema *UserGroupUsersSchema) (*models.UserGroup, []uint, error) {
+	userGroup, err := schema.GetUserGroup(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, userGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, nil, err
+	}
+	users, err := services.UserService.ListByNames(ctx, schema.Usernames)
+	if err != nil {
+		return nil, nil, err
+	}
+	userIds := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.ID)
+	}
+	return userGroup, userIds, nil
+}
+
+func (c *userGroupController) AddUsers(ctx *gin.Context, schema *UserGroupUsersSchema) ([]*schemasv1.UserSchema, error) {
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	userGroup, userIds, err := c.getUserIdsForUpdateUsers(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	err = services.UserGroupService.AddUsers(ctx, userGroup, currentUser.ID, userIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "add users to user group")
+	}
+	users, err := services.UserGroupService.ListUsers(ctx, userGroup)
+	if err != nil {
+		return nil, errors.Wrap(err, "list user group users")
+	}
+	return transformersv1.ToUserSchemas(ctx, users)
+}
+
+func (c *userGroupController) RemoveUsers(ctx *gin.Context, schema *UserGroupUsersSchema) ([]*schemasv1.UserSchema, error) {
+	userGroup, userIds, err := c.getUserIdsForUpdateUsers(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	err = services.UserGroupService.RemoveUsers(ctx, userGroup, userIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "remove users from user group")
+	}
+	users, err := services.UserGroupService.ListUsers(ctx, userGroup)
+	if err != nil {
+		return nil, errors.Wrap(err, "list user group users")
+	}
+	return transformersv1.ToUserSchemas(ctx, users)
+}
@@ -0,0 +1,237 @@
+package controllersv1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/api-server/services"
+	"github.com/bentoml/yatai/api-server/transformers/transformersv1"
+	"github.com/bentoml/yatai/common/consts"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type UserGroupMemberSchema struct {
+	Creator      *schemasv1.UserSchema     `json:"creator"`
+	UserGroup    UserGroupSchema           `json:"user_group"`
+	ResourceType modelschemas.ResourceType `json:"resource_type"`
+	Role         modelschemas.MemberRole   `json:"role"`
+}
+
+func toUserGroupMemberSchemas(ctx context.Context, members []*models.UserGroupMember) ([]*UserGroupMemberSchema, error) {
+	res := make([]*UserGroupMemberSchema, 0, len(members))
+	for _, member := range members {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, member)
+		if err != nil {
+			return nil, errors.Wrap(err, "get user group member associated creator")
+		}
+		creatorSchema, err := transformersv1.ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		userGroup, err := services.UserGroupService.GetAssociatedUserGroup(ctx, member)
+		if err != nil {
+			return nil, errors.Wrap(err, "get user group member associated user group")
+		}
+		userGroupSchema, err := toUserGroupSchema(ctx, userGroup)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &UserGroupMemberSchema{
+			Creator:      creatorSchema,
+			UserGroup:    *userGroupSchema,
+			ResourceType: member.ResourceType,
+			Role:         member.Role,
+		})
+	}
+	return res, nil
+}
+
+type CreateUserGroupMembersSchema struct {
+	UserGroupNames []string                `json:"user_group_names"`
+	Role           modelschemas.MemberRole `json:"role" enum:"guest,developer,admin"`
+}
+
+type DeleteUserGroupMemberSchema struct {
+	UserGroupName string `json:"user_group_name"`
+}
+
+// createUserGroupMembers grants the role on the resource to the user groups, the user groups must belong to the organization of the resource
+func getMissingUserGroupNames(userGroups []*models.UserGroup, names []string) []string {
+	found := make(map[string]struct{}, len(userGroups))
+	for _, userGroup := range userGroups {
+		found[userGroup.Name] = struct{}{}
+	}
+	res := make([]string, 0)
+	for _, name := range names {
+		if _, ok := found[name]; !ok {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func createUserGroupMembers(ctx context.Context, org *models.Organization, resource models.IResource, schema *CreateUserGroupMembersSchema) ([]*UserGroupMemberSchema, error) {
+	currentUser, err := services.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get current user")
+	}
+	if len(schema.UserGroupNames) == 0 {
+		return []*UserGroupMemberSchema{}, nil
+	}
+	userGroups, _, err := services.UserGroupService.List(ctx, services.ListUserGroupOption{
+		OrganizationId: utils.UintPtr(org.ID),
+		Names:          &schema.UserGroupNames,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list user groups")
+	}
+	if missingNames := getMissingUserGroupNames(userGroups, schema.UserGroupNames); len(missingNames) > 0 {
+		return nil, errors.Wrapf(consts.ErrNotFound, "cannot find user groups %s", strings.Join(missingNames, ", "))
+	}
+	members := make([]*models.UserGroupMember, 0, len(userGroups))
+	for _, userGroup := range userGroups {
+		member, err := services.UserGroupMemberService.Create(ctx, services.CreateUserGroupMemberOption{
+			CreatorId:    currentUser.ID,
+			UserGroupId:  userGroup.ID,
+			ResourceType: resource.GetResourceType(),
+			ResourceId:   resource.GetId(),
+			Role:         schema.Role,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "create user group member for user group %s", userGroup.Name)
+		}
+		members = append(members, member)
+	}
+	return toUserGroupMemberSchemas(ctx, members)
+}
+
+func listUserGroupMembers(ctx context.Context, resource models.IResource) ([]*UserGroupMemberSchema, error) {
+	members, err := services.UserGroupMemberService.List(ctx, services.ListUserGroupMemberOption{
+		ResourceType: resource.GetResourceType(),
+		ResourceId:   utils.UintPtr(resource.GetId()),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list user group members")
+	}
+	return toUserGroupMemberSchemas(ctx, members)
+}
+
+func deleteUserGroupMember(ctx context.Context, org *models.Organization, resource models.IResource, schema *DeleteUserGroupMemberSchema) (*UserGroupMemberSchema, error) {
+	userGroup, err := services.UserGroupService.GetByName(ctx, org.ID, schema.UserGroupName)
+	if err != nil {
+		return nil, err
+	}
+	member, err := services.UserGroupMemberService.GetBy(ctx, userGroup.ID, resource.GetResourceType(), resource.GetId())
+	if err != nil {
+		return nil, errors.Wrap(err, "get user group member")
+	}
+	memberSchemas, err := toUserGroupMemberSchemas(ctx, []*models.UserGroupMember{member})
+	if err != nil {
+		return nil, err
+	}
+	_, err = services.UserGroupMemberService.Delete(ctx, member)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete user group member")
+	}
+	return memberSchemas[0], nil
+}
+
+type CreateOrganizationUserGroupMembersSchema struct {
+	CreateUserGroupMembersSchema
+	GetOrganizationSchema
+}
+
+func (c *organizationMemberController) CreateUserGroupMembers(ctx *gin.Context, schema *CreateOrganizationUserGroupMembersSchema) ([]*UserGroupMemberSchema, error) {
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	return createUserGroupMembers(ctx, org, org, &schema.CreateUserGroupMembersSchema)
+}
+
+func (c *organizationMemberController) ListUserGroupMembers(ctx *gin.Context, schema *GetOrganizationSchema) ([]*UserGroupMemberSchema, error) {
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, org); err != nil {
+		return nil, err
+	}
+	return listUserGroupMembers(ctx, org)
+}
+
+type DeleteOrganizationUserGroupMemberSchema struct {
+	DeleteUserGroupMemberSchema
+	GetOrganizationSchema
+}
+
+func (c *organizationMemberController) DeleteUserGroupMember(ctx *gin.Context, schema *DeleteOrganizationUserGroupMemberSchema) (*UserGroupMemberSchema, error) {
+	org, err := schema.GetOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, org); err != nil {
+		return nil, err
+	}
+	return deleteUserGroupMember(ctx, org, org, &schema.DeleteUserGroupMemberSchema)
+}
+
+type CreateClusterUserGroupMembersSchema struct {
+	CreateUserGroupMembersSchema
+	GetClusterSchema
+}
+
+func (c *clusterMemberController) CreateUserGroupMembers(ctx *gin.Context, schema *CreateClusterUserGroupMembersSchema) ([]*UserGroupMemberSchema, error) {
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, cluster); err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return createUserGroupMembers(ctx, org, cluster, &schema.CreateUserGroupMembersSchema)
+}
+
+func (c *clusterMemberController) ListUserGroupMembers(ctx *gin.Context, schema *GetClusterSchema) ([]*UserGroupMemberSchema, error) {
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canView(ctx, cluster); err != nil {
+		return nil, err
+	}
+	return listUserGroupMembers(ctx, cluster)
+}
+
+type DeleteClusterUserGroupMemberSchema struct {
+	DeleteUserGroupMemberSchema
+	GetClusterSchema
+}
+
+func (c *clusterMemberController) DeleteUserGroupMember(ctx *gin.Context, schema *DeleteClusterUserGroupMemberSchema) (*UserGroupMemberSchema, error) {
+	cluster, err := schema.GetCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.canOperate(ctx, cluster); err != nil {
+		return nil, err
+	}
+	org, err := services.OrganizationService.GetAssociatedOrganization(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return deleteUserGroupMember(ctx, org, cluster, &schema.DeleteUserGroupMemberSchema)
+}
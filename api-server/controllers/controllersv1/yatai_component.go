@@ -135,6 +135,14 @@ func (c *yataiComponentController) Register(ctx *gin.Context, schema *RegisterYa
 		return nil, errors.Wrap(err, "register yataiComponent")
 	}
 
+	// the unhealthy components recover on their heartbeat instead of waiting for the next check
+	if yataiComponent.Health != nil && yataiComponent.Health.Status == models.YataiComponentHealthStatusUnhealthy {
+		err = services.YataiComponentService.CheckHealth(ctx_, yataiComponent)
+		if err != nil {
+			return nil, errors.Wrap(err, "check yataiComponent health")
+		}
+	}
+
 	yataiComponentSchema, err := transformersv1.ToYataiComponentSchema(ctx_, yataiComponent)
 	return yataiComponentSchema, err
 }
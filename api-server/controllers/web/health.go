@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bentoml/yatai/api-server/services"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+// Healthz is the liveness probe, it only tells that the server is able to serve requests,
+// a broken dependency is reported by Readyz because restarting the server does not fix it
+func Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz is the readiness probe, it checks the database and the migrations, and fails while the server is draining.
+// The s3 is only reported, a blip of an external dependency shared by all the replicas must not take them all out of the service at once.
+func Readyz(ctx *gin.Context) {
+	if services.HealthService.IsDraining() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	checks := []struct {
+		name     string
+		check    func(context.Context) error
+		required bool
+	}{
+		{"database", services.HealthService.CheckDatabase, true},
+		{"migrations", services.HealthService.CheckMigrations, true},
+		{"s3", services.HealthService.CheckS3, false},
+	}
+	status := http.StatusOK
+	statusText := "ok"
+	results := make(map[string]string, len(checks))
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := check.check(checkCtx)
+		cancel()
+		if err != nil {
+			results[check.name] = err.Error()
+			if check.required {
+				status = http.StatusServiceUnavailable
+				statusText = "unavailable"
+			} else if status == http.StatusOK {
+				statusText = "degraded"
+			}
+			continue
+		}
+		results[check.name] = "ok"
+	}
+	ctx.JSON(status, gin.H{"status": statusText, "checks": results})
+}
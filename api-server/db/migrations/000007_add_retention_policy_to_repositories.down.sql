@@ -0,0 +1,2 @@
+ALTER TABLE "bento_repository" DROP COLUMN IF EXISTS "retention_policy";
+ALTER TABLE "model_repository" DROP COLUMN IF EXISTS "retention_policy";
@@ -0,0 +1,2 @@
+ALTER TABLE "bento_repository" ADD COLUMN IF NOT EXISTS "retention_policy" TEXT;
+ALTER TABLE "model_repository" ADD COLUMN IF NOT EXISTS "retention_policy" TEXT;
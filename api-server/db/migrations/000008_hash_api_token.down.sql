@@ -0,0 +1,11 @@
+-- the plaintext secrets cannot be recovered from the hashes, so every api token has to be recreated after this migration
+DROP INDEX IF EXISTS "idx_apiToken_tokenPrefix";
+
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "token" VARCHAR(256);
+UPDATE "api_token" SET token = 'revoked-' || uid WHERE token IS NULL;
+ALTER TABLE "api_token" ALTER COLUMN "token" SET NOT NULL;
+ALTER TABLE "api_token" ADD CONSTRAINT "api_token_token_key" UNIQUE ("token");
+
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "token_prefix";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "token_salt";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "token_hash";
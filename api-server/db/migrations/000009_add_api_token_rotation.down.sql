@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "idx_apiToken_previousTokenPrefix";
+
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "previous_token_prefix";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "previous_token_salt";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "previous_token_hash";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "previous_token_expired_at";
+ALTER TABLE "api_token" DROP COLUMN IF EXISTS "rotated_at";
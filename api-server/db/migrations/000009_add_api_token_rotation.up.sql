@@ -0,0 +1,7 @@
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "previous_token_prefix" VARCHAR(16) DEFAULT NULL;
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "previous_token_salt" VARCHAR(64) DEFAULT NULL;
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "previous_token_hash" VARCHAR(128) DEFAULT NULL;
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "previous_token_expired_at" TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+ALTER TABLE "api_token" ADD COLUMN IF NOT EXISTS "rotated_at" TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX "idx_apiToken_previousTokenPrefix" ON "api_token" ("previous_token_prefix");
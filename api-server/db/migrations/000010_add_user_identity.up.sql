@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "user_identity" (
+    id SERIAL PRIMARY KEY,
+    uid VARCHAR(32) UNIQUE NOT NULL DEFAULT generate_object_id(),
+    provider VARCHAR(128) NOT NULL,
+    subject VARCHAR(256) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES "user"("id") ON DELETE CASCADE,
+    latest_login_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX "uk_userIdentity_provider_subject" ON "user_identity" ("provider", "subject");
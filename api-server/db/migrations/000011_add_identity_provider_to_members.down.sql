@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_orgMember_identityProvider";
+DROP INDEX IF EXISTS "idx_clusterMember_identityProvider";
+
+ALTER TABLE "organization_member" DROP COLUMN IF EXISTS "identity_provider";
+ALTER TABLE "cluster_member" DROP COLUMN IF EXISTS "identity_provider";
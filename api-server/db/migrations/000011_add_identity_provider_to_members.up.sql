@@ -0,0 +1,5 @@
+ALTER TABLE "organization_member" ADD COLUMN IF NOT EXISTS "identity_provider" VARCHAR(128) DEFAULT NULL;
+ALTER TABLE "cluster_member" ADD COLUMN IF NOT EXISTS "identity_provider" VARCHAR(128) DEFAULT NULL;
+
+CREATE INDEX "idx_orgMember_identityProvider" ON "organization_member" ("identity_provider");
+CREATE INDEX "idx_clusterMember_identityProvider" ON "cluster_member" ("identity_provider");
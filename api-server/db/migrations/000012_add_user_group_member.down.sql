@@ -0,0 +1,8 @@
+DELETE FROM "organization_member" WHERE user_id IS NULL AND user_group_id IS NOT NULL;
+DELETE FROM "cluster_member" WHERE user_id IS NULL AND user_group_id IS NOT NULL;
+
+DROP INDEX IF EXISTS "uk_clusterMember_clusterId_userGroupId";
+DROP INDEX IF EXISTS "uk_orgMember_orgId_userGroupId";
+
+DROP INDEX IF EXISTS "idx_userGroupUserRelation_userId";
+DROP INDEX IF EXISTS "uk_userGroupUserRelation_userGroupId_userId";
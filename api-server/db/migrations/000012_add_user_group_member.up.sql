@@ -0,0 +1,8 @@
+ALTER TYPE "resource_type" ADD VALUE IF NOT EXISTS 'user_group';
+
+CREATE UNIQUE INDEX "uk_userGroupUserRelation_userGroupId_userId" ON "user_group_user_relation" ("user_group_id", "user_id");
+CREATE INDEX "idx_userGroupUserRelation_userId" ON "user_group_user_relation" ("user_id");
+
+-- the roles of the user groups are granted in the member tables, in the rows without user
+CREATE UNIQUE INDEX "uk_orgMember_orgId_userGroupId" ON "organization_member" ("organization_id", "user_group_id") WHERE "user_id" IS NULL;
+CREATE UNIQUE INDEX "uk_clusterMember_clusterId_userGroupId" ON "cluster_member" ("cluster_id", "user_group_id") WHERE "user_id" IS NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "deployment_member" (
+    id SERIAL PRIMARY KEY,
+    uid VARCHAR(32) UNIQUE NOT NULL DEFAULT generate_object_id(),
+    deployment_id INTEGER NOT NULL REFERENCES "deployment"("id") ON DELETE CASCADE,
+    -- a row grants the role either to a user or to a user group
+    user_id INTEGER REFERENCES "user"("id") ON DELETE CASCADE,
+    user_group_id INTEGER REFERENCES "user_group"("id") ON DELETE CASCADE,
+    role member_role NOT NULL DEFAULT 'guest',
+    creator_id INTEGER NOT NULL REFERENCES "user"("id") ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX "uk_deploymentMember_deploymentId_userId" ON "deployment_member" ("deployment_id", "user_id");
+CREATE UNIQUE INDEX "uk_deploymentMember_deploymentId_userGroupId" ON "deployment_member" ("deployment_id", "user_group_id");
@@ -0,0 +1 @@
+ALTER TABLE "deployment_revision" DROP COLUMN IF EXISTS "rollback_from_revision_id";
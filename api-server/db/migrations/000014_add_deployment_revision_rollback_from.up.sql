@@ -0,0 +1 @@
+ALTER TABLE "deployment_revision" ADD COLUMN IF NOT EXISTS "rollback_from_revision_id" INTEGER REFERENCES "deployment_revision"("id") ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "deployment_rollout";
+DROP TYPE IF EXISTS "deployment_rollout_status";
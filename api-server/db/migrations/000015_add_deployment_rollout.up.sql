@@ -0,0 +1,24 @@
+CREATE TYPE "deployment_rollout_status" AS ENUM ('running', 'promoted', 'rolled_back', 'aborted');
+
+CREATE TABLE IF NOT EXISTS "deployment_rollout" (
+    id SERIAL PRIMARY KEY,
+    uid VARCHAR(32) UNIQUE NOT NULL DEFAULT generate_object_id(),
+    deployment_id INTEGER NOT NULL REFERENCES "deployment"("id") ON DELETE CASCADE,
+    deployment_revision_id INTEGER NOT NULL REFERENCES "deployment_revision"("id") ON DELETE CASCADE,
+    canary_deployment_target_id INTEGER NOT NULL REFERENCES "deployment_target"("id") ON DELETE CASCADE,
+    steps JSONB NOT NULL,
+    current_step INTEGER NOT NULL DEFAULT 0,
+    status deployment_rollout_status NOT NULL DEFAULT 'running',
+    message TEXT NOT NULL DEFAULT '',
+    step_started_at TIMESTAMP WITH TIME ZONE,
+    next_step_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    creator_id INTEGER NOT NULL REFERENCES "user"("id") ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX "idx_deploymentRollout_deploymentId" ON "deployment_rollout" ("deployment_id");
+CREATE INDEX "idx_deploymentRollout_status_nextStepAt" ON "deployment_rollout" ("status", "next_step_at");
+CREATE UNIQUE INDEX "uk_deploymentRollout_deploymentId_running" ON "deployment_rollout" ("deployment_id") WHERE status = 'running' AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "deployment_schedule";
+DROP TYPE IF EXISTS "deployment_schedule_state";
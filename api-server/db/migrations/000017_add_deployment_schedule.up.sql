@@ -0,0 +1,27 @@
+CREATE TYPE "deployment_schedule_state" AS ENUM ('awake', 'asleep');
+
+CREATE TABLE IF NOT EXISTS "deployment_schedule" (
+    id SERIAL PRIMARY KEY,
+    uid VARCHAR(32) UNIQUE NOT NULL DEFAULT generate_object_id(),
+    name VARCHAR(128) NOT NULL,
+    deployment_id INTEGER NOT NULL REFERENCES "deployment"("id") ON DELETE CASCADE,
+    sleep_cron VARCHAR(128) NOT NULL,
+    wake_cron VARCHAR(128) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    state deployment_schedule_state NOT NULL DEFAULT 'awake',
+    awake_deployment_revision_id INTEGER REFERENCES "deployment_revision"("id") ON DELETE SET NULL,
+    asleep_deployment_revision_id INTEGER REFERENCES "deployment_revision"("id") ON DELETE SET NULL,
+    next_sleep_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    next_wake_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    message TEXT NOT NULL DEFAULT '',
+    creator_id INTEGER NOT NULL REFERENCES "user"("id") ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX "uk_deploymentSchedule_deploymentId_name" ON "deployment_schedule" ("deployment_id", "name") WHERE deleted_at IS NULL;
+CREATE INDEX "idx_deploymentSchedule_nextSleepAt" ON "deployment_schedule" ("next_sleep_at") WHERE enabled;
+CREATE INDEX "idx_deploymentSchedule_nextWakeAt" ON "deployment_schedule" ("next_wake_at") WHERE enabled;
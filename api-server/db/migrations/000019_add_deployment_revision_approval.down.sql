@@ -0,0 +1,7 @@
+UPDATE "deployment_revision" SET "status" = 'inactive' WHERE "status" IN ('pending', 'rejected');
+
+ALTER TABLE "deployment_revision" DROP COLUMN IF EXISTS "review_comment";
+ALTER TABLE "deployment_revision" DROP COLUMN IF EXISTS "reviewed_at";
+ALTER TABLE "deployment_revision" DROP COLUMN IF EXISTS "reviewer_id";
+
+ALTER TABLE "cluster" DROP COLUMN IF EXISTS "approval_config";
@@ -0,0 +1,8 @@
+ALTER TYPE "deployment_revision_status" ADD VALUE IF NOT EXISTS 'pending';
+ALTER TYPE "deployment_revision_status" ADD VALUE IF NOT EXISTS 'rejected';
+
+ALTER TABLE "cluster" ADD COLUMN IF NOT EXISTS "approval_config" JSONB;
+
+ALTER TABLE "deployment_revision" ADD COLUMN IF NOT EXISTS "reviewer_id" INTEGER REFERENCES "user"("id") ON DELETE SET NULL;
+ALTER TABLE "deployment_revision" ADD COLUMN IF NOT EXISTS "reviewed_at" TIMESTAMP WITH TIME ZONE;
+ALTER TABLE "deployment_revision" ADD COLUMN IF NOT EXISTS "review_comment" TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE "yatai_component" DROP COLUMN IF EXISTS "health";
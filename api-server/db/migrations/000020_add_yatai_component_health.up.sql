@@ -0,0 +1 @@
+ALTER TABLE "yatai_component" ADD COLUMN IF NOT EXISTS "health" JSONB;
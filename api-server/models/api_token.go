@@ -11,10 +11,24 @@ type ApiToken struct {
 	OrganizationAssociate
 	UserAssociate
 	Description string                       `json:"description"`
-	Token       string                       `json:"token"`
+	TokenPrefix string                       `json:"token_prefix"`
+	TokenSalt   string                       `json:"-"`
+	TokenHash   string                       `json:"-"`
 	Scopes      *modelschemas.ApiTokenScopes `json:"scopes"`
 	ExpiredAt   *time.Time                   `json:"expired_at"`
 	LastUsedAt  *time.Time                   `json:"last_used_at"`
+
+	// the secret replaced by the latest rotation, it stays valid until PreviousTokenExpiredAt
+	PreviousTokenPrefix    *string    `json:"previous_token_prefix"`
+	PreviousTokenSalt      *string    `json:"-"`
+	PreviousTokenHash      *string    `json:"-"`
+	PreviousTokenExpiredAt *time.Time `json:"previous_token_expired_at"`
+	RotatedAt              *time.Time `json:"rotated_at"`
+
+	// Token is the plaintext secret, it is only known right after the creation and is never persisted
+	Token string `gorm:"-" json:"-"`
+	// IsPreviousTokenUsed is set when the api token was looked up by the secret replaced by the latest rotation
+	IsPreviousTokenUsed bool `gorm:"-" json:"-"`
 }
 
 func (a *ApiToken) GetResourceType() modelschemas.ResourceType {
@@ -6,7 +6,8 @@ type BentoRepository struct {
 	ResourceMixin
 	CreatorAssociate
 	OrganizationAssociate
-	Description string `json:"description"`
+	Description     string           `json:"description"`
+	RetentionPolicy *RetentionPolicy `json:"retention_policy"`
 }
 
 func (b *BentoRepository) GetResourceType() modelschemas.ResourceType {
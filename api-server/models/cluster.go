@@ -1,6 +1,33 @@
 package models
 
-import "github.com/bentoml/yatai-schemas/modelschemas"
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+// ClusterApprovalConfig protects the deployments of the cluster, the revisions submitted by the members
+// without the approver role stay pending until another member with the role approves them
+type ClusterApprovalConfig struct {
+	Enabled bool `json:"enabled"`
+	// ApproverRole is the lowest member role allowed to approve, it defaults to admin
+	ApproverRole modelschemas.MemberRole `json:"approver_role,omitempty"`
+}
+
+func (c *ClusterApprovalConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), c)
+}
+
+func (c *ClusterApprovalConfig) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
 
 type Cluster struct {
 	ResourceMixin
@@ -10,6 +37,8 @@ type Cluster struct {
 	Description string                            `json:"description"`
 	KubeConfig  string                            `json:"kube_config"`
 	Config      *modelschemas.ClusterConfigSchema `json:"config"`
+	// ApprovalConfig is kept beside Config because the cluster config schema is shared with the other yatai components
+	ApprovalConfig *ClusterApprovalConfig `json:"approval_config"`
 }
 
 func (c *Cluster) GetResourceType() modelschemas.ResourceType {
@@ -2,9 +2,13 @@ package models
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
 )
 
 type DeployOption struct {
 	Force           bool
 	OwnerReferences []metav1.OwnerReference
+	// AsleepTargetTypes are the types of the targets scaled to zero by the replaced revisions, they are woken up on deploy
+	AsleepTargetTypes map[modelschemas.DeploymentTargetType]bool
 }
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/bentoml/yatai-schemas/modelschemas"
+
+type DeploymentMember struct {
+	BaseModel
+	CreatorAssociate
+	UserAssociate
+	DeploymentAssociate
+
+	Role modelschemas.MemberRole `json:"role"`
+	// UserGroupId is set instead of the UserId when the role is granted to every user of the user group
+	UserGroupId *uint `json:"user_group_id"`
+}
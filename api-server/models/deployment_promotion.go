@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+// ResourceTypeDeploymentPromotion is only used in the schemas, the promotions have no labels nor members
+const ResourceTypeDeploymentPromotion modelschemas.ResourceType = "deployment_promotion"
+
+type DeploymentPromotionRunnerOverrides struct {
+	Resources *modelschemas.DeploymentTargetResources `json:"resources,omitempty"`
+	HPAConf   *modelschemas.DeploymentTargetHPAConf   `json:"hpa_conf,omitempty"`
+	Envs      *[]*modelschemas.LabelItemSchema        `json:"envs,omitempty"`
+}
+
+// DeploymentPromotionOverrides are applied to the configs of all the targets of the promoted revision,
+// the set fields of HPAConf replace the source ones and the Envs are merged by key
+type DeploymentPromotionOverrides struct {
+	Resources *modelschemas.DeploymentTargetResources       `json:"resources,omitempty"`
+	HPAConf   *modelschemas.DeploymentTargetHPAConf         `json:"hpa_conf,omitempty"`
+	Envs      *[]*modelschemas.LabelItemSchema              `json:"envs,omitempty"`
+	Runners   map[string]DeploymentPromotionRunnerOverrides `json:"runners,omitempty"`
+}
+
+func (o *DeploymentPromotionOverrides) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), o)
+}
+
+func (o *DeploymentPromotionOverrides) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	return json.Marshal(o)
+}
+
+// DeploymentPromotion links the revision of a deployment to the revision it was promoted to,
+// the target deployment may be in another cluster of the organization
+type DeploymentPromotion struct {
+	BaseModel
+	CreatorAssociate
+
+	SourceDeploymentId         uint                          `json:"source_deployment_id"`
+	SourceDeploymentRevisionId uint                          `json:"source_deployment_revision_id"`
+	TargetDeploymentId         uint                          `json:"target_deployment_id"`
+	TargetDeploymentRevisionId uint                          `json:"target_deployment_revision_id"`
+	Overrides                  *DeploymentPromotionOverrides `json:"overrides"`
+}
+
+func (s *DeploymentPromotion) GetResourceType() modelschemas.ResourceType {
+	return ResourceTypeDeploymentPromotion
+}
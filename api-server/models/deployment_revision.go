@@ -1,6 +1,17 @@
 package models
 
-import "github.com/bentoml/yatai-schemas/modelschemas"
+import (
+	"time"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+const (
+	// DeploymentRevisionStatusPending is the status of a revision waiting for an approval, it is not deployed yet
+	DeploymentRevisionStatusPending modelschemas.DeploymentRevisionStatus = "pending"
+	// DeploymentRevisionStatusRejected is the status of a pending revision which was rejected
+	DeploymentRevisionStatusRejected modelschemas.DeploymentRevisionStatus = "rejected"
+)
 
 type DeploymentRevision struct {
 	BaseModel
@@ -8,6 +19,12 @@ type DeploymentRevision struct {
 	DeploymentAssociate
 
 	Status modelschemas.DeploymentRevisionStatus `json:"status"`
+	// RollbackFromRevisionId is the revision whose targets were cloned when the revision was created by a rollback
+	RollbackFromRevisionId *uint `json:"rollback_from_revision_id"`
+	// ReviewerId is the member who approved or rejected the revision when it was pending
+	ReviewerId    *uint      `json:"reviewer_id"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewComment string     `json:"review_comment"`
 }
 
 func (s *DeploymentRevision) GetName() string {
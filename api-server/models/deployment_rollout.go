@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+// ResourceTypeDeploymentRollout is only used to subscribe to the rollouts, the rollouts have no labels nor members
+const ResourceTypeDeploymentRollout modelschemas.ResourceType = "deployment_rollout"
+
+type DeploymentRolloutStatus string
+
+const (
+	DeploymentRolloutStatusRunning    DeploymentRolloutStatus = "running"
+	DeploymentRolloutStatusPromoted   DeploymentRolloutStatus = "promoted"
+	DeploymentRolloutStatusRolledBack DeploymentRolloutStatus = "rolled_back"
+	DeploymentRolloutStatusAborted    DeploymentRolloutStatus = "aborted"
+)
+
+func DeploymentRolloutStatusPtr(status DeploymentRolloutStatus) *DeploymentRolloutStatus {
+	return &status
+}
+
+type DeploymentRolloutStep struct {
+	// Weight is the percentage of the traffic routed to the canary
+	Weight uint `json:"weight"`
+	// PauseSeconds is how long the canary is observed at the weight before the next step
+	PauseSeconds uint `json:"pause_seconds"`
+}
+
+type DeploymentRolloutSteps []*DeploymentRolloutStep
+
+func (s *DeploymentRolloutSteps) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), s)
+}
+
+func (s DeploymentRolloutSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+type DeploymentRollout struct {
+	BaseModel
+	CreatorAssociate
+	DeploymentAssociate
+	DeploymentRevisionAssociate
+
+	CanaryDeploymentTargetId uint                    `json:"canary_deployment_target_id"`
+	Steps                    DeploymentRolloutSteps  `json:"steps"`
+	CurrentStep              int                     `json:"current_step"`
+	Status                   DeploymentRolloutStatus `json:"status"`
+	Message                  string                  `json:"message"`
+	StepStartedAt            *time.Time              `json:"step_started_at"`
+	NextStepAt               *time.Time              `json:"next_step_at"`
+	FinishedAt               *time.Time              `json:"finished_at"`
+}
+
+func (r *DeploymentRollout) GetResourceType() modelschemas.ResourceType {
+	return ResourceTypeDeploymentRollout
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+// ResourceTypeDeploymentSchedule is only used in the schemas, the schedules have no labels nor members
+const ResourceTypeDeploymentSchedule modelschemas.ResourceType = "deployment_schedule"
+
+type DeploymentScheduleState string
+
+const (
+	DeploymentScheduleStateAwake  DeploymentScheduleState = "awake"
+	DeploymentScheduleStateAsleep DeploymentScheduleState = "asleep"
+)
+
+type DeploymentSchedule struct {
+	ResourceMixin
+	CreatorAssociate
+	DeploymentAssociate
+
+	// SleepCron and WakeCron are standard 5 fields cron specs evaluated in the Timezone
+	SleepCron string                  `json:"sleep_cron"`
+	WakeCron  string                  `json:"wake_cron"`
+	Timezone  string                  `json:"timezone"`
+	Enabled   bool                    `json:"enabled"`
+	State     DeploymentScheduleState `json:"state"`
+	// AwakeDeploymentRevisionId is the revision restored on wake up, AsleepDeploymentRevisionId the scaled to zero one which replaced it
+	AwakeDeploymentRevisionId  *uint      `json:"awake_deployment_revision_id"`
+	AsleepDeploymentRevisionId *uint      `json:"asleep_deployment_revision_id"`
+	NextSleepAt                time.Time  `json:"next_sleep_at"`
+	NextWakeAt                 time.Time  `json:"next_wake_at"`
+	LastRunAt                  *time.Time `json:"last_run_at"`
+	Message                    string     `json:"message"`
+}
+
+func (s *DeploymentSchedule) GetResourceType() modelschemas.ResourceType {
+	return ResourceTypeDeploymentSchedule
+}
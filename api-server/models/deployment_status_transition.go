@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+// ResourceTypeDeploymentStatusTransition is subscribed to with the uids of the deployments, the new transitions of them are pushed
+const ResourceTypeDeploymentStatusTransition modelschemas.ResourceType = "deployment_status_transition"
+
+type DeploymentStatusEvidencePod struct {
+	Name         string                           `json:"name"`
+	Status       modelschemas.KubePodActualStatus `json:"status"`
+	RestartCount int32                            `json:"restart_count"`
+}
+
+type DeploymentStatusEvidenceEvent struct {
+	InvolvedObject string     `json:"involved_object"`
+	Reason         string     `json:"reason"`
+	Message        string     `json:"message"`
+	Count          int32      `json:"count"`
+	LastTimestamp  *time.Time `json:"last_timestamp"`
+}
+
+// DeploymentStatusEvidence is what the status was computed from
+type DeploymentStatusEvidence struct {
+	Pods     []*DeploymentStatusEvidencePod   `json:"pods"`
+	Warnings []*DeploymentStatusEvidenceEvent `json:"warnings"`
+}
+
+func (e *DeploymentStatusEvidence) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), e)
+}
+
+func (e *DeploymentStatusEvidence) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+type DeploymentStatusTransition struct {
+	BaseModel
+	DeploymentAssociate
+
+	FromStatus modelschemas.DeploymentStatus `json:"from_status"`
+	ToStatus   modelschemas.DeploymentStatus `json:"to_status"`
+	Evidence   *DeploymentStatusEvidence     `json:"evidence"`
+}
+
+func (t *DeploymentStatusTransition) GetResourceType() modelschemas.ResourceType {
+	return ResourceTypeDeploymentStatusTransition
+}
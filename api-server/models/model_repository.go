@@ -6,7 +6,8 @@ type ModelRepository struct {
 	ResourceMixin
 	CreatorAssociate
 	OrganizationAssociate
-	Description string `json:"description"`
+	Description     string           `json:"description"`
+	RetentionPolicy *RetentionPolicy `json:"retention_policy"`
 }
 
 func (b *ModelRepository) GetResourceType() modelschemas.ResourceType {
@@ -13,4 +13,8 @@ type OrganizationMember struct {
 	OrganizationAssociate
 
 	Role modelschemas.MemberRole `json:"role"`
+	// UserGroupId is set instead of the UserId when the role is granted to every user of the user group
+	UserGroupId *uint `json:"user_group_id"`
+	// IdentityProvider is set when the membership is granted by the group role mappings of that identity provider
+	IdentityProvider *string `json:"identity_provider"`
 }
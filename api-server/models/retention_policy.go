@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+type RetentionPolicy struct {
+	KeepLastN      *uint `json:"keep_last_n,omitempty"`
+	KeepWithinDays *uint `json:"keep_within_days,omitempty"`
+}
+
+// IsEmpty reports whether the policy has no rule, in which case nothing is collected
+func (p *RetentionPolicy) IsEmpty() bool {
+	return p == nil || (p.KeepLastN == nil && p.KeepWithinDays == nil)
+}
+
+func (p *RetentionPolicy) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), p)
+}
+
+func (p *RetentionPolicy) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
@@ -1,7 +1,15 @@
 package models
 
+import "github.com/bentoml/yatai-schemas/modelschemas"
+
+const ResourceTypeUserGroup modelschemas.ResourceType = "user_group"
+
 type UserGroup struct {
 	ResourceMixin
 	OrganizationAssociate
 	CreatorAssociate
 }
+
+func (g *UserGroup) GetResourceType() modelschemas.ResourceType {
+	return ResourceTypeUserGroup
+}
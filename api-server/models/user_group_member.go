@@ -0,0 +1,15 @@
+package models
+
+import "github.com/bentoml/yatai-schemas/modelschemas"
+
+// UserGroupMember is a role granted on a resource to every user of the user group,
+// it has no table of its own, it is a row of the member table of the resource with the user_group_id set
+type UserGroupMember struct {
+	BaseModel
+	CreatorAssociate
+	UserGroupAssociate
+
+	ResourceType modelschemas.ResourceType `json:"resource_type" gorm:"-"`
+	ResourceId   uint                      `json:"resource_id"`
+	Role         modelschemas.MemberRole   `json:"role"`
+}
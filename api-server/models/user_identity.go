@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to the subject of an external identity provider
+type UserIdentity struct {
+	BaseModel
+	UserAssociate
+	Provider      string     `json:"provider"`
+	Subject       string     `json:"subject"`
+	LatestLoginAt *time.Time `json:"latest_login_at"`
+}
@@ -1,11 +1,46 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"github.com/bentoml/yatai-schemas/modelschemas"
 )
 
+type YataiComponentHealthStatus string
+
+const (
+	YataiComponentHealthStatusHealthy   YataiComponentHealthStatus = "healthy"
+	YataiComponentHealthStatusUnhealthy YataiComponentHealthStatus = "unhealthy"
+)
+
+// YataiComponentHealth is evaluated by the api server from the heartbeats and the version of the component
+type YataiComponentHealth struct {
+	Status YataiComponentHealthStatus `json:"status"`
+	// Stale tells that no heartbeat arrived within the heartbeat window
+	Stale bool `json:"stale"`
+	// VersionSkew tells that the component version is too old for the CRD version the deployments are deployed with
+	VersionSkew        bool      `json:"version_skew"`
+	ExpectedCRDVersion string    `json:"expected_crd_version,omitempty"`
+	Reasons            []string  `json:"reasons,omitempty"`
+	CheckedAt          time.Time `json:"checked_at"`
+}
+
+func (c *YataiComponentHealth) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.(string)), c)
+}
+
+func (c *YataiComponentHealth) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
 type YataiComponent struct {
 	ResourceMixin
 	CreatorAssociate
@@ -18,6 +53,8 @@ type YataiComponent struct {
 	Manifest          *modelschemas.YataiComponentManifestSchema `json:"manifest" type:"jsonb"`
 	LatestInstalledAt *time.Time                                 `json:"latest_installed_at"`
 	LatestHeartbeatAt *time.Time                                 `json:"latest_heartbeat_at"`
+	// Health is null until the component is checked
+	Health *YataiComponentHealth `json:"health" type:"jsonb"`
 }
 
 func (d *YataiComponent) GetResourceType() modelschemas.ResourceType {
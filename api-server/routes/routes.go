@@ -14,6 +14,8 @@ import (
 	"github.com/huandu/xstrings"
 	"github.com/loopfz/gadgeto/tonic"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 	"github.com/wI2L/fizz"
 	"github.com/wI2L/fizz/openapi"
 	"gorm.io/gorm"
@@ -25,6 +27,7 @@ import (
 	"github.com/bentoml/yatai/api-server/controllers/web"
 	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/api-server/services"
+	commonconsts "github.com/bentoml/yatai/common/consts"
 	"github.com/bentoml/yatai/common/scookie"
 	"github.com/bentoml/yatai/common/utils"
 	"github.com/bentoml/yatai/common/yataicontext"
@@ -70,15 +73,25 @@ func errorHook(c *gin.Context, e error) (status int, resp interface{}) {
 	resp = gin.H{"error": e.Error()}
 	cause := errors.Cause(e)
 
-	if errors.Is(cause, gorm.ErrRecordNotFound) {
+	switch {
+	case errors.Is(cause, gorm.ErrRecordNotFound):
 		status = http.StatusNotFound
-	} else {
+	case errors.Is(cause, commonconsts.ErrConflict):
+		status = http.StatusConflict
+	default:
 		status = http.StatusBadRequest
 	}
 
 	return
 }
 
+// NewMetricsRouter serves the prometheus metrics, it is served on its own listener which is not exposed publicly
+func NewMetricsRouter() *gin.Engine {
+	engine := gin.New()
+	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	return engine
+}
+
 func NewRouter() (*fizz.Fizz, error) {
 	tonic.SetRenderHook(func(c *gin.Context, statusCode int, payload interface{}) {
 		if _, exists := c.Get(WebsocketConnectContextKey); exists {
@@ -115,6 +128,10 @@ func NewRouter() (*fizz.Fizz, error) {
 			MaxAge: int(time.Hour * 24 * 30),
 		})
 	}
+	// the probes are registered before the middlewares, they must not depend on the session or the organization
+	engine.GET("/healthz", web.Healthz)
+	engine.GET("/readyz", web.Readyz)
+
 	engine.Use(injectCurrentOrganization)
 	engine.Use(sessions.Sessions("yatai-session-v2", store))
 
@@ -216,6 +233,12 @@ func NewRouter() (*fizz.Fizz, error) {
 
 	deploymentGroup.POST("/pods/:podName/containers/:containerName/upload_file", controllersv1.DeploymentController.UploadFileToPod)
 	deploymentGroup.GET("/pods/:podName/containers/:containerName/download_file", controllersv1.DeploymentController.DownloadFileFromPod)
+	deploymentGroup.GET("/manifest.yaml", controllersv1.DeploymentManifestController.ExportYAML)
+
+	identityProviderGroup := engine.Group("/api/v1/auth/identity_providers/:identityProviderName")
+
+	identityProviderGroup.GET("/login", controllersv1.AuthController.IdentityProviderLogin)
+	identityProviderGroup.GET("/callback", controllersv1.AuthController.IdentityProviderCallback)
 
 	publicApiRootGroup := fizzApp.Group("/api/v1", "api v1", "api v1")
 	apiRootGroup := fizzApp.Group("/api/v1", "api v1", "api v1")
@@ -225,12 +248,14 @@ func NewRouter() (*fizz.Fizz, error) {
 	authRoutes(publicApiRootGroup)
 	userRoutes(apiRootGroup)
 	organizationRoutes(apiRootGroup)
+	userGroupRoutes(apiRootGroup)
 	apiTokenRoutes(apiRootGroup)
 	labelRoutes(apiRootGroup)
 	clusterRoutes(apiRootGroup)
 	bentoRepositoryRoutes(apiRootGroup)
 	modelRepositoryRoutes(apiRootGroup)
 	terminalRecordRoutes(apiRootGroup)
+	deploymentManifestRoutes(apiRootGroup)
 
 	publicApiRootGroup.GET("/version", []fizz.OperationOption{
 		fizz.ID("Get version"),
@@ -318,6 +343,12 @@ func getLoginUser(ctx *gin.Context) (user *models.User, err error) {
 			err = errors.Wrap(err, "get user by api token")
 			return
 		}
+		if apiToken.IsPreviousTokenUsed {
+			caller := fmt.Sprintf("%s %s", ctx.ClientIP(), ctx.Request.UserAgent())
+			if err_ := services.ApiTokenService.RecordPreviousTokenUsage(ctx, apiToken, caller); err_ != nil {
+				logrus.Errorf("record previous secret usage of api token %s: %s", apiToken.Uid, err_.Error())
+			}
+		}
 		now := time.Now()
 		now_ := &now
 		apiToken, err = services.ApiTokenService.Update(ctx, apiToken, services.UpdateApiTokenOption{
@@ -395,6 +426,11 @@ func authRoutes(publicGrp *fizz.RouterGroup) {
 		fizz.Summary("Login an user"),
 	}, tonic.Handler(controllersv1.AuthController.Login, 200))
 
+	publicGrp.GET("/identity_providers", []fizz.OperationOption{
+		fizz.ID("List identity providers"),
+		fizz.Summary("List identity providers"),
+	}, tonic.Handler(controllersv1.AuthController.ListIdentityProviders, 200))
+
 	grp.GET("/current", []fizz.OperationOption{
 		fizz.ID("Get current user"),
 		fizz.Summary("Get current user"),
@@ -480,6 +516,21 @@ func organizationRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Remove an organization member"),
 	}, tonic.Handler(controllersv1.OrganizationMemberController.Delete, 200))
 
+	grp.GET("/user_group_members", []fizz.OperationOption{
+		fizz.ID("List organization user group members"),
+		fizz.Summary("List organization user group members"),
+	}, tonic.Handler(controllersv1.OrganizationMemberController.ListUserGroupMembers, 200))
+
+	grp.POST("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Create organization user group members"),
+		fizz.Summary("Create organization user group members"),
+	}, tonic.Handler(controllersv1.OrganizationMemberController.CreateUserGroupMembers, 200))
+
+	grp.DELETE("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Remove an organization user group member"),
+		fizz.Summary("Remove an organization user group member"),
+	}, tonic.Handler(controllersv1.OrganizationMemberController.DeleteUserGroupMember, 200))
+
 	grp.GET("/deployments", []fizz.OperationOption{
 		fizz.ID("List organization deployments"),
 		fizz.Summary("List organization deployments"),
@@ -505,6 +556,52 @@ func organizationRoutes(grp *fizz.RouterGroup) {
 	// modelRepositoryRoutes(resourceGrp)
 }
 
+func userGroupRoutes(grp *fizz.RouterGroup) {
+	grp = grp.Group("/user_groups", "user groups", "user groups api")
+
+	resourceGrp := grp.Group("/:userGroupName", "user group resource", "user group resource")
+
+	resourceGrp.GET("", []fizz.OperationOption{
+		fizz.ID("Get an user group"),
+		fizz.Summary("Get an user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.Get, 200))
+
+	resourceGrp.PATCH("", []fizz.OperationOption{
+		fizz.ID("Update an user group"),
+		fizz.Summary("Update an user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.Update, 200))
+
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete an user group"),
+		fizz.Summary("Delete an user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.Delete, 200))
+
+	resourceGrp.GET("/users", []fizz.OperationOption{
+		fizz.ID("List user group users"),
+		fizz.Summary("List user group users"),
+	}, tonic.Handler(controllersv1.UserGroupController.ListUsers, 200))
+
+	resourceGrp.POST("/users", []fizz.OperationOption{
+		fizz.ID("Add users to an user group"),
+		fizz.Summary("Add users to an user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.AddUsers, 200))
+
+	resourceGrp.DELETE("/users", []fizz.OperationOption{
+		fizz.ID("Remove users from an user group"),
+		fizz.Summary("Remove users from an user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.RemoveUsers, 200))
+
+	grp.GET("", []fizz.OperationOption{
+		fizz.ID("List user groups"),
+		fizz.Summary("List user groups"),
+	}, tonic.Handler(controllersv1.UserGroupController.List, 200))
+
+	grp.POST("", []fizz.OperationOption{
+		fizz.ID("Create user group"),
+		fizz.Summary("Create user group"),
+	}, tonic.Handler(controllersv1.UserGroupController.Create, 200))
+}
+
 func apiTokenRoutes(grp *fizz.RouterGroup) {
 	grp = grp.Group("/api_tokens", "api tokens", "api tokens")
 
@@ -520,6 +617,11 @@ func apiTokenRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a api token"),
 	}, tonic.Handler(controllersv1.ApiTokenController.Update, 200))
 
+	resourceGrp.POST("/rotate", []fizz.OperationOption{
+		fizz.ID("Rotate a api token"),
+		fizz.Summary("Rotate a api token"),
+	}, tonic.Handler(controllersv1.ApiTokenController.Rotate, 200))
+
 	resourceGrp.DELETE("", []fizz.OperationOption{
 		fizz.ID("Delete a api token"),
 		fizz.Summary("Delete a api token"),
@@ -574,6 +676,21 @@ func clusterRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Remove a cluster member"),
 	}, tonic.Handler(controllersv1.ClusterMemberController.Delete, 200))
 
+	resourceGrp.GET("/user_group_members", []fizz.OperationOption{
+		fizz.ID("List cluster user group members"),
+		fizz.Summary("List cluster user group members"),
+	}, tonic.Handler(controllersv1.ClusterMemberController.ListUserGroupMembers, 200))
+
+	resourceGrp.POST("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Create cluster user group members"),
+		fizz.Summary("Create cluster user group members"),
+	}, tonic.Handler(controllersv1.ClusterMemberController.CreateUserGroupMembers, 200))
+
+	resourceGrp.DELETE("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Remove a cluster user group member"),
+		fizz.Summary("Remove a cluster user group member"),
+	}, tonic.Handler(controllersv1.ClusterMemberController.DeleteUserGroupMember, 200))
+
 	grp.GET("", []fizz.OperationOption{
 		fizz.ID("List clusters"),
 		fizz.Summary("List clusters"),
@@ -584,6 +701,11 @@ func clusterRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Create cluster"),
 	}, tonic.Handler(controllersv1.ClusterController.Create, 200))
 
+	grp.POST("/preflight", []fizz.OperationOption{
+		fizz.ID("Preflight cluster"),
+		fizz.Summary("Check a kube config before creating a cluster"),
+	}, tonic.Handler(controllersv1.ClusterController.Preflight, 200))
+
 	yataiComponentRoutes(resourceGrp)
 	deploymentRoutes(resourceGrp)
 }
@@ -603,6 +725,26 @@ func bentoRepositoryRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a bento repository"),
 	}, tonic.Handler(controllersv1.BentoRepositoryController.Update, 200))
 
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete a bento repository"),
+		fizz.Summary("Delete a bento repository"),
+	}, tonic.Handler(controllersv1.BentoRepositoryController.Delete, 200))
+
+	resourceGrp.GET("/retention_policy", []fizz.OperationOption{
+		fizz.ID("Get a bento repository retention policy"),
+		fizz.Summary("Get a bento repository retention policy"),
+	}, tonic.Handler(controllersv1.BentoRepositoryController.GetRetentionPolicy, 200))
+
+	resourceGrp.PUT("/retention_policy", []fizz.OperationOption{
+		fizz.ID("Update a bento repository retention policy"),
+		fizz.Summary("Update a bento repository retention policy"),
+	}, tonic.Handler(controllersv1.BentoRepositoryController.UpdateRetentionPolicy, 200))
+
+	resourceGrp.GET("/retention_policy/dry_run", []fizz.OperationOption{
+		fizz.ID("List bentos collectable by a bento repository retention policy"),
+		fizz.Summary("List bentos collectable by a bento repository retention policy"),
+	}, tonic.Handler(controllersv1.BentoRepositoryController.DryRunRetentionPolicy, 200))
+
 	resourceGrp.GET("/deployments", []fizz.OperationOption{
 		fizz.ID("List bento repository deployments"),
 		fizz.Summary("List bento repository deployments"),
@@ -636,6 +778,11 @@ func bentoRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a bento"),
 	}, tonic.Handler(controllersv1.BentoController.Update, 200))
 
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete a bento"),
+		fizz.Summary("Delete a bento"),
+	}, tonic.Handler(controllersv1.BentoController.Delete, 200))
+
 	resourceGrp.PATCH("/update_image_build_status_syncing_at", []fizz.OperationOption{
 		fizz.ID("Update a bento image build status syncing_at"),
 		fizz.Summary("Update a bento image build status syncing_at"),
@@ -732,6 +879,11 @@ func deploymentRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a deployment"),
 	}, tonic.Handler(controllersv1.DeploymentController.Update, 200))
 
+	resourceGrp.POST("/dry_run", []fizz.OperationOption{
+		fizz.ID("Dry run a deployment update"),
+		fizz.Summary("Dry run a deployment update"),
+	}, tonic.Handler(controllersv1.DeploymentController.DryRunUpdate, 200))
+
 	resourceGrp.POST("/sync_status", []fizz.OperationOption{
 		fizz.ID("Sync a deployment status"),
 		fizz.Summary("Sync a deployment status"),
@@ -747,11 +899,56 @@ func deploymentRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Delete a deployment"),
 	}, tonic.Handler(controllersv1.DeploymentController.Delete, 200))
 
+	resourceGrp.GET("/status_transitions", []fizz.OperationOption{
+		fizz.ID("List deployment status transitions"),
+		fizz.Summary("List deployment status transitions"),
+	}, tonic.Handler(controllersv1.DeploymentStatusTransitionController.List, 200))
+
+	resourceGrp.GET("/promotions", []fizz.OperationOption{
+		fizz.ID("List deployment promotions"),
+		fizz.Summary("List the promotions from and to the deployment"),
+	}, tonic.Handler(controllersv1.DeploymentPromotionController.List, 200))
+
+	resourceGrp.GET("/manifest", []fizz.OperationOption{
+		fizz.ID("Export a deployment manifest"),
+		fizz.Summary("Export the active revision of a deployment as a manifest"),
+	}, tonic.Handler(controllersv1.DeploymentManifestController.Export, 200))
+
 	resourceGrp.GET("/terminal_records", []fizz.OperationOption{
 		fizz.ID("List deployment terminal records"),
 		fizz.Summary("List deployment terminal records"),
 	}, tonic.Handler(controllersv1.DeploymentController.ListTerminalRecords, 200))
 
+	resourceGrp.GET("/members", []fizz.OperationOption{
+		fizz.ID("List deployment members"),
+		fizz.Summary("List deployment members"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.List, 200))
+
+	resourceGrp.POST("/members", []fizz.OperationOption{
+		fizz.ID("Create deployment members"),
+		fizz.Summary("Create deployment members"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.Create, 200))
+
+	resourceGrp.DELETE("/members", []fizz.OperationOption{
+		fizz.ID("Remove a deployment member"),
+		fizz.Summary("Remove a deployment member"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.Delete, 200))
+
+	resourceGrp.GET("/user_group_members", []fizz.OperationOption{
+		fizz.ID("List deployment user group members"),
+		fizz.Summary("List deployment user group members"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.ListUserGroupMembers, 200))
+
+	resourceGrp.POST("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Create deployment user group members"),
+		fizz.Summary("Create deployment user group members"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.CreateUserGroupMembers, 200))
+
+	resourceGrp.DELETE("/user_group_members", []fizz.OperationOption{
+		fizz.ID("Remove a deployment user group member"),
+		fizz.Summary("Remove a deployment user group member"),
+	}, tonic.Handler(controllersv1.DeploymentMemberController.DeleteUserGroupMember, 200))
+
 	grp.GET("", []fizz.OperationOption{
 		fizz.ID("List cluster deployments"),
 		fizz.Summary("List cluster deployments"),
@@ -762,7 +959,85 @@ func deploymentRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Create deployment"),
 	}, tonic.Handler(controllersv1.DeploymentController.Create, 200))
 
+	grp.POST("/dry_run", []fizz.OperationOption{
+		fizz.ID("Dry run a deployment creation"),
+		fizz.Summary("Dry run a deployment creation"),
+	}, tonic.Handler(controllersv1.DeploymentController.DryRunCreate, 200))
+
 	deploymentRevisionRoutes(resourceGrp)
+	deploymentRolloutRoutes(resourceGrp)
+	deploymentScheduleRoutes(resourceGrp)
+}
+
+func deploymentRolloutRoutes(grp *fizz.RouterGroup) {
+	grp = grp.Group("/rollouts", "deployment rollouts", "deployment rollouts")
+
+	resourceGrp := grp.Group("/:rolloutUid", "deployment rollout resource", "deployment rollout resource")
+
+	resourceGrp.GET("", []fizz.OperationOption{
+		fizz.ID("Get a deployment rollout"),
+		fizz.Summary("Get a deployment rollout"),
+	}, tonic.Handler(controllersv1.DeploymentRolloutController.Get, 200))
+
+	resourceGrp.POST("/promote", []fizz.OperationOption{
+		fizz.ID("Promote a deployment rollout"),
+		fizz.Summary("Promote the canary of a deployment rollout"),
+	}, tonic.Handler(controllersv1.DeploymentRolloutController.Promote, 200))
+
+	resourceGrp.POST("/abort", []fizz.OperationOption{
+		fizz.ID("Abort a deployment rollout"),
+		fizz.Summary("Abort a deployment rollout and remove its canary"),
+	}, tonic.Handler(controllersv1.DeploymentRolloutController.Abort, 200))
+
+	grp.GET("", []fizz.OperationOption{
+		fizz.ID("List deployment rollouts"),
+		fizz.Summary("List deployment rollouts"),
+	}, tonic.Handler(controllersv1.DeploymentRolloutController.List, 200))
+
+	grp.POST("", []fizz.OperationOption{
+		fizz.ID("Create a deployment rollout"),
+		fizz.Summary("Start a canary rollout of the active deployment revision"),
+	}, tonic.Handler(controllersv1.DeploymentRolloutController.Create, 200))
+}
+
+func deploymentManifestRoutes(grp *fizz.RouterGroup) {
+	grp = grp.Group("/deployment_manifests", "deployment manifests", "deployment manifests")
+
+	grp.POST("/apply", []fizz.OperationOption{
+		fizz.ID("Apply a deployment manifest"),
+		fizz.Summary("Create or update the deployment of a manifest"),
+	}, tonic.Handler(controllersv1.DeploymentManifestController.Apply, 200))
+}
+
+func deploymentScheduleRoutes(grp *fizz.RouterGroup) {
+	grp = grp.Group("/schedules", "deployment schedules", "deployment schedules")
+
+	resourceGrp := grp.Group("/:scheduleUid", "deployment schedule resource", "deployment schedule resource")
+
+	resourceGrp.GET("", []fizz.OperationOption{
+		fizz.ID("Get a deployment schedule"),
+		fizz.Summary("Get a deployment schedule"),
+	}, tonic.Handler(controllersv1.DeploymentScheduleController.Get, 200))
+
+	resourceGrp.PATCH("", []fizz.OperationOption{
+		fizz.ID("Update a deployment schedule"),
+		fizz.Summary("Update a deployment schedule"),
+	}, tonic.Handler(controllersv1.DeploymentScheduleController.Update, 200))
+
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete a deployment schedule"),
+		fizz.Summary("Delete a deployment schedule"),
+	}, tonic.Handler(controllersv1.DeploymentScheduleController.Delete, 200))
+
+	grp.GET("", []fizz.OperationOption{
+		fizz.ID("List deployment schedules"),
+		fizz.Summary("List deployment schedules"),
+	}, tonic.Handler(controllersv1.DeploymentScheduleController.List, 200))
+
+	grp.POST("", []fizz.OperationOption{
+		fizz.ID("Create a deployment schedule"),
+		fizz.Summary("Create a schedule which scales the deployment to zero and wakes it up"),
+	}, tonic.Handler(controllersv1.DeploymentScheduleController.Create, 200))
 }
 
 func deploymentRevisionRoutes(grp *fizz.RouterGroup) {
@@ -775,6 +1050,31 @@ func deploymentRevisionRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Get a deployment revision"),
 	}, tonic.Handler(controllersv1.DeploymentRevisionController.Get, 200))
 
+	resourceGrp.POST("/rollback", []fizz.OperationOption{
+		fizz.ID("Rollback a deployment to the revision"),
+		fizz.Summary("Rollback a deployment to the revision"),
+	}, tonic.Handler(controllersv1.DeploymentRevisionController.Rollback, 200))
+
+	resourceGrp.POST("/approve", []fizz.OperationOption{
+		fizz.ID("Approve a deployment revision"),
+		fizz.Summary("Approve and deploy a pending deployment revision"),
+	}, tonic.Handler(controllersv1.DeploymentRevisionController.Approve, 200))
+
+	resourceGrp.POST("/reject", []fizz.OperationOption{
+		fizz.ID("Reject a deployment revision"),
+		fizz.Summary("Reject a pending deployment revision"),
+	}, tonic.Handler(controllersv1.DeploymentRevisionController.Reject, 200))
+
+	resourceGrp.GET("/diff", []fizz.OperationOption{
+		fizz.ID("Diff a deployment revision"),
+		fizz.Summary("Diff a deployment revision with another revision or the live deployment"),
+	}, tonic.Handler(controllersv1.DeploymentRevisionController.Diff, 200))
+
+	resourceGrp.POST("/promote", []fizz.OperationOption{
+		fizz.ID("Promote a deployment revision"),
+		fizz.Summary("Deploy the revision to a deployment of another cluster"),
+	}, tonic.Handler(controllersv1.DeploymentPromotionController.Promote, 200))
+
 	grp.GET("", []fizz.OperationOption{
 		fizz.ID("List deployment revisions"),
 		fizz.Summary("List deployment revisions"),
@@ -812,6 +1112,26 @@ func modelRepositoryRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a model repository"),
 	}, tonic.Handler(controllersv1.ModelRepositoryController.Update, 200))
 
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete a model repository"),
+		fizz.Summary("Delete a model repository"),
+	}, tonic.Handler(controllersv1.ModelRepositoryController.Delete, 200))
+
+	resourceGrp.GET("/retention_policy", []fizz.OperationOption{
+		fizz.ID("Get a model repository retention policy"),
+		fizz.Summary("Get a model repository retention policy"),
+	}, tonic.Handler(controllersv1.ModelRepositoryController.GetRetentionPolicy, 200))
+
+	resourceGrp.PUT("/retention_policy", []fizz.OperationOption{
+		fizz.ID("Update a model repository retention policy"),
+		fizz.Summary("Update a model repository retention policy"),
+	}, tonic.Handler(controllersv1.ModelRepositoryController.UpdateRetentionPolicy, 200))
+
+	resourceGrp.GET("/retention_policy/dry_run", []fizz.OperationOption{
+		fizz.ID("List models collectable by a model repository retention policy"),
+		fizz.Summary("List models collectable by a model repository retention policy"),
+	}, tonic.Handler(controllersv1.ModelRepositoryController.DryRunRetentionPolicy, 200))
+
 	grp.GET("", []fizz.OperationOption{
 		fizz.ID("List model repositories"),
 		fizz.Summary("List model repositories"),
@@ -840,6 +1160,11 @@ func modelRoutes(grp *fizz.RouterGroup) {
 		fizz.Summary("Update a model"),
 	}, tonic.Handler(controllersv1.ModelController.Update, 200))
 
+	resourceGrp.DELETE("", []fizz.OperationOption{
+		fizz.ID("Delete a model"),
+		fizz.Summary("Delete a model"),
+	}, tonic.Handler(controllersv1.ModelController.Delete, 200))
+
 	resourceGrp.GET("/bentos", []fizz.OperationOption{
 		fizz.ID("List model bentos"),
 		fizz.Summary("List model bentos"),
@@ -0,0 +1,30 @@
+// Package schemasv1 holds the v1 schemas of the resources which are not in github.com/bentoml/yatai-schemas yet
+package schemasv1
+
+import (
+	"time"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai-schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+type DeploymentRolloutSchema struct {
+	schemasv1.BaseSchema
+	ResourceType              modelschemas.ResourceType      `json:"resource_type"`
+	Creator                   *schemasv1.UserSchema          `json:"creator"`
+	DeploymentRevisionUid     string                         `json:"deployment_revision_uid"`
+	CanaryDeploymentTargetUid string                         `json:"canary_deployment_target_uid"`
+	Steps                     models.DeploymentRolloutSteps  `json:"steps"`
+	CurrentStep               int                            `json:"current_step"`
+	Status                    models.DeploymentRolloutStatus `json:"status"`
+	Message                   string                         `json:"message"`
+	StepStartedAt             *time.Time                     `json:"step_started_at"`
+	NextStepAt                *time.Time                     `json:"next_step_at"`
+	FinishedAt                *time.Time                     `json:"finished_at"`
+}
+
+type DeploymentRolloutListSchema struct {
+	schemasv1.BaseListSchema
+	Items []*DeploymentRolloutSchema `json:"items"`
+}
@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/rs/xid"
 	"gorm.io/gorm"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -42,6 +46,12 @@ type UpdateApiTokenOption struct {
 	LastUsedAt  **time.Time
 }
 
+const (
+	apiTokenSecretBytes  = 24
+	apiTokenSaltBytes    = 16
+	apiTokenPrefixLength = 8
+)
+
 type ListApiTokenOption struct {
 	BaseListOption
 	VisitorId      *uint
@@ -56,8 +66,14 @@ func (s *apiTokenService) Create(ctx context.Context, opt CreateApiTokenOption)
 		return nil, errors.New(strings.Join(errs, ";"))
 	}
 
-	guid := xid.New()
-	token := guid.String()
+	token, err := generateApiTokenSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate api token secret")
+	}
+	salt, err := generateApiTokenSalt()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate api token salt")
+	}
 
 	apiToken := models.ApiToken{
 		ResourceMixin: models.ResourceMixin{
@@ -70,17 +86,48 @@ func (s *apiTokenService) Create(ctx context.Context, opt CreateApiTokenOption)
 		OrganizationAssociate: models.OrganizationAssociate{
 			OrganizationId: opt.OrganizationId,
 		},
-		Token:     token,
-		Scopes:    opt.Scopes,
-		ExpiredAt: opt.ExpiredAt,
+		TokenPrefix: getApiTokenPrefix(token),
+		TokenSalt:   salt,
+		TokenHash:   hashApiToken(salt, token),
+		Scopes:      opt.Scopes,
+		ExpiredAt:   opt.ExpiredAt,
 	}
-	err := mustGetSession(ctx).Create(&apiToken).Error
+	err = mustGetSession(ctx).Create(&apiToken).Error
 	if err != nil {
 		return nil, err
 	}
+	apiToken.Token = token
 	return &apiToken, err
 }
 
+func generateApiTokenSecret() (string, error) {
+	b := make([]byte, apiTokenSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func generateApiTokenSalt() (string, error) {
+	b := make([]byte, apiTokenSaltBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func getApiTokenPrefix(token string) string {
+	if len(token) <= apiTokenPrefixLength {
+		return token
+	}
+	return token[:apiTokenPrefixLength]
+}
+
+func hashApiToken(salt, token string) string {
+	h := sha256.Sum256([]byte(salt + token))
+	return hex.EncodeToString(h[:])
+}
+
 func (s *apiTokenService) Update(ctx context.Context, c *models.ApiToken, opt UpdateApiTokenOption) (*models.ApiToken, error) {
 	var err error
 	updaters := make(map[string]interface{})
@@ -143,6 +190,9 @@ func (s *apiTokenService) Get(ctx context.Context, id uint) (*models.ApiToken, e
 
 func (s *apiTokenService) Delete(ctx context.Context, m *models.ApiToken) (*models.ApiToken, error) {
 	err := s.getBaseDB(ctx).Unscoped().Delete(m).Error
+	if err == nil {
+		forgetPreviousTokenUsages(m.ID)
+	}
 	return m, err
 }
 
@@ -207,7 +257,7 @@ func (s *apiTokenService) GetByToken(ctx context.Context, token string) (*models
 			err = errors.Errorf("failed to get api token in cluster %s in organization %s, the expected api token is empty!", clusterName, org.Name)
 			return nil, err
 		}
-		if token_ != expectedApiToken {
+		if subtle.ConstantTimeCompare([]byte(token_), []byte(expectedApiToken)) != 1 {
 			err = errors.Errorf("the api token is not valid in cluster %s in organization %s", clusterName, org.Name)
 			return nil, err
 		}
@@ -245,15 +295,163 @@ func (s *apiTokenService) GetByToken(ctx context.Context, token string) (*models
 		}
 		return apiToken, nil
 	}
-	var apiToken models.ApiToken
-	err := getBaseQuery(ctx, s).Where("token = ?", token).First(&apiToken).Error
+	return s.getByHashedToken(ctx, token)
+}
+
+// getByHashedToken narrows the candidates down by the visible prefix and then compares every candidate hash in constant time,
+// so the response time does not tell how much of the secret is right
+func (s *apiTokenService) getByHashedToken(ctx context.Context, token string) (*models.ApiToken, error) {
+	prefix := getApiTokenPrefix(token)
+	candidates := make([]*models.ApiToken, 0)
+	err := getBaseQuery(ctx, s).Where("token_prefix = ? OR (previous_token_prefix = ? AND previous_token_expired_at > ?)", prefix, prefix, time.Now()).Find(&candidates).Error
 	if err != nil {
 		return nil, err
 	}
-	if apiToken.ID == 0 {
-		return nil, consts.ErrNotFound
+	if len(candidates) == 0 {
+		// hash anyway to keep an unknown prefix as slow as a wrong secret
+		hashApiToken("", token)
+		return nil, gorm.ErrRecordNotFound
 	}
-	return &apiToken, nil
+	var apiToken *models.ApiToken
+	var previousApiToken *models.ApiToken
+	for _, candidate := range candidates {
+		if subtle.ConstantTimeCompare([]byte(hashApiToken(candidate.TokenSalt, token)), []byte(candidate.TokenHash)) == 1 && apiToken == nil {
+			apiToken = candidate
+		}
+		if candidate.PreviousTokenSalt == nil || candidate.PreviousTokenHash == nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hashApiToken(*candidate.PreviousTokenSalt, token)), []byte(*candidate.PreviousTokenHash)) == 1 && previousApiToken == nil {
+			previousApiToken = candidate
+		}
+	}
+	if apiToken != nil {
+		return apiToken, nil
+	}
+	if previousApiToken != nil && previousApiToken.PreviousTokenExpiredAt != nil && time.Now().Before(*previousApiToken.PreviousTokenExpiredAt) {
+		previousApiToken.IsPreviousTokenUsed = true
+		return previousApiToken, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Rotate issues a new secret for the api token, the replaced secret keeps working until the grace period is over
+func (s *apiTokenService) Rotate(ctx context.Context, apiToken *models.ApiToken, gracePeriod time.Duration) (*models.ApiToken, error) {
+	token, err := generateApiTokenSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate api token secret")
+	}
+	salt, err := generateApiTokenSalt()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate api token salt")
+	}
+	now := time.Now()
+	previousTokenExpiredAt := now.Add(gracePeriod)
+	updaters := map[string]interface{}{
+		"token_prefix":              getApiTokenPrefix(token),
+		"token_salt":                salt,
+		"token_hash":                hashApiToken(salt, token),
+		"previous_token_prefix":     apiToken.TokenPrefix,
+		"previous_token_salt":       apiToken.TokenSalt,
+		"previous_token_hash":       apiToken.TokenHash,
+		"previous_token_expired_at": previousTokenExpiredAt,
+		"rotated_at":                now,
+	}
+	err = s.getBaseDB(ctx).Where("id = ?", apiToken.ID).Updates(updaters).Error
+	if err != nil {
+		return nil, err
+	}
+	previousTokenPrefix := apiToken.TokenPrefix
+	previousTokenSalt := apiToken.TokenSalt
+	previousTokenHash := apiToken.TokenHash
+	apiToken.PreviousTokenPrefix = &previousTokenPrefix
+	apiToken.PreviousTokenSalt = &previousTokenSalt
+	apiToken.PreviousTokenHash = &previousTokenHash
+	apiToken.PreviousTokenExpiredAt = &previousTokenExpiredAt
+	apiToken.RotatedAt = &now
+	apiToken.TokenPrefix = getApiTokenPrefix(token)
+	apiToken.TokenSalt = salt
+	apiToken.TokenHash = hashApiToken(salt, token)
+	apiToken.Token = token
+	return apiToken, nil
+}
+
+const (
+	previousApiTokenUsageEventInterval = 10 * time.Minute
+	// maxPreviousApiTokenUsageRecords bounds the records whatever the callers send,
+	// once it is reached the usages of a token are only recorded once per interval whoever the caller is
+	maxPreviousApiTokenUsageRecords = 10000
+)
+
+type previousApiTokenUsageKey struct {
+	apiTokenId uint
+	caller     string
+}
+
+// previousApiTokenUsages remembers when the usages of the previous secrets were recorded, per api token and caller
+var previousApiTokenUsages = struct {
+	sync.Mutex
+	recordedAt map[previousApiTokenUsageKey]time.Time
+	prunedAt   time.Time
+}{
+	recordedAt: make(map[previousApiTokenUsageKey]time.Time),
+}
+
+// shouldRecordPreviousTokenUsage tells whether the usage of the token by the caller was not recorded within the interval,
+// the records older than the interval are pruned so that the records are bounded by the callers of an interval
+func shouldRecordPreviousTokenUsage(apiTokenId uint, caller string, now time.Time) bool {
+	previousApiTokenUsages.Lock()
+	defer previousApiTokenUsages.Unlock()
+	if now.Sub(previousApiTokenUsages.prunedAt) >= previousApiTokenUsageEventInterval || len(previousApiTokenUsages.recordedAt) >= maxPreviousApiTokenUsageRecords {
+		for key, recordedAt := range previousApiTokenUsages.recordedAt {
+			if now.Sub(recordedAt) >= previousApiTokenUsageEventInterval {
+				delete(previousApiTokenUsages.recordedAt, key)
+			}
+		}
+		previousApiTokenUsages.prunedAt = now
+	}
+	key := previousApiTokenUsageKey{apiTokenId: apiTokenId, caller: caller}
+	if len(previousApiTokenUsages.recordedAt) >= maxPreviousApiTokenUsageRecords {
+		key.caller = ""
+	}
+	if recordedAt, ok := previousApiTokenUsages.recordedAt[key]; ok && now.Sub(recordedAt) < previousApiTokenUsageEventInterval {
+		return false
+	}
+	previousApiTokenUsages.recordedAt[key] = now
+	return true
+}
+
+func forgetPreviousTokenUsages(apiTokenId uint) {
+	previousApiTokenUsages.Lock()
+	defer previousApiTokenUsages.Unlock()
+	for key := range previousApiTokenUsages.recordedAt {
+		if key.apiTokenId == apiTokenId {
+			delete(previousApiTokenUsages.recordedAt, key)
+		}
+	}
+}
+
+// RecordPreviousTokenUsage creates an event telling that a caller still uses the secret replaced by the latest rotation,
+// the usages of a token by a caller are recorded at most once per previousApiTokenUsageEventInterval
+func (s *apiTokenService) RecordPreviousTokenUsage(ctx context.Context, apiToken *models.ApiToken, caller string) error {
+	if !shouldRecordPreviousTokenUsage(apiToken.ID, caller, time.Now()) {
+		return nil
+	}
+	name := caller
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	_, err := EventService.Create(ctx, CreateEventOption{
+		Name:           name,
+		CreatorId:      apiToken.UserId,
+		ApiTokenName:   apiToken.Name,
+		OrganizationId: &apiToken.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeApiToken,
+		ResourceId:     apiToken.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "used previous secret",
+	})
+	return err
 }
 
 func (s *apiTokenService) GetByName(ctx context.Context, organizationId, userId uint, name string) (*models.ApiToken, error) {
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldRecordPreviousTokenUsage(t *testing.T) {
+	now := time.Now()
+	if !shouldRecordPreviousTokenUsage(1, "10.0.0.1 curl/7.0", now) {
+		t.Error("expected the first usage to be recorded")
+	}
+	if shouldRecordPreviousTokenUsage(1, "10.0.0.1 curl/7.0", now.Add(time.Minute)) {
+		t.Error("expected the usage of the same caller within the interval to be skipped")
+	}
+	// a frequent caller does not hide the other callers of the token
+	if !shouldRecordPreviousTokenUsage(1, "10.0.0.2 yatai-cli/1.0", now.Add(time.Minute)) {
+		t.Error("expected the usage of another caller to be recorded")
+	}
+	if !shouldRecordPreviousTokenUsage(2, "10.0.0.1 curl/7.0", now.Add(time.Minute)) {
+		t.Error("expected the usage of another token to be recorded")
+	}
+	if !shouldRecordPreviousTokenUsage(1, "10.0.0.1 curl/7.0", now.Add(previousApiTokenUsageEventInterval)) {
+		t.Error("expected the usage to be recorded again after the interval")
+	}
+
+	// the stale records are pruned
+	shouldRecordPreviousTokenUsage(3, "10.0.0.3 curl/7.0", now.Add(3*previousApiTokenUsageEventInterval))
+	previousApiTokenUsages.Lock()
+	defer previousApiTokenUsages.Unlock()
+	for key, recordedAt := range previousApiTokenUsages.recordedAt {
+		if now.Add(3*previousApiTokenUsageEventInterval).Sub(recordedAt) >= previousApiTokenUsageEventInterval {
+			t.Errorf("expected the stale record of token %d and caller %q to be pruned", key.apiTokenId, key.caller)
+		}
+	}
+}
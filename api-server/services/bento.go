@@ -17,6 +17,7 @@ import (
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	commonconsts "github.com/bentoml/yatai-common/consts"
 	"github.com/bentoml/yatai-schemas/modelschemas"
@@ -447,6 +448,91 @@ func (s *bentoService) Download(ctx context.Context, bento *models.Bento, writer
 	return
 }
 
+// checkDeletable must be called in the transaction of the deletion, it locks the bento so that no deployment target can reference it meanwhile.
+// The deployment targets of the inactive revisions keep their bentos for the rollbacks and the diffs of the revisions.
+func (s *bentoService) checkDeletable(ctx context.Context, bento *models.Bento) error {
+	db := mustGetSession(ctx)
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", bento.ID).First(&models.Bento{}).Error
+	if err != nil {
+		return errors.Wrap(err, "lock bento")
+	}
+	var total int64
+	err = db.Model(&models.DeploymentTarget{}).Unscoped().Where("bento_id = ?", bento.ID).Count(&total).Error
+	if err != nil {
+		return errors.Wrap(err, "count deployment targets")
+	}
+	if total > 0 {
+		return errors.Errorf("bento %s is referenced by %d deployment targets of the deployment revisions, it is kept for their rollbacks", bento.Version, total)
+	}
+	return nil
+}
+
+// removeS3ObjectAfterCommit removes the object of the bento once its deletion is committed, the object is kept when the deletion is rolled back
+func (s *bentoService) removeS3ObjectAfterCommit(ctx context.Context, bento *models.Bento) error {
+	bentoRepository, err := BentoRepositoryService.GetAssociatedBentoRepository(ctx, bento)
+	if err != nil {
+		return err
+	}
+	org, err := OrganizationService.GetAssociatedOrganization(ctx, bentoRepository)
+	if err != nil {
+		return err
+	}
+	s3Config, err := OrganizationService.GetS3Config(ctx, org)
+	if err != nil {
+		return err
+	}
+	minioClient, err := s3Config.GetMinioClient()
+	if err != nil {
+		return errors.Wrap(err, "create s3 client")
+	}
+	bucketName, err := s.GetS3BucketName(ctx, bento)
+	if err != nil {
+		return err
+	}
+	objectName, err := s.getS3ObjectName(ctx, bento)
+	if err != nil {
+		return err
+	}
+	runAfterCommit(ctx, func() {
+		err := minioClient.RemoveObject(context.Background(), bucketName, objectName, minio.RemoveObjectOptions{})
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+			logrus.Errorf("remove object %s of the deleted bento: %s", objectName, err.Error())
+		}
+	})
+	return nil
+}
+
+func (s *bentoService) Delete(ctx context.Context, bento *models.Bento) (b *models.Bento, err error) {
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	err = s.checkDeletable(ctx, bento)
+	if err != nil {
+		return
+	}
+	err = db.Where("bento_id = ?", bento.ID).Unscoped().Delete(&models.BentoModelRel{}).Error
+	if err != nil {
+		return
+	}
+	err = LabelService.DeleteByResource(ctx, bento)
+	if err != nil {
+		return
+	}
+	err = db.Unscoped().Delete(bento).Error
+	if err != nil {
+		return
+	}
+	err = s.removeS3ObjectAfterCommit(ctx, bento)
+	if err != nil {
+		return
+	}
+	b = bento
+	return
+}
+
 func (s *bentoService) getS3ObjectName(ctx context.Context, bento *models.Bento) (string, error) {
 	bentoRepository, err := BentoRepositoryService.GetAssociatedBentoRepository(ctx, bento)
 	if err != nil {
@@ -570,6 +656,11 @@ func (s *bentoService) Update(ctx context.Context, bento *models.Bento, opt Upda
 		}
 	}
 
+	err = NotificationService.Publish(ctx, modelschemas.ResourceTypeBento, bento.Uid)
+	if err != nil {
+		return nil, err
+	}
+
 	if opt.Labels != nil {
 		bentoRepository, err := BentoRepositoryService.GetAssociatedBentoRepository(ctx, bento)
 		if err != nil {
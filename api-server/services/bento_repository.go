@@ -28,20 +28,22 @@ type CreateBentoRepositoryOption struct {
 }
 
 type UpdateBentoRepositoryOption struct {
-	Description *string
-	Labels      *modelschemas.LabelItemsSchema
+	Description     *string
+	Labels          *modelschemas.LabelItemsSchema
+	RetentionPolicy **models.RetentionPolicy
 }
 
 type ListBentoRepositoryOption struct {
 	BaseListOption
 	BaseListByLabelsOption
-	OrganizationId *uint
-	CreatorId      *uint
-	CreatorIds     *[]uint
-	LastUpdaterIds *[]uint
-	Order          *string
-	Names          *[]string
-	Ids            *[]uint
+	OrganizationId     *uint
+	CreatorId          *uint
+	CreatorIds         *[]uint
+	LastUpdaterIds     *[]uint
+	Order              *string
+	Names              *[]string
+	Ids                *[]uint
+	HasRetentionPolicy *bool
 }
 
 func (*bentoRepositoryService) Create(ctx context.Context, opt CreateBentoRepositoryOption) (*models.BentoRepository, error) {
@@ -75,6 +77,14 @@ func (s *bentoRepositoryService) Update(ctx context.Context, bentoRepository *mo
 			}
 		}()
 	}
+	if opt.RetentionPolicy != nil {
+		updaters["retention_policy"] = *opt.RetentionPolicy
+		defer func() {
+			if err == nil {
+				bentoRepository.RetentionPolicy = *opt.RetentionPolicy
+			}
+		}()
+	}
 
 	if len(updaters) == 0 {
 		return bentoRepository, nil
@@ -156,6 +166,13 @@ func (s *bentoRepositoryService) List(ctx context.Context, opt ListBentoReposito
 	if opt.CreatorIds != nil {
 		query = query.Where("bento_repository.creator_id in (?)", *opt.CreatorIds)
 	}
+	if opt.HasRetentionPolicy != nil {
+		if *opt.HasRetentionPolicy {
+			query = query.Where("bento_repository.retention_policy IS NOT NULL")
+		} else {
+			query = query.Where("bento_repository.retention_policy IS NULL")
+		}
+	}
 	query = query.Joins("LEFT JOIN bento ON bento.bento_repository_id = bento_repository.id")
 	query = query.Joins("LEFT OUTER JOIN bento b2 ON b2.bento_repository_id = bento_repository.id AND bento.id < b2.id")
 	query = query.Where("b2.id IS NULL")
@@ -184,6 +201,38 @@ func (s *bentoRepositoryService) List(ctx context.Context, opt ListBentoReposito
 	return bentoRepositories, uint(total), err
 }
 
+func (s *bentoRepositoryService) Delete(ctx context.Context, bentoRepository *models.BentoRepository) (r *models.BentoRepository, err error) {
+	bentos, _, err := BentoService.List(ctx, ListBentoOption{
+		BentoRepositoryId: &bentoRepository.ID,
+	})
+	if err != nil {
+		return
+	}
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	for _, bento := range bentos {
+		_, err = BentoService.Delete(ctx, bento)
+		if err != nil {
+			err = errors.Wrapf(err, "delete bento %s", bento.Version)
+			return
+		}
+	}
+	err = LabelService.DeleteByResource(ctx, bentoRepository)
+	if err != nil {
+		return
+	}
+	err = db.Unscoped().Delete(bentoRepository).Error
+	if err != nil {
+		return
+	}
+	r = bentoRepository
+	return
+}
+
 type IBentoRepositoryAssociate interface {
 	GetAssociatedBentoRepositoryId() uint
 	GetAssociatedBentoRepositoryCache() *models.BentoRepository
@@ -50,12 +50,14 @@ type CreateClusterOption struct {
 	Description    string
 	KubeConfig     string
 	Config         *modelschemas.ClusterConfigSchema
+	ApprovalConfig *models.ClusterApprovalConfig
 }
 
 type UpdateClusterOption struct {
-	Description *string
-	Config      **modelschemas.ClusterConfigSchema
-	KubeConfig  *string
+	Description    *string
+	Config         **modelschemas.ClusterConfigSchema
+	KubeConfig     *string
+	ApprovalConfig **models.ClusterApprovalConfig
 }
 
 type ListClusterOption struct {
@@ -74,6 +76,11 @@ func (s *clusterService) Create(ctx context.Context, opt CreateClusterOption) (*
 		return nil, errors.New(strings.Join(errs, ";"))
 	}
 
+	kubeConfig, err := SecretService.Encrypt(opt.KubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt kube config")
+	}
+
 	// nolint: ineffassign,staticcheck
 	db, ctx, df, err := startTransaction(ctx)
 	if err != nil {
@@ -85,9 +92,10 @@ func (s *clusterService) Create(ctx context.Context, opt CreateClusterOption) (*
 		ResourceMixin: models.ResourceMixin{
 			Name: opt.Name,
 		},
-		Description: opt.Description,
-		KubeConfig:  opt.KubeConfig,
-		Config:      opt.Config,
+		Description:    opt.Description,
+		KubeConfig:     kubeConfig,
+		Config:         opt.Config,
+		ApprovalConfig: opt.ApprovalConfig,
 		CreatorAssociate: models.CreatorAssociate{
 			CreatorId: opt.CreatorId,
 		},
@@ -123,10 +131,25 @@ func (s *clusterService) Update(ctx context.Context, c *models.Cluster, opt Upda
 		}()
 	}
 	if opt.KubeConfig != nil {
-		updaters["kube_config"] = *opt.KubeConfig
+		var kubeConfig string
+		kubeConfig, err = SecretService.Encrypt(*opt.KubeConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "encrypt kube config")
+		}
+		updaters["kube_config"] = kubeConfig
 		defer func() {
 			if err == nil {
-				c.KubeConfig = *opt.KubeConfig
+				c.KubeConfig = kubeConfig
+				KubeClientPool.Invalidate(c.ID)
+				KubeInformerManager.Invalidate(c.ID)
+			}
+		}()
+	}
+	if opt.ApprovalConfig != nil {
+		updaters["approval_config"] = *opt.ApprovalConfig
+		defer func() {
+			if err == nil {
+				c.ApprovalConfig = *opt.ApprovalConfig
 			}
 		}()
 	}
@@ -203,6 +226,11 @@ func (s *clusterService) List(ctx context.Context, opt ListClusterOption) ([]*mo
 			for _, member := range clusterMembers {
 				clusterIds = append(clusterIds, member.ClusterId)
 			}
+			userGroupClusterIds, err := UserGroupMemberService.ListResourceIds(ctx, *userID, modelschemas.ResourceTypeCluster)
+			if err != nil {
+				return nil, 0, err
+			}
+			clusterIds = append(clusterIds, userGroupClusterIds...)
 			clusterIds = append(clusterIds, 0) // Add a fill value of 0 because it cannot be empty
 			query = query.Where("(id in (?) OR creator_id = ?)", clusterIds, userID)
 		}
@@ -310,8 +338,39 @@ func (s *clusterService) GetDefault(ctx context.Context, orgId uint) (defaultClu
 	return
 }
 
+// GetKubeConfig returns the decrypted kube config of the cluster
+func (s *clusterService) GetKubeConfig(c *models.Cluster) (string, error) {
+	kubeConfig, err := SecretService.Decrypt(c.KubeConfig)
+	return kubeConfig, errors.Wrapf(err, "decrypt the kube config of cluster %s", c.Name)
+}
+
+// Reencrypt encrypts the kube config of the cluster with the primary key, it returns false when it already is
+func (s *clusterService) Reencrypt(ctx context.Context, c *models.Cluster) (bool, error) {
+	if !SecretService.NeedsReencryption(c.KubeConfig) {
+		return false, nil
+	}
+	kubeConfig, err := s.GetKubeConfig(c)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.Update(ctx, c, UpdateClusterOption{
+		KubeConfig: &kubeConfig,
+	})
+	return err == nil, err
+}
+
+// GetKubeCliSet returns the clients of the cluster from KubeClientPool, they are built on the first call
+// and rebuilt when the kube config of the cluster changes
 func (s *clusterService) GetKubeCliSet(ctx context.Context, c *models.Cluster) (clientSet *kubernetes.Clientset, restConfig *rest.Config, err error) {
-	if c.KubeConfig == "" {
+	return KubeClientPool.Get(c)
+}
+
+func (s *clusterService) newKubeCliSet(c *models.Cluster) (clientSet *kubernetes.Clientset, restConfig *rest.Config, err error) {
+	kubeConfig, err := s.GetKubeConfig(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kubeConfig == "" {
 		restConfig, err = rest.InClusterConfig()
 		if err != nil {
 			kubeConfig :=
@@ -324,7 +383,7 @@ func (s *clusterService) GetKubeCliSet(ctx context.Context, c *models.Cluster) (
 	} else {
 		configV1 := clientcmdapiv1.Config{}
 		var jsonBytes []byte
-		jsonBytes, err = yaml.YAMLToJSON([]byte(c.KubeConfig))
+		jsonBytes, err = yaml.YAMLToJSON([]byte(kubeConfig))
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "k8s cluster config yaml to json")
 		}
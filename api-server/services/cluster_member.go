@@ -24,20 +24,23 @@ func (s *clusterMemberService) GetResourceType() modelschemas.ResourceType {
 }
 
 type CreateClusterMemberOption struct {
-	CreatorId uint
-	UserId    uint
-	ClusterId uint
-	Role      modelschemas.MemberRole
+	CreatorId        uint
+	UserId           uint
+	ClusterId        uint
+	Role             modelschemas.MemberRole
+	IdentityProvider *string
 }
 
 type UpdateClusterMemberOption struct {
-	Role modelschemas.MemberRole
+	Role             modelschemas.MemberRole
+	IdentityProvider **string
 }
 
 type ListClusterMemberOption struct {
-	UserId    *uint
-	ClusterId *uint
-	Roles     *[]modelschemas.MemberRole
+	UserId           *uint
+	ClusterId        *uint
+	Roles            *[]modelschemas.MemberRole
+	IdentityProvider *string
 }
 
 func (s *clusterMemberService) Create(ctx context.Context, operatorId uint, opt CreateClusterMemberOption) (*models.ClusterMember, error) {
@@ -47,7 +50,8 @@ func (s *clusterMemberService) Create(ctx context.Context, operatorId uint, opt
 	}
 
 	if err == nil {
-		return s.Update(ctx, oldMember, operatorId, UpdateClusterMemberOption{opt.Role})
+		// an explicitly created membership takes over the one granted by the role mappings
+		return s.Update(ctx, oldMember, operatorId, UpdateClusterMemberOption{Role: opt.Role, IdentityProvider: &opt.IdentityProvider})
 	}
 
 	// nolint: ineffassign,staticcheck
@@ -66,7 +70,8 @@ func (s *clusterMemberService) Create(ctx context.Context, operatorId uint, opt
 		ClusterAssociate: models.ClusterAssociate{
 			ClusterId: opt.ClusterId,
 		},
-		Role: opt.Role,
+		Role:             opt.Role,
+		IdentityProvider: opt.IdentityProvider,
 	}
 	err = db.Create(member).Error
 	if err != nil {
@@ -76,11 +81,18 @@ func (s *clusterMemberService) Create(ctx context.Context, operatorId uint, opt
 }
 
 func (s *clusterMemberService) Update(ctx context.Context, m *models.ClusterMember, operatorId uint, opt UpdateClusterMemberOption) (*models.ClusterMember, error) {
-	err := s.getBaseDB(ctx).Where("id = ?", m.ID).Updates(map[string]interface{}{
+	updaters := map[string]interface{}{
 		"role": opt.Role,
-	}).Error
+	}
+	if opt.IdentityProvider != nil {
+		updaters["identity_provider"] = *opt.IdentityProvider
+	}
+	err := s.getBaseDB(ctx).Where("id = ?", m.ID).Updates(updaters).Error
 	if err == nil {
 		m.Role = opt.Role
+		if opt.IdentityProvider != nil {
+			m.IdentityProvider = *opt.IdentityProvider
+		}
 	}
 	return m, err
 }
@@ -100,6 +112,8 @@ func (s *clusterMemberService) GetBy(ctx context.Context, userId, clusterId uint
 func (s *clusterMemberService) List(ctx context.Context, opt ListClusterMemberOption) ([]*models.ClusterMember, error) {
 	members := make([]*models.ClusterMember, 0)
 	query := getBaseQuery(ctx, s)
+	// the rows of the user groups are listed by the UserGroupMemberService
+	query = query.Where("user_group_id IS NULL")
 	if opt.ClusterId != nil {
 		query = query.Where("cluster_id = ?", *opt.ClusterId)
 	}
@@ -109,6 +123,9 @@ func (s *clusterMemberService) List(ctx context.Context, opt ListClusterMemberOp
 	if opt.Roles != nil {
 		query = query.Where("role in (?)", *opt.Roles)
 	}
+	if opt.IdentityProvider != nil {
+		query = query.Where("identity_provider = ?", *opt.IdentityProvider)
+	}
 	err := query.Order("id DESC").Find(&members).Error
 	return members, err
 }
@@ -131,7 +148,7 @@ func (s *clusterMemberService) GetOrganization(ctx context.Context, resourceId u
 
 func (s *clusterMemberService) CheckRoles(ctx context.Context, userId, resourceId uint, roles []modelschemas.MemberRole) (bool, error) {
 	q := s.getBaseDB(ctx).
-		Where("user_id = ?", userId).
+		Where("(user_id = ? OR user_group_id in (?))", userId, UserGroupMemberService.userGroupIdsOfUser(ctx, userId)).
 		Where("cluster_id = ?", resourceId).
 		Where("role in (?)", roles)
 	var total int64
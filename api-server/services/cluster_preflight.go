@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/sync/errsgroup"
+)
+
+const clusterPreflightRequestTimeout = 10 * time.Second
+
+type ClusterPreflightStatus string
+
+const (
+	ClusterPreflightStatusPassed  ClusterPreflightStatus = "passed"
+	ClusterPreflightStatusWarning ClusterPreflightStatus = "warning"
+	ClusterPreflightStatusFailed  ClusterPreflightStatus = "failed"
+)
+
+var clusterPreflightStatusSeverities = map[ClusterPreflightStatus]int{
+	ClusterPreflightStatusPassed:  0,
+	ClusterPreflightStatusWarning: 1,
+	ClusterPreflightStatusFailed:  2,
+}
+
+type ClusterPreflightCheck struct {
+	Name    string                 `json:"name"`
+	Status  ClusterPreflightStatus `json:"status"`
+	Message string                 `json:"message,omitempty"`
+}
+
+type ClusterPreflightPermission struct {
+	ClusterPreflightCheck
+	// Namespace is empty for the cluster wide resources
+	Namespace   string `json:"namespace,omitempty"`
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	Allowed     bool   `json:"allowed"`
+}
+
+type ClusterPreflightCRD struct {
+	ClusterPreflightCheck
+	Group             string   `json:"group"`
+	Resource          string   `json:"resource"`
+	InstalledVersions []string `json:"installed_versions"`
+	SupportedVersions []string `json:"supported_versions"`
+}
+
+// ClusterPreflightReport is the result of the checks of a kube config, Status is the worst status of the checks
+type ClusterPreflightReport struct {
+	Status        ClusterPreflightStatus        `json:"status"`
+	ServerVersion string                        `json:"server_version,omitempty"`
+	Reachability  *ClusterPreflightCheck        `json:"reachability"`
+	Permissions   []*ClusterPreflightPermission `json:"permissions"`
+	CRDs          []*ClusterPreflightCRD        `json:"crds"`
+	Namespaces    []*ClusterPreflightCheck      `json:"namespaces"`
+	Components    []*ClusterPreflightCheck      `json:"components"`
+}
+
+func (r *ClusterPreflightReport) add(check *ClusterPreflightCheck) {
+	if clusterPreflightStatusSeverities[check.Status] > clusterPreflightStatusSeverities[r.Status] {
+		r.Status = check.Status
+	}
+}
+
+type clusterPreflightService struct{}
+
+var ClusterPreflightService = clusterPreflightService{}
+
+type ClusterPreflightOption struct {
+	KubeConfig string
+	Config     *modelschemas.ClusterConfigSchema
+}
+
+type clusterPreflightPermissionSpec struct {
+	namespace   string
+	verb        string
+	group       string
+	resource    string
+	subresource string
+	// the missing optional permissions only degrade some features, they are warnings
+	optional bool
+}
+
+// getPermissionSpecs returns the operations the api server performs on the kube cluster
+func (s *clusterPreflightService) getPermissionSpecs(deploymentNamespace string) []clusterPreflightPermissionSpec {
+	specs := make([]clusterPreflightPermissionSpec, 0)
+	namespaced := func(group, resource, subresource string, optional bool, verbs ...string) {
+		for _, verb := range verbs {
+			specs = append(specs, clusterPreflightPermissionSpec{
+				namespace:   deploymentNamespace,
+				verb:        verb,
+				group:       group,
+				resource:    resource,
+				subresource: subresource,
+				optional:    optional,
+			})
+		}
+	}
+	namespaced("serving.yatai.ai", "bentodeployments", "", false, "get", "list", "watch", "create", "update", "delete")
+	namespaced("resources.yatai.ai", "bentorequests", "", false, "get", "create", "update")
+	namespaced("", "pods", "", false, "get", "list", "watch")
+	namespaced("", "pods", "log", false, "get")
+	namespaced("", "pods", "exec", true, "create")
+	namespaced("", "events", "", false, "list", "watch")
+	namespaced("", "services", "", false, "get", "list")
+	namespaced("apps", "deployments", "", false, "get", "list")
+	namespaced("networking.k8s.io", "ingresses", "", false, "get", "list")
+	namespaced("autoscaling", "horizontalpodautoscalers", "", true, "get", "list")
+	namespaced("", "resourcequotas", "", true, "list")
+	specs = append(specs,
+		clusterPreflightPermissionSpec{
+			namespace: commonconsts.DefaultKubeNamespaceYataiDeploymentComponent,
+			verb:      "get",
+			resource:  "secrets",
+		},
+		clusterPreflightPermissionSpec{
+			verb:     "get",
+			resource: "namespaces",
+		},
+		clusterPreflightPermissionSpec{
+			verb:     "create",
+			resource: "namespaces",
+			optional: true,
+		},
+		clusterPreflightPermissionSpec{
+			verb:     "list",
+			resource: "nodes",
+			optional: true,
+		},
+	)
+	return specs
+}
+
+// Check validates a kube config before it is saved, the kube config is only used for the checks
+func (s *clusterPreflightService) Check(ctx context.Context, opt ClusterPreflightOption) (*ClusterPreflightReport, error) {
+	// the clients fall back to the in-cluster config of the api server without a kube config
+	if strings.TrimSpace(opt.KubeConfig) == "" {
+		return nil, errors.New("kube_config is required")
+	}
+	cluster := &models.Cluster{
+		KubeConfig: opt.KubeConfig,
+		Config:     opt.Config,
+	}
+	report := &ClusterPreflightReport{
+		Status:      ClusterPreflightStatusPassed,
+		Permissions: make([]*ClusterPreflightPermission, 0),
+		CRDs:        make([]*ClusterPreflightCRD, 0),
+		Namespaces:  make([]*ClusterPreflightCheck, 0),
+		Components:  make([]*ClusterPreflightCheck, 0),
+	}
+
+	report.Reachability = &ClusterPreflightCheck{
+		Name:   "kube api server",
+		Status: ClusterPreflightStatusPassed,
+	}
+	defer report.add(report.Reachability)
+	_, restConfig, err := ClusterService.newKubeCliSet(cluster)
+	if err != nil {
+		report.Reachability.Status = ClusterPreflightStatusFailed
+		report.Reachability.Message = errors.Wrap(err, "parse kube config").Error()
+		return report, nil
+	}
+	restConfig.Timeout = clusterPreflightRequestTimeout
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "new for k8s config")
+	}
+	startedAt := time.Now()
+	version, err := clientSet.Discovery().ServerVersion()
+	if err != nil {
+		report.Reachability.Status = ClusterPreflightStatusFailed
+		report.Reachability.Message = errors.Wrap(err, "get the kube api server version").Error()
+		return report, nil
+	}
+	report.ServerVersion = version.GitVersion
+	report.Reachability.Message = fmt.Sprintf("kubernetes %s responded in %dms", version.GitVersion, time.Since(startedAt).Milliseconds())
+
+	deploymentNamespace := ClusterService.GetDeploymentKubeNamespace(cluster)
+
+	var eg errsgroup.Group
+	eg.SetPoolSize(10)
+	var mu sync.Mutex
+	for _, spec := range s.getPermissionSpecs(deploymentNamespace) {
+		spec := spec
+		eg.Go(func() error {
+			permission := s.checkPermission(ctx, clientSet, spec)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Permissions = append(report.Permissions, permission)
+			return nil
+		})
+	}
+	eg.Go(func() error {
+		crds := s.checkCRDs(clientSet.Discovery())
+		mu.Lock()
+		defer mu.Unlock()
+		report.CRDs = crds
+		return nil
+	})
+	eg.Go(func() error {
+		namespaces := s.checkNamespaces(ctx, clientSet, deploymentNamespace)
+		mu.Lock()
+		defer mu.Unlock()
+		report.Namespaces = namespaces
+		return nil
+	})
+	eg.Go(func() error {
+		components := s.checkComponents(ctx, clientSet)
+		mu.Lock()
+		defer mu.Unlock()
+		report.Components = components
+		return nil
+	})
+	err = eg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, permission := range report.Permissions {
+		report.add(&permission.ClusterPreflightCheck)
+	}
+	for _, crd := range report.CRDs {
+		report.add(&crd.ClusterPreflightCheck)
+	}
+	for _, namespace := range report.Namespaces {
+		report.add(namespace)
+	}
+	for _, component := range report.Components {
+		report.add(component)
+	}
+	return report, nil
+}
+
+func (s *clusterPreflightService) checkPermission(ctx context.Context, clientSet *kubernetes.Clientset, spec clusterPreflightPermissionSpec) *ClusterPreflightPermission {
+	resource := spec.resource
+	if spec.subresource != "" {
+		resource = fmt.Sprintf("%s/%s", spec.resource, spec.subresource)
+	}
+	if spec.group != "" {
+		resource = fmt.Sprintf("%s.%s", resource, spec.group)
+	}
+	name := fmt.Sprintf("%s %s", spec.verb, resource)
+	if spec.namespace != "" {
+		name = fmt.Sprintf("%s in namespace %s", name, spec.namespace)
+	}
+	permission := &ClusterPreflightPermission{
+		ClusterPreflightCheck: ClusterPreflightCheck{
+			Name:   name,
+			Status: ClusterPreflightStatusPassed,
+		},
+		Namespace:   spec.namespace,
+		Verb:        spec.verb,
+		Group:       spec.group,
+		Resource:    spec.resource,
+		Subresource: spec.subresource,
+	}
+	review, err := clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   spec.namespace,
+				Verb:        spec.verb,
+				Group:       spec.group,
+				Resource:    spec.resource,
+				Subresource: spec.subresource,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		permission.Status = ClusterPreflightStatusFailed
+		permission.Message = errors.Wrap(err, "create self subject access review").Error()
+		return permission
+	}
+	permission.Allowed = review.Status.Allowed
+	if review.Status.Allowed {
+		return permission
+	}
+	permission.Status = ClusterPreflightStatusFailed
+	if spec.optional {
+		permission.Status = ClusterPreflightStatusWarning
+	}
+	permission.Message = "the credentials are not allowed to " + name
+	if review.Status.Reason != "" {
+		permission.Message = fmt.Sprintf("%s: %s", permission.Message, review.Status.Reason)
+	}
+	return permission
+}
+
+// getInstalledVersions returns the versions of the group which serve the resource
+func (s *clusterPreflightService) getInstalledVersions(discoveryCli discovery.DiscoveryInterface, groups *metav1.APIGroupList, group, resource string) ([]string, error) {
+	versions := make([]string, 0)
+	for _, apiGroup := range groups.Groups {
+		if apiGroup.Name != group {
+			continue
+		}
+		for _, version := range apiGroup.Versions {
+			resources, err := discoveryCli.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return nil, errors.Wrapf(err, "list the resources of %s", version.GroupVersion)
+			}
+			for _, apiResource := range resources.APIResources {
+				if apiResource.Name == resource {
+					versions = append(versions, version.Version)
+					break
+				}
+			}
+		}
+	}
+	return versions, nil
+}
+
+// checkCRD checks the installed versions of the resource, groupsErr is the error of the discovery of the api groups
+func (s *clusterPreflightService) checkCRD(discoveryCli discovery.DiscoveryInterface, groups *metav1.APIGroupList, groupsErr error, group, resource string, supportedVersions []string) *ClusterPreflightCRD {
+	crd := &ClusterPreflightCRD{
+		ClusterPreflightCheck: ClusterPreflightCheck{
+			Name:   fmt.Sprintf("%s.%s", resource, group),
+			Status: ClusterPreflightStatusPassed,
+		},
+		Group:             group,
+		Resource:          resource,
+		InstalledVersions: make([]string, 0),
+		SupportedVersions: supportedVersions,
+	}
+	if groupsErr != nil {
+		crd.Status = ClusterPreflightStatusFailed
+		crd.Message = errors.Wrap(groupsErr, "list the api groups").Error()
+		return crd
+	}
+	versions, err := s.getInstalledVersions(discoveryCli, groups, group, resource)
+	if err != nil {
+		crd.Status = ClusterPreflightStatusFailed
+		crd.Message = err.Error()
+		return crd
+	}
+	crd.InstalledVersions = versions
+	return crd
+}
+
+func (s *clusterPreflightService) hasVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *clusterPreflightService) checkCRDs(discoveryCli discovery.DiscoveryInterface) []*ClusterPreflightCRD {
+	// the api groups are discovered once for all the CRDs
+	groups, groupsErr := discoveryCli.ServerGroups()
+	bentoDeployment := s.checkCRD(discoveryCli, groups, groupsErr, "serving.yatai.ai", "bentodeployments", []string{"v1alpha2", "v1alpha3", "v2alpha1"})
+	bentoRequest := s.checkCRD(discoveryCli, groups, groupsErr, "resources.yatai.ai", "bentorequests", []string{"v1alpha1"})
+
+	if bentoDeployment.Status == ClusterPreflightStatusPassed {
+		supported := false
+		for _, version := range bentoDeployment.InstalledVersions {
+			supported = supported || s.hasVersion(bentoDeployment.SupportedVersions, version)
+		}
+		switch {
+		case len(bentoDeployment.InstalledVersions) == 0:
+			bentoDeployment.Status = ClusterPreflightStatusFailed
+			bentoDeployment.Message = "the BentoDeployment CRD is not installed, install the yatai-deployment component"
+		case !supported:
+			bentoDeployment.Status = ClusterPreflightStatusFailed
+			bentoDeployment.Message = fmt.Sprintf("none of the installed BentoDeployment versions %s is supported", strings.Join(bentoDeployment.InstalledVersions, ", "))
+		case !s.hasVersion(bentoDeployment.InstalledVersions, "v2alpha1"):
+			bentoDeployment.Status = ClusterPreflightStatusWarning
+			bentoDeployment.Message = "the dry runs and the diffs of the deployments need the v2alpha1 BentoDeployment CRD, upgrade the yatai-deployment component"
+		}
+	}
+
+	// the BentoRequests are only created for the v2alpha1 BentoDeployments
+	if bentoRequest.Status == ClusterPreflightStatusPassed && !s.hasVersion(bentoRequest.InstalledVersions, "v1alpha1") {
+		if s.hasVersion(bentoDeployment.InstalledVersions, "v2alpha1") {
+			bentoRequest.Status = ClusterPreflightStatusFailed
+			bentoRequest.Message = "the v1alpha1 BentoRequest CRD is not installed, install the yatai-image-builder component"
+		} else {
+			bentoRequest.Message = "the BentoRequest CRD is not needed by the installed BentoDeployment CRD"
+		}
+	}
+	return []*ClusterPreflightCRD{bentoDeployment, bentoRequest}
+}
+
+func (s *clusterPreflightService) checkNamespace(ctx context.Context, clientSet *kubernetes.Clientset, namespace string, missingStatus ClusterPreflightStatus, missingMessage string) *ClusterPreflightCheck {
+	check := &ClusterPreflightCheck{
+		Name:   namespace,
+		Status: ClusterPreflightStatusPassed,
+	}
+	_, err := clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		check.Status = missingStatus
+		check.Message = missingMessage
+		return check
+	}
+	if err != nil {
+		check.Status = ClusterPreflightStatusFailed
+		check.Message = errors.Wrapf(err, "get namespace %s", namespace).Error()
+	}
+	return check
+}
+
+func (s *clusterPreflightService) checkNamespaces(ctx context.Context, clientSet *kubernetes.Clientset, deploymentNamespace string) []*ClusterPreflightCheck {
+	return []*ClusterPreflightCheck{
+		s.checkNamespace(ctx, clientSet, commonconsts.DefaultKubeNamespaceYataiDeploymentComponent, ClusterPreflightStatusFailed, "the namespace of the yatai-deployment component does not exist, install the component"),
+		s.checkNamespace(ctx, clientSet, deploymentNamespace, ClusterPreflightStatusWarning, "the deployment namespace does not exist, it is created by the first deployment"),
+	}
+}
+
+// checkComponents checks that the yatai-deployment component has an available operator
+func (s *clusterPreflightService) checkComponents(ctx context.Context, clientSet *kubernetes.Clientset) []*ClusterPreflightCheck {
+	check := &ClusterPreflightCheck{
+		Name:   string(modelschemas.YataiComponentNameDeployment),
+		Status: ClusterPreflightStatusPassed,
+	}
+	deployments, err := clientSet.AppsV1().Deployments(commonconsts.DefaultKubeNamespaceYataiDeploymentComponent).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		check.Status = ClusterPreflightStatusWarning
+		check.Message = errors.Wrap(err, "list the deployments of the yatai-deployment component").Error()
+		return []*ClusterPreflightCheck{check}
+	}
+	available := false
+	for _, deployment := range deployments.Items {
+		available = available || deployment.Status.AvailableReplicas > 0
+	}
+	if !available {
+		check.Status = ClusterPreflightStatusFailed
+		check.Message = fmt.Sprintf("no deployment of namespace %s is available, install the yatai-deployment component", commonconsts.DefaultKubeNamespaceYataiDeploymentComponent)
+	}
+	return []*ClusterPreflightCheck{check}
+}
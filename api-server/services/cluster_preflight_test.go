@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// countingDiscovery counts the discoveries of the api groups, the fake builds them from its resources
+type countingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	serverGroupsCalls int
+}
+
+func (d *countingDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	d.serverGroupsCalls++
+	return d.FakeDiscovery.ServerGroups()
+}
+
+func newPreflightTestDiscovery(groupVersions map[string]string) *countingDiscovery {
+	fakeDiscovery := kubefake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	for groupVersion, resource := range groupVersions {
+		fakeDiscovery.Resources = append(fakeDiscovery.Resources, &metav1.APIResourceList{
+			GroupVersion: groupVersion,
+			APIResources: []metav1.APIResource{
+				{Name: resource},
+			},
+		})
+	}
+	return &countingDiscovery{FakeDiscovery: fakeDiscovery}
+}
+
+func TestClusterPreflightRejectsEmptyKubeConfig(t *testing.T) {
+	for _, kubeConfig := range []string{"", " \n"} {
+		_, err := ClusterPreflightService.Check(context.Background(), ClusterPreflightOption{
+			KubeConfig: kubeConfig,
+		})
+		if err == nil {
+			t.Errorf("expected the kube config %q to be rejected instead of falling back to the in-cluster config", kubeConfig)
+		}
+	}
+}
+
+func TestClusterPreflightCheckCRDs(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		groupVersions map[string]string
+		expected      []ClusterPreflightStatus
+	}{
+		{
+			name: "v2alpha1",
+			groupVersions: map[string]string{
+				"serving.yatai.ai/v2alpha1":   "bentodeployments",
+				"resources.yatai.ai/v1alpha1": "bentorequests",
+			},
+			expected: []ClusterPreflightStatus{ClusterPreflightStatusPassed, ClusterPreflightStatusPassed},
+		},
+		{
+			name: "v2alpha1 without bento requests",
+			groupVersions: map[string]string{
+				"serving.yatai.ai/v2alpha1": "bentodeployments",
+			},
+			expected: []ClusterPreflightStatus{ClusterPreflightStatusPassed, ClusterPreflightStatusFailed},
+		},
+		{
+			name: "v1alpha3",
+			groupVersions: map[string]string{
+				"serving.yatai.ai/v1alpha3": "bentodeployments",
+			},
+			expected: []ClusterPreflightStatus{ClusterPreflightStatusWarning, ClusterPreflightStatusPassed},
+		},
+		{
+			name:     "not installed",
+			expected: []ClusterPreflightStatus{ClusterPreflightStatusFailed, ClusterPreflightStatusPassed},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			discoveryCli := newPreflightTestDiscovery(c.groupVersions)
+			crds := ClusterPreflightService.checkCRDs(discoveryCli)
+			if discoveryCli.serverGroupsCalls != 1 {
+				t.Errorf("expected the api groups to be discovered once, got %d", discoveryCli.serverGroupsCalls)
+			}
+			if len(crds) != len(c.expected) {
+				t.Fatalf("expected %d crds, got %d", len(c.expected), len(crds))
+			}
+			for idx, crd := range crds {
+				if crd.Status != c.expected[idx] {
+					t.Errorf("%s: expected status %s, got %s: %s", crd.Name, c.expected[idx], crd.Status, crd.Message)
+				}
+			}
+		})
+	}
+}
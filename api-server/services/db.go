@@ -144,8 +144,22 @@ func StartTransaction(ctx context.Context) (*gorm.DB, context.Context, func(erro
 }
 
 type TransactionDBWrapper struct {
-	orig     *gorm.DB
-	released bool
+	orig        *gorm.DB
+	released    bool
+	afterCommit []func()
+}
+
+// runAfterCommit runs fn once the transaction of the ctx is committed, or right away when the ctx is not in a transaction
+func runAfterCommit(ctx context.Context, fn func()) {
+	session_ := ctx.Value(DbSessionKey)
+	if session_ != nil {
+		db_ := session_.(*TransactionDBWrapper)
+		if !db_.released {
+			db_.afterCommit = append(db_.afterCommit, fn)
+			return
+		}
+	}
+	fn()
 }
 
 // nolint: unparam
@@ -183,8 +197,10 @@ func startTransaction(ctx context.Context) (*gorm.DB, context.Context, func(erro
 			panic(p)
 		} else if err != nil {
 			tx.Rollback()
-		} else {
-			tx.Commit()
+		} else if tx.Commit().Error == nil {
+			for _, fn := range db_.afterCommit {
+				fn()
+			}
 		}
 	}, nil
 }
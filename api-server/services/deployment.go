@@ -60,7 +60,9 @@ type UpdateDeploymentOption struct {
 }
 
 type UpdateDeploymentStatusOption struct {
-	Status    *modelschemas.DeploymentStatus
+	Status *modelschemas.DeploymentStatus
+	// Evidence is recorded with the transition when the status changes
+	Evidence  *models.DeploymentStatusEvidence
 	SyncingAt **time.Time
 	UpdatedAt **time.Time
 	Labels    *modelschemas.LabelItemsSchema
@@ -156,6 +158,10 @@ func (s *deploymentService) Update(ctx context.Context, b *models.Deployment, op
 	if err != nil {
 		return nil, err
 	}
+	err = NotificationService.Publish(ctx, modelschemas.ResourceTypeDeployment, b.Uid)
+	if err != nil {
+		return nil, err
+	}
 	if opt.Labels != nil {
 		cluster, err := ClusterService.GetAssociatedCluster(ctx, b)
 		if err != nil {
@@ -283,31 +289,96 @@ func (s *deploymentService) List(ctx context.Context, opt ListDeploymentOption)
 	return deployments, uint(total), err
 }
 
-func (s *deploymentService) ListUnsynced(ctx context.Context) ([]*models.Deployment, error) {
+// ListUnsynced lists the deployments whose status was not synced within the interval
+func (s *deploymentService) ListUnsynced(ctx context.Context, interval time.Duration) ([]*models.Deployment, error) {
 	q := getBaseQuery(ctx, s)
 	now := time.Now()
-	t := now.Add(-time.Minute)
+	t := now.Add(-interval)
 	q = q.Where("status_syncing_at is null or status_syncing_at < ? or status_updated_at is null or status_updated_at < ?", t, t)
 	envs := make([]*models.Deployment, 0)
 	err := q.Order("id DESC").Find(&envs).Error
 	return envs, err
 }
 
+type ClusterKubeNamespace struct {
+	ClusterId     uint
+	KubeNamespace string
+}
+
+func (s *deploymentService) ListClusterKubeNamespaces(ctx context.Context) ([]*ClusterKubeNamespace, error) {
+	namespaces := make([]*ClusterKubeNamespace, 0)
+	err := s.getBaseDB(ctx).Distinct("cluster_id", "kube_namespace").Find(&namespaces).Error
+	return namespaces, err
+}
+
+// UpdateStatus only changes the status if it is still the one read in the transaction,
+// so that a transition is recorded once when the replicas and the syncs race on the same change
 func (s *deploymentService) UpdateStatus(ctx context.Context, deployment *models.Deployment, opt UpdateDeploymentStatusOption) (*models.Deployment, error) {
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
 	updater := map[string]interface{}{}
+	var oldStatus *modelschemas.DeploymentStatus
 	if opt.Status != nil {
-		deployment.Status = *opt.Status
+		// the in memory status can be stale when another replica synced it
+		oldStatuses := make([]modelschemas.DeploymentStatus, 0, 1)
+		err = db.Model(&models.Deployment{}).Where("id = ?", deployment.ID).Pluck("status", &oldStatuses).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment status")
+		}
+		if len(oldStatuses) > 0 {
+			oldStatus = &oldStatuses[0]
+		}
 		updater["status"] = *opt.Status
 	}
 	if opt.SyncingAt != nil {
-		deployment.StatusSyncingAt = *opt.SyncingAt
 		updater["status_syncing_at"] = *opt.SyncingAt
 	}
 	if opt.UpdatedAt != nil {
-		deployment.StatusUpdatedAt = *opt.UpdatedAt
 		updater["status_updated_at"] = *opt.UpdatedAt
 	}
-	err := s.getBaseDB(ctx).Where("id = ?", deployment.ID).Updates(updater).Error
+	query := db.Model(&models.Deployment{}).Where("id = ?", deployment.ID)
+	if oldStatus != nil {
+		query = query.Where("status = ?", *oldStatus)
+	}
+	result := query.Updates(updater)
+	if result.Error != nil {
+		err = result.Error
+		return nil, err
+	}
+	if oldStatus != nil && result.RowsAffected != 1 {
+		// the status was changed by someone else in between, the change and its transition are theirs
+		var current *models.Deployment
+		current, err = s.Get(ctx, deployment.ID)
+		return current, err
+	}
+
+	if opt.Status != nil {
+		deployment.Status = *opt.Status
+	}
+	if opt.SyncingAt != nil {
+		deployment.StatusSyncingAt = *opt.SyncingAt
+	}
+	if opt.UpdatedAt != nil {
+		deployment.StatusUpdatedAt = *opt.UpdatedAt
+	}
+	if oldStatus != nil && *oldStatus != *opt.Status {
+		_, err = DeploymentStatusTransitionService.Create(ctx, CreateDeploymentStatusTransitionOption{
+			Deployment: deployment,
+			FromStatus: *oldStatus,
+			ToStatus:   *opt.Status,
+			Evidence:   opt.Evidence,
+		})
+		if err != nil {
+			err = errors.Wrap(err, "create deployment status transition")
+			return nil, err
+		}
+	}
+	err = NotificationService.Publish(ctx, modelschemas.ResourceTypeDeployment, deployment.Uid)
 	return deployment, err
 }
 
@@ -500,7 +571,7 @@ func (s *deploymentService) SyncStatus(ctx context.Context, d *models.Deployment
 	if err != nil {
 		return d.Status, err
 	}
-	currentStatus, err := s.getStatusFromK8s(ctx, d)
+	currentStatus, pods, err := s.getStatusFromK8s(ctx, d)
 	if err != nil {
 		return d.Status, err
 	}
@@ -508,6 +579,7 @@ func (s *deploymentService) SyncStatus(ctx context.Context, d *models.Deployment
 	nowPtr = &now
 	_, err = s.UpdateStatus(ctx, d, UpdateDeploymentStatusOption{
 		Status:    &currentStatus,
+		Evidence:  makeDeploymentStatusEvidence(pods),
 		UpdatedAt: &nowPtr,
 	})
 	if err != nil {
@@ -516,19 +588,19 @@ func (s *deploymentService) SyncStatus(ctx context.Context, d *models.Deployment
 	return currentStatus, nil
 }
 
-func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Deployment) (modelschemas.DeploymentStatus, error) {
+func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Deployment) (modelschemas.DeploymentStatus, []*models.KubePodWithStatus, error) {
 	defaultStatus := modelschemas.DeploymentStatusUnknown
 
 	cluster, err := ClusterService.GetAssociatedCluster(ctx, d)
 	if err != nil {
-		return defaultStatus, errors.Wrapf(err, "get associated cluster for deployment %d", d.ID)
+		return defaultStatus, nil, errors.Wrapf(err, "get associated cluster for deployment %d", d.ID)
 	}
 
 	namespace := DeploymentService.GetKubeNamespace(d)
 
 	_, podLister, err := GetPodInformer(ctx, cluster, namespace)
 	if err != nil {
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	imageBuilderPodNamespace := namespace
@@ -536,17 +608,17 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 	yataiDeploymentComponent, err := YataiComponentService.GetByName(ctx, cluster.ID, "deployment")
 	if err != nil {
 		err = errors.Wrap(err, "get yatai component")
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	doNotHaveYataiImageBuilderComponent := strings.HasPrefix(yataiDeploymentComponent.Manifest.LatestCRDVersion, "v1alpha")
 	if doNotHaveYataiImageBuilderComponent {
-		imageBuilderPodNamespace = "yatai-builders"
+		imageBuilderPodNamespace = commonconsts.DefaultKubeNamespaceImageBuilders
 	}
 
 	_, imageBuilderPodLister, err := GetPodInformer(ctx, cluster, imageBuilderPodNamespace)
 	if err != nil {
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	imageBuilderPods := make([]*models.KubePodWithStatus, 0)
@@ -557,7 +629,7 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 		Status:       &status_,
 	})
 	if err != nil {
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	deploymentRevisionIds := make([]uint, 0, len(deploymentRevisions))
@@ -569,19 +641,19 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 		DeploymentRevisionIds: &deploymentRevisionIds,
 	})
 	if err != nil {
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	for _, deploymentTarget := range deploymentTargets {
 		var bento *models.Bento
 		bento, err = BentoService.GetAssociatedBento(ctx, deploymentTarget)
 		if err != nil {
-			return defaultStatus, err
+			return defaultStatus, nil, err
 		}
 		var bentoRepository *models.BentoRepository
 		bentoRepository, err = BentoRepositoryService.GetAssociatedBentoRepository(ctx, bento)
 		if err != nil {
-			return defaultStatus, err
+			return defaultStatus, nil, err
 		}
 		var imageBuilderPodsSelector labels.Selector
 		if doNotHaveYataiImageBuilderComponent {
@@ -590,12 +662,12 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 			imageBuilderPodsSelector, err = labels.Parse(fmt.Sprintf("%s=true,%s=%s,%s=%s", commonconsts.KubeLabelIsBentoImageBuilder, commonconsts.KubeLabelYataiBentoRepository, bentoRepository.Name, commonconsts.KubeLabelYataiBento, bento.Version))
 		}
 		if err != nil {
-			return defaultStatus, err
+			return defaultStatus, nil, err
 		}
 		var pods_ []*models.KubePodWithStatus
 		pods_, err = KubePodService.ListPodsBySelector(ctx, cluster, namespace, imageBuilderPodLister, imageBuilderPodsSelector)
 		if err != nil {
-			return defaultStatus, err
+			return defaultStatus, nil, err
 		}
 		imageBuilderPods = append(imageBuilderPods, pods_...)
 	}
@@ -603,37 +675,37 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 	if len(imageBuilderPods) != 0 {
 		for _, imageBuilderPod := range imageBuilderPods {
 			if imageBuilderPod.Status.Status == modelschemas.KubePodActualStatusPending || imageBuilderPod.Status.Status == modelschemas.KubePodActualStatusRunning {
-				return modelschemas.DeploymentStatusImageBuilding, nil
+				return modelschemas.DeploymentStatusImageBuilding, imageBuilderPods, nil
 			}
 			if imageBuilderPod.Status.Status == modelschemas.KubePodActualStatusFailed {
-				return modelschemas.DeploymentStatusImageBuildFailed, nil
+				return modelschemas.DeploymentStatusImageBuildFailed, imageBuilderPods, nil
 			}
 			if imageBuilderPod.Status.Status == modelschemas.KubePodActualStatusUnknown {
-				return modelschemas.DeploymentStatusImageBuildFailed, nil
+				return modelschemas.DeploymentStatusImageBuildFailed, imageBuilderPods, nil
 			}
 			if imageBuilderPod.Status.Status == modelschemas.KubePodActualStatusTerminating {
-				return modelschemas.DeploymentStatusImageBuildFailed, nil
+				return modelschemas.DeploymentStatusImageBuildFailed, imageBuilderPods, nil
 			}
 		}
 	}
 
 	pods, err := KubePodService.ListPodsByDeployment(ctx, podLister, d)
 	if err != nil {
-		return defaultStatus, err
+		return defaultStatus, nil, err
 	}
 
 	if len(pods) == 0 {
 		if d.Status == modelschemas.DeploymentStatusTerminating || d.Status == modelschemas.DeploymentStatusTerminated {
-			return modelschemas.DeploymentStatusTerminated, nil
+			return modelschemas.DeploymentStatusTerminated, pods, nil
 		}
 		if d.Status == modelschemas.DeploymentStatusDeploying {
-			return modelschemas.DeploymentStatusDeploying, nil
+			return modelschemas.DeploymentStatusDeploying, pods, nil
 		}
-		return modelschemas.DeploymentStatusNonDeployed, nil
+		return modelschemas.DeploymentStatusNonDeployed, pods, nil
 	}
 
 	if d.Status == modelschemas.DeploymentStatusTerminated {
-		return d.Status, nil
+		return d.Status, pods, nil
 	}
 
 	hasFailed := false
@@ -655,27 +727,27 @@ func (s *deploymentService) getStatusFromK8s(ctx context.Context, d *models.Depl
 
 	if d.Status == modelschemas.DeploymentStatusTerminating {
 		if !hasRunning {
-			return modelschemas.DeploymentStatusTerminated, nil
+			return modelschemas.DeploymentStatusTerminated, pods, nil
 		}
-		return d.Status, nil
+		return d.Status, pods, nil
 	}
 
 	if hasFailed && hasRunning {
 		if hasPending {
-			return modelschemas.DeploymentStatusDeploying, nil
+			return modelschemas.DeploymentStatusDeploying, pods, nil
 		}
-		return modelschemas.DeploymentStatusUnhealthy, nil
+		return modelschemas.DeploymentStatusUnhealthy, pods, nil
 	}
 
 	if hasPending {
-		return modelschemas.DeploymentStatusDeploying, nil
+		return modelschemas.DeploymentStatusDeploying, pods, nil
 	}
 
 	if hasRunning {
-		return modelschemas.DeploymentStatusRunning, nil
+		return modelschemas.DeploymentStatusRunning, pods, nil
 	}
 
-	return modelschemas.DeploymentStatusFailed, nil
+	return modelschemas.DeploymentStatusFailed, pods, nil
 }
 
 func (s *deploymentService) UpdateKubeDeployToken(ctx context.Context, deployment *models.Deployment, oldToken, newToken string) (*models.Deployment, error) {
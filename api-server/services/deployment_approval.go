@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	jujuerrors "github.com/juju/errors"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+// deploymentReviewStore reads the clusters and the approval rights, and writes and deploys the reviewed revisions,
+// the reviews are tested against an in-memory one
+type deploymentReviewStore interface {
+	getDeploymentCluster(ctx context.Context, deploymentId uint) (*models.Cluster, error)
+	canApprove(ctx context.Context, cluster *models.Cluster, user *models.User) (bool, error)
+	startTransaction(ctx context.Context) (context.Context, func(error), error)
+	updateDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision, opt UpdateDeploymentRevisionOption) (*models.DeploymentRevision, error)
+	deployDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision) error
+}
+
+type deploymentRevisionReviewStore struct{}
+
+func (deploymentRevisionReviewStore) getDeploymentCluster(ctx context.Context, deploymentId uint) (*models.Cluster, error) {
+	deployment, err := DeploymentService.Get(ctx, deploymentId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment")
+	}
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	return cluster, errors.Wrap(err, "get deployment associated cluster")
+}
+
+func (deploymentRevisionReviewStore) canApprove(ctx context.Context, cluster *models.Cluster, user *models.User) (bool, error) {
+	return DeploymentApprovalService.CanApprove(ctx, cluster, user)
+}
+
+func (deploymentRevisionReviewStore) startTransaction(ctx context.Context) (context.Context, func(error), error) {
+	_, ctx, df, err := startTransaction(ctx)
+	return ctx, df, err
+}
+
+func (deploymentRevisionReviewStore) updateDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision, opt UpdateDeploymentRevisionOption) (*models.DeploymentRevision, error) {
+	return DeploymentRevisionService.Update(ctx, deploymentRevision, opt)
+}
+
+func (deploymentRevisionReviewStore) deployDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision) error {
+	return DeploymentRevisionService.Deploy(ctx, deploymentRevision, nil, false)
+}
+
+type deploymentApprovalService struct {
+	store deploymentReviewStore
+}
+
+var DeploymentApprovalService = deploymentApprovalService{
+	store: deploymentRevisionReviewStore{},
+}
+
+// getDeploymentApproverRoles returns the member roles at least as high as the approver role
+func getDeploymentApproverRoles(approverRole modelschemas.MemberRole) []modelschemas.MemberRole {
+	switch approverRole {
+	case modelschemas.MemberRoleGuest:
+		return []modelschemas.MemberRole{modelschemas.MemberRoleGuest, modelschemas.MemberRoleDeveloper, modelschemas.MemberRoleAdmin}
+	case modelschemas.MemberRoleDeveloper:
+		return []modelschemas.MemberRole{modelschemas.MemberRoleDeveloper, modelschemas.MemberRoleAdmin}
+	default:
+		return []modelschemas.MemberRole{modelschemas.MemberRoleAdmin}
+	}
+}
+
+func (s *deploymentApprovalService) ValidateConfig(config *models.ClusterApprovalConfig) error {
+	if config == nil {
+		return nil
+	}
+	switch config.ApproverRole {
+	case "", modelschemas.MemberRoleGuest, modelschemas.MemberRoleDeveloper, modelschemas.MemberRoleAdmin:
+		return nil
+	default:
+		return errors.Errorf("invalid approver role %s", config.ApproverRole)
+	}
+}
+
+// CanApprove tells whether the user has the approver role of the cluster, the organization creator and admins always have it
+func (s *deploymentApprovalService) CanApprove(ctx context.Context, cluster *models.Cluster, user *models.User) (bool, error) {
+	org, err := OrganizationService.GetAssociatedOrganization(ctx, cluster)
+	if err != nil {
+		return false, errors.Wrap(err, "get cluster associated organization")
+	}
+	if org.CreatorId == user.ID || UserService.IsAdmin(ctx, user, org) {
+		return true, nil
+	}
+	approverRole := modelschemas.MemberRoleAdmin
+	if cluster.ApprovalConfig != nil && cluster.ApprovalConfig.ApproverRole != "" {
+		approverRole = cluster.ApprovalConfig.ApproverRole
+	}
+	return ClusterMemberService.CheckRoles(ctx, user.ID, cluster.ID, getDeploymentApproverRoles(approverRole))
+}
+
+// RequiresApproval tells whether the revisions submitted by the user to the deployment must be approved before being deployed
+func (s *deploymentApprovalService) RequiresApproval(ctx context.Context, deployment *models.Deployment, user *models.User) (bool, error) {
+	cluster, err := s.store.getDeploymentCluster(ctx, deployment.ID)
+	if err != nil {
+		return false, err
+	}
+	if cluster.ApprovalConfig == nil || !cluster.ApprovalConfig.Enabled {
+		return false, nil
+	}
+	canApprove, err := s.store.canApprove(ctx, cluster, user)
+	if err != nil {
+		return false, errors.Wrap(err, "check can approve")
+	}
+	return !canApprove, nil
+}
+
+// getPendingApprovalMessage tells that the revision waits for an approval, it is empty when the revision was deployed
+func getPendingApprovalMessage(deploymentRevision *models.DeploymentRevision) string {
+	if deploymentRevision.Status != models.DeploymentRevisionStatusPending {
+		return ""
+	}
+	return fmt.Sprintf("deployment revision %s is pending approval", deploymentRevision.Uid)
+}
+
+// Submit marks the other pending revisions of the deployment inactive, only the latest submitted revision can be approved
+func (s *deploymentApprovalService) Submit(ctx context.Context, deploymentRevision *models.DeploymentRevision) error {
+	status := models.DeploymentRevisionStatusPending
+	pendingDeploymentRevisions, _, err := DeploymentRevisionService.List(ctx, ListDeploymentRevisionOption{
+		DeploymentId: utils.UintPtr(deploymentRevision.DeploymentId),
+		Status:       &status,
+	})
+	if err != nil {
+		return errors.Wrap(err, "list pending deployment revisions")
+	}
+	for _, pendingDeploymentRevision := range pendingDeploymentRevisions {
+		if pendingDeploymentRevision.ID == deploymentRevision.ID {
+			continue
+		}
+		_, err = DeploymentRevisionService.Update(ctx, pendingDeploymentRevision, UpdateDeploymentRevisionOption{
+			Status: modelschemas.DeploymentRevisionStatusPtr(modelschemas.DeploymentRevisionStatusInactive),
+		})
+		if err != nil {
+			return errors.Wrap(err, "update pending deployment revision")
+		}
+	}
+	return nil
+}
+
+func (s *deploymentApprovalService) checkReviewer(ctx context.Context, deploymentRevision *models.DeploymentRevision, reviewer *models.User) error {
+	if deploymentRevision.Status != models.DeploymentRevisionStatusPending {
+		return errors.Errorf("deployment revision %s is not pending", deploymentRevision.Uid)
+	}
+	if deploymentRevision.CreatorId == reviewer.ID {
+		return jujuerrors.Unauthorizedf("user %s cannot review its own deployment revision %s", reviewer.Name, deploymentRevision.Uid)
+	}
+	cluster, err := s.store.getDeploymentCluster(ctx, deploymentRevision.DeploymentId)
+	if err != nil {
+		return err
+	}
+	canApprove, err := s.store.canApprove(ctx, cluster, reviewer)
+	if err != nil {
+		return errors.Wrap(err, "check can approve")
+	}
+	if !canApprove {
+		return jujuerrors.Unauthorizedf("user %s cannot review the deployment revisions of cluster %s", reviewer.Name, cluster.Name)
+	}
+	return nil
+}
+
+// review moves the revision from pending to the status, it fails with consts.ErrConflict when it was reviewed or replaced meanwhile
+func (s *deploymentApprovalService) review(ctx context.Context, deploymentRevision *models.DeploymentRevision, reviewer *models.User, status modelschemas.DeploymentRevisionStatus, comment string) (*models.DeploymentRevision, error) {
+	now := time.Now()
+	return s.store.updateDeploymentRevision(ctx, deploymentRevision, UpdateDeploymentRevisionOption{
+		FromStatus:    modelschemas.DeploymentRevisionStatusPtr(models.DeploymentRevisionStatusPending),
+		Status:        &status,
+		ReviewerId:    utils.UintPtr(reviewer.ID),
+		ReviewedAt:    &now,
+		ReviewComment: &comment,
+	})
+}
+
+// Approve activates and deploys the pending revision,
+// the reviewer must have the approver role of the cluster and cannot be the creator of the revision
+func (s *deploymentApprovalService) Approve(ctx context.Context, deploymentRevision *models.DeploymentRevision, reviewer *models.User, comment string) (*models.DeploymentRevision, error) {
+	err := s.checkReviewer(ctx, deploymentRevision, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, df, err := s.store.startTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	deploymentRevision, err = s.review(ctx, deploymentRevision, reviewer, modelschemas.DeploymentRevisionStatusActive, comment)
+	if err != nil {
+		return nil, errors.Wrap(err, "update deployment revision")
+	}
+	err = s.store.deployDeploymentRevision(ctx, deploymentRevision)
+	if err != nil {
+		return nil, errors.Wrap(err, "deploy deployment revision")
+	}
+	return deploymentRevision, nil
+}
+
+// Reject closes the pending revision without deploying it, the reviewer has the same requirements as for Approve
+func (s *deploymentApprovalService) Reject(ctx context.Context, deploymentRevision *models.DeploymentRevision, reviewer *models.User, comment string) (*models.DeploymentRevision, error) {
+	err := s.checkReviewer(ctx, deploymentRevision, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	deploymentRevision, err = s.review(ctx, deploymentRevision, reviewer, models.DeploymentRevisionStatusRejected, comment)
+	if err != nil {
+		return nil, errors.Wrap(err, "update deployment revision")
+	}
+	return deploymentRevision, nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	jujuerrors "github.com/juju/errors"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+)
+
+// memoryDeploymentReviewStore keeps the revision statuses in memory like the database does,
+// the updates from a status fail when the stored status is another one
+type memoryDeploymentReviewStore struct {
+	cluster   *models.Cluster
+	approvers map[uint]bool
+	statuses  map[uint]modelschemas.DeploymentRevisionStatus
+	deployed  []uint
+}
+
+func (s *memoryDeploymentReviewStore) getDeploymentCluster(ctx context.Context, deploymentId uint) (*models.Cluster, error) {
+	return s.cluster, nil
+}
+
+func (s *memoryDeploymentReviewStore) canApprove(ctx context.Context, cluster *models.Cluster, user *models.User) (bool, error) {
+	return s.approvers[user.ID], nil
+}
+
+func (s *memoryDeploymentReviewStore) startTransaction(ctx context.Context) (context.Context, func(error), error) {
+	return ctx, func(error) {}, nil
+}
+
+func (s *memoryDeploymentReviewStore) updateDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision, opt UpdateDeploymentRevisionOption) (*models.DeploymentRevision, error) {
+	if opt.FromStatus != nil && s.statuses[deploymentRevision.ID] != *opt.FromStatus {
+		return nil, errors.Wrapf(consts.ErrConflict, "deployment revision %s is no longer %s", deploymentRevision.Uid, *opt.FromStatus)
+	}
+	if opt.Status != nil {
+		s.statuses[deploymentRevision.ID] = *opt.Status
+		deploymentRevision.Status = *opt.Status
+	}
+	if opt.ReviewerId != nil {
+		deploymentRevision.ReviewerId = opt.ReviewerId
+	}
+	return deploymentRevision, nil
+}
+
+func (s *memoryDeploymentReviewStore) deployDeploymentRevision(ctx context.Context, deploymentRevision *models.DeploymentRevision) error {
+	s.deployed = append(s.deployed, deploymentRevision.ID)
+	return nil
+}
+
+const (
+	approvalTestCreatorId  uint = 1
+	approvalTestApproverId uint = 2
+	approvalTestGuestId    uint = 3
+)
+
+func newApprovalTestService(enabled bool) (*deploymentApprovalService, *memoryDeploymentReviewStore) {
+	store := &memoryDeploymentReviewStore{
+		cluster: &models.Cluster{
+			ApprovalConfig: &models.ClusterApprovalConfig{
+				Enabled: enabled,
+			},
+		},
+		approvers: map[uint]bool{
+			approvalTestCreatorId:  true,
+			approvalTestApproverId: true,
+		},
+		statuses: make(map[uint]modelschemas.DeploymentRevisionStatus),
+	}
+	return &deploymentApprovalService{store: store}, store
+}
+
+func newApprovalTestRevision(store *memoryDeploymentReviewStore, id uint) *models.DeploymentRevision {
+	deploymentRevision := &models.DeploymentRevision{
+		BaseModel: models.BaseModel{
+			Uid: "rev",
+		},
+		Status: models.DeploymentRevisionStatusPending,
+	}
+	deploymentRevision.ID = id
+	deploymentRevision.CreatorId = approvalTestCreatorId
+	store.statuses[id] = models.DeploymentRevisionStatusPending
+	return deploymentRevision
+}
+
+func newApprovalTestUser(id uint) *models.User {
+	user := &models.User{}
+	user.ID = id
+	return user
+}
+
+func TestRequiresApproval(t *testing.T) {
+	deployment := &models.Deployment{}
+	for _, c := range []struct {
+		enabled  bool
+		userId   uint
+		expected bool
+	}{
+		{enabled: false, userId: approvalTestGuestId, expected: false},
+		{enabled: true, userId: approvalTestGuestId, expected: true},
+		{enabled: true, userId: approvalTestApproverId, expected: false},
+	} {
+		s, _ := newApprovalTestService(c.enabled)
+		requiresApproval, err := s.RequiresApproval(context.Background(), deployment, newApprovalTestUser(c.userId))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requiresApproval != c.expected {
+			t.Errorf("enabled %v, user %d: expected requires approval %v, got %v", c.enabled, c.userId, c.expected, requiresApproval)
+		}
+	}
+}
+
+func TestApprove(t *testing.T) {
+	s, store := newApprovalTestService(true)
+
+	// the creator cannot approve its own revision and the reviewer needs the approver role
+	for _, userId := range []uint{approvalTestCreatorId, approvalTestGuestId} {
+		_, err := s.Approve(context.Background(), newApprovalTestRevision(store, 1), newApprovalTestUser(userId), "")
+		if !jujuerrors.IsUnauthorized(err) {
+			t.Errorf("user %d: expected an unauthorized error, got %v", userId, err)
+		}
+	}
+	if len(store.deployed) != 0 {
+		t.Fatalf("expected nothing to be deployed without a valid reviewer, got %v", store.deployed)
+	}
+
+	deploymentRevision, err := s.Approve(context.Background(), newApprovalTestRevision(store, 1), newApprovalTestUser(approvalTestApproverId), "lgtm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deploymentRevision.Status != modelschemas.DeploymentRevisionStatusActive || *deploymentRevision.ReviewerId != approvalTestApproverId {
+		t.Errorf("expected the revision to be activated by the approver, got %+v", deploymentRevision)
+	}
+	if len(store.deployed) != 1 || store.deployed[0] != 1 {
+		t.Errorf("expected the approved revision to be deployed once, got %v", store.deployed)
+	}
+}
+
+func TestReject(t *testing.T) {
+	s, store := newApprovalTestService(true)
+
+	deploymentRevision, err := s.Reject(context.Background(), newApprovalTestRevision(store, 1), newApprovalTestUser(approvalTestApproverId), "no")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deploymentRevision.Status != models.DeploymentRevisionStatusRejected {
+		t.Errorf("expected the revision to be rejected, got %s", deploymentRevision.Status)
+	}
+
+	// a rejected revision cannot be approved afterwards
+	_, err = s.Approve(context.Background(), deploymentRevision, newApprovalTestUser(approvalTestApproverId), "")
+	if err == nil {
+		t.Error("expected the approval of a rejected revision to fail")
+	}
+
+	// nor by a reviewer who loaded it while it was still pending
+	stale := newApprovalTestRevision(store, 2)
+	_, err = s.Reject(context.Background(), stale, newApprovalTestUser(approvalTestApproverId), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.Status = models.DeploymentRevisionStatusPending
+	_, err = s.Approve(context.Background(), stale, newApprovalTestUser(approvalTestApproverId), "")
+	if !errors.Is(err, consts.ErrConflict) {
+		t.Errorf("expected a conflict when approving a revision rejected meanwhile, got %v", err)
+	}
+
+	if len(store.deployed) != 0 {
+		t.Errorf("expected the rejected revisions never to be deployed, got %v", store.deployed)
+	}
+	if store.statuses[2] != models.DeploymentRevisionStatusRejected {
+		t.Errorf("expected the revision to stay rejected, got %s", store.statuses[2])
+	}
+}
+
+func TestGetPendingApprovalMessage(t *testing.T) {
+	deploymentRevision := &models.DeploymentRevision{
+		BaseModel: models.BaseModel{
+			Uid: "abc",
+		},
+		Status: models.DeploymentRevisionStatusPending,
+	}
+	if message := getPendingApprovalMessage(deploymentRevision); message != "deployment revision abc is pending approval" {
+		t.Errorf("unexpected message for a pending revision: %q", message)
+	}
+	deploymentRevision.Status = modelschemas.DeploymentRevisionStatusActive
+	if message := getPendingApprovalMessage(deploymentRevision); message != "" {
+		t.Errorf("expected no message for a deployed revision, got %q", message)
+	}
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+
+	servingcommon "github.com/bentoml/yatai-deployment/apis/serving/common"
+	servingv2alpha1 "github.com/bentoml/yatai-deployment/apis/serving/v2alpha1"
+	servingv2alpha1client "github.com/bentoml/yatai-deployment/generated/serving/clientset/versioned/typed/serving/v2alpha1"
+
+	resourcesv1alpha1 "github.com/bentoml/yatai-image-builder/apis/resources/v1alpha1"
+	resourcesv1alpha1client "github.com/bentoml/yatai-image-builder/generated/resources/clientset/versioned/typed/resources/v1alpha1"
+)
+
+type DeploymentTargetDryRunResult struct {
+	Type  modelschemas.DeploymentTargetType `json:"type"`
+	Bento string                            `json:"bento"`
+	// BentoDeployment and BentoRequest are returned by the dry run of the api server, they are the rendered ones when the dry run failed
+	BentoDeployment  *servingv2alpha1.BentoDeployment `json:"bento_deployment"`
+	BentoRequest     *resourcesv1alpha1.BentoRequest  `json:"bento_request"`
+	ValidationErrors []string                         `json:"validation_errors"`
+	QuotaErrors      []string                         `json:"quota_errors"`
+	Warnings         []string                         `json:"warnings"`
+}
+
+type DeploymentDryRunResult struct {
+	Valid   bool                            `json:"valid"`
+	Targets []*DeploymentTargetDryRunResult `json:"targets"`
+	// QuotaErrors are the resource quotas exceeded by the requests of all the targets together
+	QuotaErrors []string `json:"quota_errors"`
+}
+
+// DryRun renders the BentoDeployment CRs of the targets and applies them with a server side dry run, nothing is persisted.
+// The targets do not need to be saved, but their deployment and bento caches must be set when they are not.
+func (s *deploymentService) DryRun(ctx context.Context, deployment *models.Deployment, deploymentTargets []*models.DeploymentTarget) (*DeploymentDryRunResult, error) {
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get associated cluster")
+	}
+	yataiDeploymentComp, err := YataiComponentService.GetByName(ctx, cluster.ID, string(modelschemas.YataiComponentNameDeployment))
+	if err != nil {
+		return nil, errors.Wrap(err, "get yatai deployment component")
+	}
+	if yataiDeploymentComp.Manifest == nil || yataiDeploymentComp.Manifest.LatestCRDVersion != "v2alpha1" {
+		return nil, errors.New("dry run requires the v2alpha1 BentoDeployment CRD")
+	}
+	cli, err := s.GetKubeBentoDeploymentV2alpha1Cli(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube bento deployment cli")
+	}
+	bentoRequestCli, err := s.GetKubeBentoRequestV1alpha1Cli(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube bento request cli")
+	}
+	kubeCli, _, err := ClusterService.GetKubeCliSet(ctx, cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube cli set")
+	}
+	return s.dryRun(ctx, deploymentTargets, cli, bentoRequestCli, kubeCli.CoreV1().ResourceQuotas(s.GetKubeNamespace(deployment)))
+}
+
+func (s *deploymentService) dryRun(ctx context.Context, deploymentTargets []*models.DeploymentTarget, cli servingv2alpha1client.BentoDeploymentInterface, bentoRequestCli resourcesv1alpha1client.BentoRequestInterface, quotaCli corev1client.ResourceQuotaInterface) (*DeploymentDryRunResult, error) {
+	quotas, err := quotaCli.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list kube resource quotas")
+	}
+	res := &DeploymentDryRunResult{
+		Valid:   true,
+		Targets: make([]*DeploymentTargetDryRunResult, 0, len(deploymentTargets)),
+	}
+	requestsList := make([]corev1.ResourceList, 0, len(deploymentTargets))
+	releasedList := make([]corev1.ResourceList, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		bento, err := BentoService.GetAssociatedBento(ctx, deploymentTarget)
+		if err != nil {
+			return nil, errors.Wrap(err, "get associated bento")
+		}
+		tag, err := BentoService.GetTag(ctx, bento)
+		if err != nil {
+			return nil, errors.Wrap(err, "get bento tag")
+		}
+		result := &DeploymentTargetDryRunResult{
+			Type:             deploymentTarget.Type,
+			Bento:            string(tag),
+			ValidationErrors: make([]string, 0),
+			QuotaErrors:      make([]string, 0),
+			Warnings:         make([]string, 0),
+		}
+		res.Targets = append(res.Targets, result)
+		result.ValidationErrors = append(result.ValidationErrors, validateDeploymentTargetHPAConfs(deploymentTarget.Config)...)
+
+		kubeBentoDeployment, bentoRequest, err := KubeBentoDeploymentService.transformToBentoDeploymentV2alpha1(ctx, deploymentTarget)
+		if err != nil {
+			result.ValidationErrors = append(result.ValidationErrors, err.Error())
+			res.Valid = false
+			continue
+		}
+		requests, released, err := dryRunKubeBentoDeploymentV2alpha1(ctx, deploymentTarget, kubeBentoDeployment, bentoRequest, cli, bentoRequestCli, result)
+		if err != nil {
+			return nil, err
+		}
+		requestsList = append(requestsList, requests)
+		releasedList = append(releasedList, released)
+		if len(result.ValidationErrors) > 0 || len(result.QuotaErrors) > 0 {
+			res.Valid = false
+		}
+	}
+	// the targets are deployed together, a quota must hold the requests of all of them
+	res.QuotaErrors = checkKubeResourceQuotas(quotas.Items, sumKubeResourceLists(requestsList), sumKubeResourceLists(releasedList))
+	if len(res.QuotaErrors) > 0 {
+		res.Valid = false
+	}
+	return res, nil
+}
+
+// dryRunKubeBentoDeploymentV2alpha1 validates the rendered CRs of a target and collects the problems into the result,
+// it returns the resources requested by the target and the ones released by its live CR for the quota check
+func dryRunKubeBentoDeploymentV2alpha1(ctx context.Context, deploymentTarget *models.DeploymentTarget, kubeBentoDeployment *servingv2alpha1.BentoDeployment, bentoRequest *resourcesv1alpha1.BentoRequest, cli servingv2alpha1client.BentoDeploymentInterface, bentoRequestCli resourcesv1alpha1client.BentoRequestInterface, result *DeploymentTargetDryRunResult) (requests, released corev1.ResourceList, err error) {
+	result.BentoDeployment = kubeBentoDeployment
+	result.BentoRequest = bentoRequest
+	result.ValidationErrors = append(result.ValidationErrors, validateBentoDeploymentV2alpha1Spec(&kubeBentoDeployment.Spec)...)
+	// the requests are computed before the merge with the live CR, which keeps its autoscaling policy but not its replicas
+	requests = getBentoDeploymentV2alpha1ResourceRequests(&kubeBentoDeployment.Spec)
+	oldKubeBentoDeployment, err := dryRunApplyV2alpha1(ctx, deploymentTarget, cli, bentoRequestCli, kubeBentoDeployment, bentoRequest, result)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the pods of the live CR are already counted in the usage of the quotas, they are replaced by the new ones
+	if oldKubeBentoDeployment != nil {
+		released = getBentoDeploymentV2alpha1ResourceRequests(&oldKubeBentoDeployment.Spec)
+	}
+	return requests, released, nil
+}
+
+// dryRunApplyV2alpha1 creates or updates the CRs like DeployV2alpha1 does, but with a server side dry run,
+// the live BentoDeployment is returned when there is one
+func dryRunApplyV2alpha1(ctx context.Context, deploymentTarget *models.DeploymentTarget, cli servingv2alpha1client.BentoDeploymentInterface, bentoRequestCli resourcesv1alpha1client.BentoRequestInterface, kubeBentoDeployment *servingv2alpha1.BentoDeployment, bentoRequest *resourcesv1alpha1.BentoRequest, result *DeploymentTargetDryRunResult) (*servingv2alpha1.BentoDeployment, error) {
+	dryRun := []string{metav1.DryRunAll}
+
+	var appliedBentoRequest *resourcesv1alpha1.BentoRequest
+	oldBentoRequest, err := bentoRequestCli.Get(ctx, bentoRequest.Name, metav1.GetOptions{})
+	isNotFound := apierrors.IsNotFound(err)
+	if err != nil && !isNotFound {
+		return nil, errors.Wrap(err, "get kube bento request")
+	}
+	if isNotFound {
+		appliedBentoRequest, err = bentoRequestCli.Create(ctx, bentoRequest, metav1.CreateOptions{DryRun: dryRun})
+	} else {
+		bentoRequest.SetResourceVersion(oldBentoRequest.GetResourceVersion())
+		appliedBentoRequest, err = bentoRequestCli.Update(ctx, bentoRequest, metav1.UpdateOptions{DryRun: dryRun})
+	}
+	if err == nil {
+		result.BentoRequest = appliedBentoRequest
+	} else if err = collectDryRunError("BentoRequest", err, result); err != nil {
+		return nil, err
+	}
+
+	var appliedKubeBentoDeployment *servingv2alpha1.BentoDeployment
+	oldKubeBentoDeployment, err := cli.Get(ctx, kubeBentoDeployment.Name, metav1.GetOptions{})
+	isNotFound = apierrors.IsNotFound(err)
+	if err != nil && !isNotFound {
+		return nil, errors.Wrap(err, "get kube bento deployment")
+	}
+	var liveKubeBentoDeployment *servingv2alpha1.BentoDeployment
+	if isNotFound {
+		appliedKubeBentoDeployment, err = cli.Create(ctx, kubeBentoDeployment, metav1.CreateOptions{DryRun: dryRun})
+	} else {
+		// the merge shares the autoscaling policies of the live CR with the new one and changes their replicas
+		liveKubeBentoDeployment = oldKubeBentoDeployment.DeepCopy()
+		KubeBentoDeploymentService.mergeOldKubeBentoDeploymentV2alpha1(deploymentTarget, kubeBentoDeployment, oldKubeBentoDeployment)
+		appliedKubeBentoDeployment, err = cli.Update(ctx, kubeBentoDeployment, metav1.UpdateOptions{DryRun: dryRun})
+	}
+	if err == nil {
+		result.BentoDeployment = appliedKubeBentoDeployment
+		return liveKubeBentoDeployment, nil
+	}
+	return liveKubeBentoDeployment, collectDryRunError("BentoDeployment", err, result)
+}
+
+// collectDryRunError sorts the rejections of the dry run into the result, the other errors are returned
+func collectDryRunError(kind string, err error, result *DeploymentTargetDryRunResult) error {
+	switch {
+	case apierrors.IsInvalid(err):
+		var status apierrors.APIStatus
+		if errors.As(err, &status) && status.Status().Details != nil && len(status.Status().Details.Causes) > 0 {
+			for _, cause := range status.Status().Details.Causes {
+				result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("%s %s: %s", kind, cause.Field, cause.Message))
+			}
+			return nil
+		}
+		result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("%s: %s", kind, err.Error()))
+	case apierrors.IsBadRequest(err):
+		result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("%s: %s", kind, err.Error()))
+	case apierrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		result.QuotaErrors = append(result.QuotaErrors, fmt.Sprintf("%s: %s", kind, err.Error()))
+	case apierrors.IsNotFound(err):
+		// the namespace of a new deployment is only created when it is deployed
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s could not be validated: %s", kind, err.Error()))
+	default:
+		return errors.Wrapf(err, "dry run %s", kind)
+	}
+	return nil
+}
+
+func validateKubeResources(path string, resources *servingcommon.Resources) []string {
+	res := make([]string, 0)
+	if resources == nil {
+		return res
+	}
+	parse := func(path, value string) *resource.Quantity {
+		if value == "" {
+			return nil
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			res = append(res, fmt.Sprintf("%s: invalid quantity %q", path, value))
+			return nil
+		}
+		return &quantity
+	}
+	items := map[string]*servingcommon.ResourceItem{
+		"requests": resources.Requests,
+		"limits":   resources.Limits,
+	}
+	quantities := make(map[string]*resource.Quantity)
+	for _, itemName := range []string{"requests", "limits"} {
+		item := items[itemName]
+		if item == nil {
+			continue
+		}
+		quantities[itemName+".cpu"] = parse(fmt.Sprintf("%s.%s.cpu", path, itemName), item.CPU)
+		quantities[itemName+".memory"] = parse(fmt.Sprintf("%s.%s.memory", path, itemName), item.Memory)
+		parse(fmt.Sprintf("%s.%s.gpu", path, itemName), item.GPU)
+	}
+	for _, name := range []string{"cpu", "memory"} {
+		request, limit := quantities["requests."+name], quantities["limits."+name]
+		if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			res = append(res, fmt.Sprintf("%s: the %s request %s is greater than the limit %s", path, name, request.String(), limit.String()))
+		}
+	}
+	return res
+}
+
+func validateAutoscaling(path string, autoscaling *servingv2alpha1.Autoscaling) []string {
+	res := make([]string, 0)
+	if autoscaling == nil {
+		return res
+	}
+	if autoscaling.MinReplicas < 0 {
+		res = append(res, fmt.Sprintf("%s.minReplicas: %d is negative", path, autoscaling.MinReplicas))
+	}
+	if autoscaling.MaxReplicas < 1 {
+		res = append(res, fmt.Sprintf("%s.maxReplicas: %d should be at least 1", path, autoscaling.MaxReplicas))
+	}
+	if autoscaling.MaxReplicas < autoscaling.MinReplicas {
+		res = append(res, fmt.Sprintf("%s: maxReplicas %d is lower than minReplicas %d", path, autoscaling.MaxReplicas, autoscaling.MinReplicas))
+	}
+	return res
+}
+
+// validateDeploymentTargetHPAConfs rejects the hpa confs with no replicas, they are rendered with one replica
+// because only the sleep of the schedules scales the targets to zero
+func validateDeploymentTargetHPAConfs(config *modelschemas.DeploymentTargetConfig) []string {
+	res := make([]string, 0)
+	if config == nil {
+		return res
+	}
+	if isHPAConfScaledToZero(config.HPAConf) {
+		res = append(res, "hpa_conf.max_replicas: 0 should be at least 1")
+	}
+	for name, runner := range config.Runners {
+		if isHPAConfScaledToZero(runner.HPAConf) {
+			res = append(res, fmt.Sprintf("runners[%s].hpa_conf.max_replicas: 0 should be at least 1", name))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// validateBentoDeploymentV2alpha1Spec checks what the CRD schema does not, the quantities and the replicas bounds
+func validateBentoDeploymentV2alpha1Spec(spec *servingv2alpha1.BentoDeploymentSpec) []string {
+	res := validateKubeResources("resources", spec.Resources)
+	res = append(res, validateAutoscaling("autoscaling", spec.Autoscaling)...)
+	for _, runner := range spec.Runners {
+		res = append(res, validateKubeResources(fmt.Sprintf("runners[%s].resources", runner.Name), runner.Resources)...)
+		res = append(res, validateAutoscaling(fmt.Sprintf("runners[%s].autoscaling", runner.Name), runner.Autoscaling)...)
+	}
+	return res
+}
+
+// getBentoDeploymentV2alpha1ResourceRequests sums the resources of the minimum replicas of the api server and the runners,
+// keyed by the names used by the resource quotas, the invalid quantities are skipped
+func getBentoDeploymentV2alpha1ResourceRequests(spec *servingv2alpha1.BentoDeploymentSpec) corev1.ResourceList {
+	res := corev1.ResourceList{}
+	add := func(name corev1.ResourceName, value string, replicas int32) {
+		if value == "" {
+			return
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return
+		}
+		total := res[name]
+		for i := int32(0); i < replicas; i++ {
+			total.Add(quantity)
+		}
+		res[name] = total
+	}
+	addComponent := func(resources *servingcommon.Resources, autoscaling *servingv2alpha1.Autoscaling) {
+		replicas := int32(1)
+		if autoscaling != nil {
+			replicas = autoscaling.MinReplicas
+		}
+		pods := res[corev1.ResourcePods]
+		pods.Add(*resource.NewQuantity(int64(replicas), resource.DecimalSI))
+		res[corev1.ResourcePods] = pods
+		if resources == nil {
+			return
+		}
+		if resources.Requests != nil {
+			add(corev1.ResourceRequestsCPU, resources.Requests.CPU, replicas)
+			add(corev1.ResourceRequestsMemory, resources.Requests.Memory, replicas)
+			add(corev1.ResourceName("requests."+commonconsts.KubeResourceGPUNvidia), resources.Requests.GPU, replicas)
+		}
+		if resources.Limits != nil {
+			add(corev1.ResourceLimitsCPU, resources.Limits.CPU, replicas)
+			add(corev1.ResourceLimitsMemory, resources.Limits.Memory, replicas)
+		}
+	}
+	addComponent(spec.Resources, spec.Autoscaling)
+	for _, runner := range spec.Runners {
+		addComponent(runner.Resources, runner.Autoscaling)
+	}
+	// the quotas on cpu and memory are quotas on the requests
+	if cpu, ok := res[corev1.ResourceRequestsCPU]; ok {
+		res[corev1.ResourceCPU] = cpu.DeepCopy()
+	}
+	if memory, ok := res[corev1.ResourceRequestsMemory]; ok {
+		res[corev1.ResourceMemory] = memory.DeepCopy()
+	}
+	return res
+}
+
+// sumKubeResourceLists adds up the resource lists by resource name
+func sumKubeResourceLists(resourceLists []corev1.ResourceList) corev1.ResourceList {
+	res := corev1.ResourceList{}
+	for _, resourceList := range resourceLists {
+		for name, quantity := range resourceList {
+			total := res[name]
+			total.Add(quantity)
+			res[name] = total
+		}
+	}
+	return res
+}
+
+// checkKubeResourceQuotas reports the quotas which the requests would exceed on top of the current usage,
+// the released resources are the requests of the live CR which are counted in the usage and replaced by the requests
+func checkKubeResourceQuotas(quotas []corev1.ResourceQuota, requests, released corev1.ResourceList) []string {
+	res := make([]string, 0)
+	for _, quota := range quotas {
+		hard := quota.Status.Hard
+		if len(hard) == 0 {
+			hard = quota.Spec.Hard
+		}
+		names := make([]string, 0, len(hard))
+		for name := range hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name_ := range names {
+			name := corev1.ResourceName(name_)
+			request, ok := requests[name]
+			if !ok {
+				continue
+			}
+			limit := hard[name]
+			used := quota.Status.Used[name]
+			if release, ok := released[name]; ok {
+				used = used.DeepCopy()
+				used.Sub(release)
+				if used.Sign() < 0 {
+					used = resource.Quantity{Format: used.Format}
+				}
+			}
+			total := used.DeepCopy()
+			total.Add(request)
+			if total.Cmp(limit) > 0 {
+				res = append(res, fmt.Sprintf("resource quota %s: %s needs %s on top of the %s used by the others, the limit is %s", quota.Name, name, request.String(), used.String(), limit.String()))
+			}
+		}
+	}
+	return res
+}
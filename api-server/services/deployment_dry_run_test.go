@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+
+	servingcommon "github.com/bentoml/yatai-deployment/apis/serving/common"
+	servingv2alpha1 "github.com/bentoml/yatai-deployment/apis/serving/v2alpha1"
+	servingfake "github.com/bentoml/yatai-deployment/generated/serving/clientset/versioned/fake"
+
+	resourcesv1alpha1 "github.com/bentoml/yatai-image-builder/apis/resources/v1alpha1"
+	resourcesfake "github.com/bentoml/yatai-image-builder/generated/resources/clientset/versioned/fake"
+)
+
+// The fake clientsets ignore the DryRun option and do not run the admission of an api server:
+// these tests check what is sent to the api server and how its errors are reported, not that nothing is persisted
+// nor that the quotas are enforced by the api server itself.
+const dryRunTestNamespace = "yatai"
+
+func newDryRunTestObjects(resources *servingcommon.Resources, autoscaling *servingv2alpha1.Autoscaling) (*models.DeploymentTarget, *servingv2alpha1.BentoDeployment, *resourcesv1alpha1.BentoRequest) {
+	deploymentTarget := &models.DeploymentTarget{
+		Type: modelschemas.DeploymentTargetTypeStable,
+	}
+	kubeBentoDeployment := &servingv2alpha1.BentoDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "iris",
+			Namespace: dryRunTestNamespace,
+		},
+		Spec: servingv2alpha1.BentoDeploymentSpec{
+			Bento:       "iris-classifier--1",
+			Resources:   resources,
+			Autoscaling: autoscaling,
+		},
+	}
+	bentoRequest := &resourcesv1alpha1.BentoRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "iris-classifier--1",
+			Namespace: dryRunTestNamespace,
+		},
+	}
+	return deploymentTarget, kubeBentoDeployment, bentoRequest
+}
+
+func newDryRunTestResult() *DeploymentTargetDryRunResult {
+	return &DeploymentTargetDryRunResult{
+		ValidationErrors: make([]string, 0),
+		QuotaErrors:      make([]string, 0),
+		Warnings:         make([]string, 0),
+	}
+}
+
+func TestDryRunKubeBentoDeploymentV2alpha1Create(t *testing.T) {
+	servingCli := servingfake.NewSimpleClientset()
+	resourcesCli := resourcesfake.NewSimpleClientset()
+	deploymentTarget, kubeBentoDeployment, bentoRequest := newDryRunTestObjects(&servingcommon.Resources{
+		Requests: &servingcommon.ResourceItem{CPU: "500m", Memory: "1Gi"},
+		Limits:   &servingcommon.ResourceItem{CPU: "1", Memory: "2Gi"},
+	}, &servingv2alpha1.Autoscaling{MinReplicas: 2, MaxReplicas: 4})
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1500m")},
+		},
+	}}
+
+	result := newDryRunTestResult()
+	requests, released, err := dryRunKubeBentoDeploymentV2alpha1(context.Background(), deploymentTarget, kubeBentoDeployment, bentoRequest, servingCli.ServingV2alpha1().BentoDeployments(dryRunTestNamespace), resourcesCli.ResourcesV1alpha1().BentoRequests(dryRunTestNamespace), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ValidationErrors) != 0 {
+		t.Fatalf("expected no validation errors, got %v", result.ValidationErrors)
+	}
+	if released != nil {
+		t.Fatalf("expected nothing to be released without a live BentoDeployment, got %v", released)
+	}
+	quotaErrors := checkKubeResourceQuotas(quotas, requests, released)
+	if len(quotaErrors) != 1 || !strings.Contains(quotaErrors[0], "requests.cpu") {
+		t.Fatalf("expected a requests.cpu quota error, got %v", quotaErrors)
+	}
+	if result.BentoDeployment == nil || result.BentoDeployment.Name != "iris" {
+		t.Fatalf("expected the applied BentoDeployment, got %+v", result.BentoDeployment)
+	}
+	for _, action := range servingCli.Actions() {
+		if action.GetVerb() == "update" {
+			t.Fatalf("expected the BentoDeployment to be created, got %+v", action)
+		}
+	}
+}
+
+func TestDryRunKubeBentoDeploymentV2alpha1Update(t *testing.T) {
+	deploymentTarget, kubeBentoDeployment, bentoRequest := newDryRunTestObjects(nil, &servingv2alpha1.Autoscaling{MinReplicas: 1, MaxReplicas: 3})
+	oldKubeBentoDeployment := kubeBentoDeployment.DeepCopy()
+	oldKubeBentoDeployment.ResourceVersion = "42"
+	oldKubeBentoDeployment.Annotations = map[string]string{"managed-by": "ops"}
+	servingCli := servingfake.NewSimpleClientset(oldKubeBentoDeployment)
+	resourcesCli := resourcesfake.NewSimpleClientset(bentoRequest.DeepCopy())
+
+	result := newDryRunTestResult()
+	_, _, err := dryRunKubeBentoDeploymentV2alpha1(context.Background(), deploymentTarget, kubeBentoDeployment, bentoRequest, servingCli.ServingV2alpha1().BentoDeployments(dryRunTestNamespace), resourcesCli.ResourcesV1alpha1().BentoRequests(dryRunTestNamespace), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updated *servingv2alpha1.BentoDeployment
+	for _, action := range servingCli.Actions() {
+		if updateAction, ok := action.(k8stesting.UpdateAction); ok {
+			updated = updateAction.GetObject().(*servingv2alpha1.BentoDeployment)
+		}
+	}
+	if updated == nil {
+		t.Fatal("expected the BentoDeployment to be updated")
+	}
+	if updated.ResourceVersion != "42" {
+		t.Fatalf("expected the resource version of the live BentoDeployment, got %q", updated.ResourceVersion)
+	}
+	if updated.Annotations["managed-by"] != "ops" {
+		t.Fatalf("expected the annotations of the live BentoDeployment to be kept, got %v", updated.Annotations)
+	}
+}
+
+func TestDryRunKubeBentoDeploymentV2alpha1UpdateQuota(t *testing.T) {
+	resources := &servingcommon.Resources{
+		Requests: &servingcommon.ResourceItem{CPU: "500m"},
+	}
+	// the 2 live pods of the deployment use 1 cpu of the 1500m used
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1500m")},
+		},
+	}}
+
+	for minReplicas, quotaErrors := range map[int32]int{2: 0, 3: 0, 4: 1} {
+		_, oldKubeBentoDeployment, _ := newDryRunTestObjects(resources, &servingv2alpha1.Autoscaling{MinReplicas: 2, MaxReplicas: 4})
+		deploymentTarget, kubeBentoDeployment, bentoRequest := newDryRunTestObjects(resources, &servingv2alpha1.Autoscaling{MinReplicas: minReplicas, MaxReplicas: 4})
+		servingCli := servingfake.NewSimpleClientset(oldKubeBentoDeployment)
+		resourcesCli := resourcesfake.NewSimpleClientset(bentoRequest.DeepCopy())
+
+		result := newDryRunTestResult()
+		requests, released, err := dryRunKubeBentoDeploymentV2alpha1(context.Background(), deploymentTarget, kubeBentoDeployment, bentoRequest, servingCli.ServingV2alpha1().BentoDeployments(dryRunTestNamespace), resourcesCli.ResourcesV1alpha1().BentoRequests(dryRunTestNamespace), result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := checkKubeResourceQuotas(quotas, requests, released); len(errs) != quotaErrors {
+			t.Fatalf("expected %d quota errors for %d replicas, got %v", quotaErrors, minReplicas, errs)
+		}
+	}
+}
+
+func TestDryRunKubeBentoDeploymentV2alpha1Rejected(t *testing.T) {
+	servingCli := servingfake.NewSimpleClientset()
+	servingCli.PrependReactor("create", "bentodeployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInvalid(schema.GroupKind{Group: "serving.yatai.ai", Kind: "BentoDeployment"}, "iris", field.ErrorList{
+			field.Invalid(field.NewPath("spec", "ingress", "hostSuffix"), "-", "must be a valid domain"),
+		})
+	})
+	resourcesCli := resourcesfake.NewSimpleClientset()
+	resourcesCli.PrependReactor("create", "bentorequests", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "resources.yatai.ai", Resource: "bentorequests"}, "iris-classifier--1", errors.New("exceeded quota: count, requested: count/bentorequests.resources.yatai.ai=1"))
+	})
+	deploymentTarget, kubeBentoDeployment, bentoRequest := newDryRunTestObjects(&servingcommon.Resources{
+		Requests: &servingcommon.ResourceItem{CPU: "2", Memory: "a lot"},
+		Limits:   &servingcommon.ResourceItem{CPU: "1"},
+	}, &servingv2alpha1.Autoscaling{MinReplicas: 3, MaxReplicas: 2})
+
+	result := newDryRunTestResult()
+	_, _, err := dryRunKubeBentoDeploymentV2alpha1(context.Background(), deploymentTarget, kubeBentoDeployment, bentoRequest, servingCli.ServingV2alpha1().BentoDeployments(dryRunTestNamespace), resourcesCli.ResourcesV1alpha1().BentoRequests(dryRunTestNamespace), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`resources.requests.memory: invalid quantity "a lot"`,
+		"resources: the cpu request 2 is greater than the limit 1",
+		"autoscaling: maxReplicas 2 is lower than minReplicas 3",
+		"BentoDeployment spec.ingress.hostSuffix: Invalid value: \"-\": must be a valid domain",
+	}
+	if len(result.ValidationErrors) != len(expected) {
+		t.Fatalf("expected %d validation errors, got %v", len(expected), result.ValidationErrors)
+	}
+	for idx, msg := range expected {
+		if result.ValidationErrors[idx] != msg {
+			t.Fatalf("expected %q, got %q", msg, result.ValidationErrors[idx])
+		}
+	}
+	if len(result.QuotaErrors) != 1 || !strings.HasPrefix(result.QuotaErrors[0], "BentoRequest: ") {
+		t.Fatalf("expected a BentoRequest quota error, got %v", result.QuotaErrors)
+	}
+}
+
+func TestCheckKubeResourceQuotas(t *testing.T) {
+	spec := &servingv2alpha1.BentoDeploymentSpec{
+		Resources: &servingcommon.Resources{
+			Requests: &servingcommon.ResourceItem{CPU: "1", Memory: "1Gi"},
+		},
+		Autoscaling: &servingv2alpha1.Autoscaling{MinReplicas: 2, MaxReplicas: 4},
+		Runners: []servingv2alpha1.BentoDeploymentRunnerSpec{{
+			Name: "model",
+			Resources: &servingcommon.Resources{
+				Requests: &servingcommon.ResourceItem{CPU: "500m", Memory: "2Gi", GPU: "1"},
+			},
+		}},
+	}
+	requests := getBentoDeploymentV2alpha1ResourceRequests(spec)
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    "2500m",
+		corev1.ResourceCPU:            "2500m",
+		corev1.ResourceRequestsMemory: "4Gi",
+		corev1.ResourcePods:           "3",
+		"requests.nvidia.com/gpu":     "1",
+	} {
+		quantity := requests[name]
+		if quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("expected %s to be %s, got %s", name, value, quantity.String())
+		}
+	}
+
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{
+				corev1.ResourcePods:           resource.MustParse("10"),
+				corev1.ResourceRequestsMemory: resource.MustParse("8Gi"),
+				"requests.nvidia.com/gpu":     resource.MustParse("1"),
+			},
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Used: corev1.ResourceList{
+				corev1.ResourcePods:           resource.MustParse("7"),
+				corev1.ResourceRequestsMemory: resource.MustParse("6Gi"),
+			},
+		},
+	}}
+	errs := checkKubeResourceQuotas(quotas, requests, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0], "requests.memory") {
+		t.Fatalf("expected a requests.memory quota error, got %v", errs)
+	}
+}
+
+func TestCheckKubeResourceQuotasOfAllTargets(t *testing.T) {
+	resources := &servingcommon.Resources{
+		Requests: &servingcommon.ResourceItem{CPU: "500m"},
+	}
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("500m")},
+		},
+	}}
+
+	// the stable target and the canary target need 1 cpu each, each fits alone but not both
+	requestsList := make([]corev1.ResourceList, 0, 2)
+	releasedList := make([]corev1.ResourceList, 0, 2)
+	for _, name := range []string{"iris", "iris-canary"} {
+		deploymentTarget, kubeBentoDeployment, bentoRequest := newDryRunTestObjects(resources, &servingv2alpha1.Autoscaling{MinReplicas: 2, MaxReplicas: 4})
+		kubeBentoDeployment.Name = name
+		result := newDryRunTestResult()
+		requests, released, err := dryRunKubeBentoDeploymentV2alpha1(context.Background(), deploymentTarget, kubeBentoDeployment, bentoRequest, servingfake.NewSimpleClientset().ServingV2alpha1().BentoDeployments(dryRunTestNamespace), resourcesfake.NewSimpleClientset().ResourcesV1alpha1().BentoRequests(dryRunTestNamespace), result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := checkKubeResourceQuotas(quotas, requests, released); len(errs) != 0 {
+			t.Fatalf("expected the target %s to fit alone, got %v", name, errs)
+		}
+		requestsList = append(requestsList, requests)
+		releasedList = append(releasedList, released)
+	}
+	errs := checkKubeResourceQuotas(quotas, sumKubeResourceLists(requestsList), sumKubeResourceLists(releasedList))
+	if len(errs) != 1 || !strings.Contains(errs[0], "requests.cpu needs 2") {
+		t.Fatalf("expected a requests.cpu quota error for the targets together, got %v", errs)
+	}
+}
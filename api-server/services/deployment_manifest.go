@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+// the manifest format is versioned so that it can evolve without breaking the manifests kept in git
+const (
+	DeploymentManifestApiVersion = "yatai.ai/v1"
+	DeploymentManifestKind       = "Deployment"
+)
+
+type DeploymentManifestMetadata struct {
+	Name          string            `json:"name"`
+	Cluster       string            `json:"cluster"`
+	KubeNamespace string            `json:"kubeNamespace"`
+	Description   string            `json:"description,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+type DeploymentManifestTarget struct {
+	Type modelschemas.DeploymentTargetType `json:"type"`
+	// Bento is the tag of the bento, <bento repository>:<version>
+	Bento       string                                    `json:"bento"`
+	CanaryRules *modelschemas.DeploymentTargetCanaryRules `json:"canaryRules,omitempty"`
+	Config      *modelschemas.DeploymentTargetConfig      `json:"config,omitempty"`
+}
+
+// DeploymentManifestSpec targets are matched with the targets of the active revision by type,
+// the targets of the same type are matched in order
+type DeploymentManifestSpec struct {
+	Targets []*DeploymentManifestTarget `json:"targets"`
+}
+
+type DeploymentManifest struct {
+	ApiVersion string                     `json:"apiVersion"`
+	Kind       string                     `json:"kind"`
+	Metadata   DeploymentManifestMetadata `json:"metadata"`
+	Spec       DeploymentManifestSpec     `json:"spec"`
+}
+
+type DeploymentManifestApplyResult struct {
+	Created bool `json:"created"`
+	Changed bool `json:"changed"`
+	// DeploymentRevisionUid is the revision created by the apply, it is empty when the targets did not change
+	DeploymentRevisionUid string `json:"deployment_revision_uid"`
+	// PendingApproval is true when the revision waits for an approval before being deployed
+	PendingApproval bool              `json:"pending_approval"`
+	PrunedTargets   []string          `json:"pruned_targets"`
+	Items           []*utils.DiffItem `json:"items"`
+}
+
+type ApplyDeploymentManifestOption struct {
+	CreatorId uint
+	Cluster   *models.Cluster
+	// Deployment is nil when the deployment of the manifest does not exist yet
+	Deployment *models.Deployment
+	Manifest   *DeploymentManifest
+	// Prune removes the targets of the active revision which are not in the manifest, they are kept otherwise
+	Prune  bool
+	DryRun bool
+}
+
+type deploymentManifestService struct{}
+
+var DeploymentManifestService = deploymentManifestService{}
+
+// ParseDeploymentManifest accepts YAML and JSON, the unknown fields are rejected so that typos are not silently ignored
+func ParseDeploymentManifest(content []byte) (*DeploymentManifest, error) {
+	jsonContent, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert the manifest to json")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	decoder.DisallowUnknownFields()
+	manifest := &DeploymentManifest{}
+	if err = decoder.Decode(manifest); err != nil {
+		return nil, errors.Wrap(err, "decode the manifest")
+	}
+	if manifest.ApiVersion != DeploymentManifestApiVersion {
+		return nil, errors.Errorf("unsupported manifest apiVersion %q, expected %q", manifest.ApiVersion, DeploymentManifestApiVersion)
+	}
+	if manifest.Kind != DeploymentManifestKind {
+		return nil, errors.Errorf("unsupported manifest kind %q, expected %q", manifest.Kind, DeploymentManifestKind)
+	}
+	if strings.TrimSpace(manifest.Metadata.Name) == "" {
+		return nil, errors.New("metadata.name is required")
+	}
+	if strings.TrimSpace(manifest.Metadata.Cluster) == "" {
+		return nil, errors.New("metadata.cluster is required")
+	}
+	if len(manifest.Spec.Targets) == 0 {
+		return nil, errors.New("spec.targets is empty")
+	}
+	for idx, target := range manifest.Spec.Targets {
+		if target == nil {
+			return nil, errors.Errorf("spec.targets[%d] is empty", idx)
+		}
+		if target.Type == "" {
+			return nil, errors.Errorf("spec.targets[%d].type is required", idx)
+		}
+		if _, _, err = modelschemas.Tag(target.Bento).Parse(); err != nil {
+			return nil, errors.Wrapf(err, "spec.targets[%d].bento", idx)
+		}
+	}
+	return manifest, nil
+}
+
+func MarshalDeploymentManifestYAML(manifest *DeploymentManifest) ([]byte, error) {
+	return yaml.Marshal(manifest)
+}
+
+func (s *deploymentManifestService) listActiveDeploymentTargets(ctx context.Context, deployment *models.Deployment) ([]*models.DeploymentTarget, error) {
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentId:             utils.UintPtr(deployment.ID),
+		DeploymentRevisionStatus: modelschemas.DeploymentRevisionStatusPtr(modelschemas.DeploymentRevisionStatusActive),
+	})
+	return deploymentTargets, errors.Wrap(err, "list active deployment targets")
+}
+
+func (s *deploymentManifestService) getLabels(ctx context.Context, deployment *models.Deployment) (map[string]string, error) {
+	labels, _, err := LabelService.List(ctx, ListLabelOption{
+		ResourceType: deployment.GetResourceType().Ptr(),
+		ResourceId:   utils.UintPtr(deployment.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment labels")
+	}
+	res := make(map[string]string, len(labels))
+	for _, label := range labels {
+		res[label.Key] = label.Value
+	}
+	return res, nil
+}
+
+// Export renders the active revision of the deployment as a manifest, exporting an unchanged deployment twice gives the same manifest
+func (s *deploymentManifestService) Export(ctx context.Context, deployment *models.Deployment) (*DeploymentManifest, error) {
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment associated cluster")
+	}
+	labels, err := s.getLabels(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	deploymentTargets, err := s.listActiveDeploymentTargets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	if len(deploymentTargets) == 0 {
+		return nil, errors.Errorf("deployment %s has no active revision", deployment.Name)
+	}
+	targets := make([]*DeploymentManifestTarget, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		bento, err := BentoService.GetAssociatedBento(ctx, deploymentTarget)
+		if err != nil {
+			return nil, errors.Wrap(err, "get associated bento")
+		}
+		tag, err := BentoService.GetTag(ctx, bento)
+		if err != nil {
+			return nil, errors.Wrap(err, "get bento tag")
+		}
+		var config *modelschemas.DeploymentTargetConfig
+		if deploymentTarget.Config != nil {
+			// the kube resource version changes on every deploy, it does not belong to the manifest
+			config = deploymentTarget.Config.DeepCopy()
+			config.KubeResourceUid = ""
+			config.KubeResourceVersion = ""
+		}
+		targets = append(targets, &DeploymentManifestTarget{
+			Type:        deploymentTarget.Type,
+			Bento:       string(tag),
+			CanaryRules: deploymentTarget.CanaryRules,
+			Config:      config,
+		})
+	}
+	return &DeploymentManifest{
+		ApiVersion: DeploymentManifestApiVersion,
+		Kind:       DeploymentManifestKind,
+		Metadata: DeploymentManifestMetadata{
+			Name:          deployment.Name,
+			Cluster:       cluster.Name,
+			KubeNamespace: deployment.KubeNamespace,
+			Description:   deployment.Description,
+			Labels:        labels,
+		},
+		Spec: DeploymentManifestSpec{
+			Targets: targets,
+		},
+	}, nil
+}
+
+func (s *deploymentManifestService) getBento(ctx context.Context, organizationId uint, tag string) (*models.Bento, error) {
+	bentoRepositoryName, version, err := modelschemas.Tag(tag).Parse()
+	if err != nil {
+		return nil, err
+	}
+	bentoRepository, err := BentoRepositoryService.GetByName(ctx, organizationId, bentoRepositoryName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get bento repository %s", bentoRepositoryName)
+	}
+	bento, err := BentoService.GetByVersion(ctx, bentoRepository.ID, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get bento %s", tag)
+	}
+	return bento, nil
+}
+
+// Apply makes the deployment match the manifest, a new revision is only deployed when the targets changed
+// so that applying the same manifest again is a no-op
+func (s *deploymentManifestService) Apply(ctx context.Context, opt ApplyDeploymentManifestOption) (deployment *models.Deployment, result *DeploymentManifestApplyResult, err error) {
+	manifest := opt.Manifest
+	result = &DeploymentManifestApplyResult{
+		Created:       opt.Deployment == nil,
+		PrunedTargets: make([]string, 0),
+		Items:         make([]*utils.DiffItem, 0),
+	}
+
+	desiredDeploymentTargets := make([]*models.DeploymentTarget, 0, len(manifest.Spec.Targets))
+	for _, target := range manifest.Spec.Targets {
+		bento, err := s.getBento(ctx, opt.Cluster.OrganizationId, target.Bento)
+		if err != nil {
+			return nil, nil, err
+		}
+		var config *modelschemas.DeploymentTargetConfig
+		if target.Config != nil {
+			config = target.Config.DeepCopy()
+			config.KubeResourceUid = ""
+			config.KubeResourceVersion = ""
+		}
+		desiredDeploymentTargets = append(desiredDeploymentTargets, &models.DeploymentTarget{
+			BentoAssociate: models.BentoAssociate{
+				BentoId:              bento.ID,
+				AssociatedBentoCache: bento,
+			},
+			Type:        target.Type,
+			CanaryRules: target.CanaryRules,
+			Config:      config,
+		})
+	}
+
+	currentDeploymentTargets := make([]*models.DeploymentTarget, 0)
+	currentLabels := make(map[string]string)
+	currentDescription := ""
+	if opt.Deployment != nil {
+		currentDeploymentTargets, err = s.listActiveDeploymentTargets(ctx, opt.Deployment)
+		if err != nil {
+			return nil, nil, err
+		}
+		currentLabels, err = s.getLabels(ctx, opt.Deployment)
+		if err != nil {
+			return nil, nil, err
+		}
+		currentDescription = opt.Deployment.Description
+	}
+
+	desiredKeys := make(map[string]struct{}, len(desiredDeploymentTargets))
+	for _, key := range getDeploymentTargetDiffKeys(desiredDeploymentTargets) {
+		desiredKeys[key] = struct{}{}
+	}
+	// the kept targets have the highest indexes of their type, appending them keeps their keys
+	for idx, key := range getDeploymentTargetDiffKeys(currentDeploymentTargets) {
+		if _, ok := desiredKeys[key]; ok {
+			continue
+		}
+		if opt.Prune {
+			result.PrunedTargets = append(result.PrunedTargets, key)
+			continue
+		}
+		desiredDeploymentTargets = append(desiredDeploymentTargets, currentDeploymentTargets[idx])
+	}
+
+	currentViews, err := getDeploymentTargetDiffViews(ctx, currentDeploymentTargets)
+	if err != nil {
+		return nil, nil, err
+	}
+	desiredViews, err := getDeploymentTargetDiffViews(ctx, desiredDeploymentTargets)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetItems, err := utils.DiffJSON(map[string]interface{}{"targets": currentViews}, map[string]interface{}{"targets": desiredViews})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "diff targets")
+	}
+	desiredLabels := manifest.Metadata.Labels
+	if desiredLabels == nil {
+		desiredLabels = make(map[string]string)
+	}
+	metadataItems, err := utils.DiffJSON(map[string]interface{}{
+		"description": currentDescription,
+		"labels":      currentLabels,
+	}, map[string]interface{}{
+		"description": manifest.Metadata.Description,
+		"labels":      desiredLabels,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "diff metadata")
+	}
+	result.Items = append(result.Items, metadataItems...)
+	result.Items = append(result.Items, targetItems...)
+	// a deployment without an active revision is deployed even if its targets are empty
+	deployTargets := len(targetItems) > 0 || len(currentDeploymentTargets) == 0
+	result.Changed = result.Created || deployTargets || len(metadataItems) > 0
+
+	if opt.DryRun || !result.Changed {
+		return opt.Deployment, result, nil
+	}
+
+	labelKeys := make([]string, 0, len(desiredLabels))
+	for key := range desiredLabels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+	labelItems := make(modelschemas.LabelItemsSchema, 0, len(labelKeys))
+	for _, key := range labelKeys {
+		labelItems = append(labelItems, modelschemas.LabelItemSchema{
+			Key:   key,
+			Value: desiredLabels[key],
+		})
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { df(err) }()
+
+	deployment = opt.Deployment
+	if deployment == nil {
+		deployment, err = DeploymentService.Create(ctx, CreateDeploymentOption{
+			CreatorId:     opt.CreatorId,
+			ClusterId:     opt.Cluster.ID,
+			Name:          manifest.Metadata.Name,
+			Description:   manifest.Metadata.Description,
+			Labels:        labelItems,
+			KubeNamespace: manifest.Metadata.KubeNamespace,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "create deployment")
+		}
+	} else if len(metadataItems) > 0 {
+		deployment, err = DeploymentService.Update(ctx, deployment, UpdateDeploymentOption{
+			Description: &manifest.Metadata.Description,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "update deployment")
+		}
+		err = LabelService.CreateOrUpdateLabelsFromLabelItemsSchema(ctx, labelItems, opt.CreatorId, opt.Cluster.OrganizationId, deployment)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "update deployment labels")
+		}
+	}
+
+	if deployTargets {
+		var deploymentRevision *models.DeploymentRevision
+		deploymentRevision, err = DeploymentRevisionService.submitAsNewRevision(ctx, deployment.ID, opt.CreatorId, nil, desiredDeploymentTargets)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.DeploymentRevisionUid = deploymentRevision.Uid
+		result.PendingApproval = deploymentRevision.Status == models.DeploymentRevisionStatusPending
+	}
+	return deployment, result, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentMemberService struct{}
+
+var DeploymentMemberService = deploymentMemberService{}
+
+func (s *deploymentMemberService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.DeploymentMember{})
+}
+
+func (s *deploymentMemberService) GetResourceType() modelschemas.ResourceType {
+	return modelschemas.ResourceTypeDeployment
+}
+
+type CreateDeploymentMemberOption struct {
+	CreatorId    uint
+	UserId       uint
+	DeploymentId uint
+	Role         modelschemas.MemberRole
+}
+
+type UpdateDeploymentMemberOption struct {
+	Role modelschemas.MemberRole
+}
+
+type ListDeploymentMemberOption struct {
+	UserId       *uint
+	DeploymentId *uint
+	Roles        *[]modelschemas.MemberRole
+}
+
+func (s *deploymentMemberService) Create(ctx context.Context, operatorId uint, opt CreateDeploymentMemberOption) (*models.DeploymentMember, error) {
+	oldMember, err := s.GetBy(ctx, opt.UserId, opt.DeploymentId)
+	if err != nil && !utils.IsNotFound(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		return s.Update(ctx, oldMember, operatorId, UpdateDeploymentMemberOption{Role: opt.Role})
+	}
+
+	member := &models.DeploymentMember{
+		CreatorAssociate: models.CreatorAssociate{
+			CreatorId: opt.CreatorId,
+		},
+		UserAssociate: models.UserAssociate{
+			UserId: opt.UserId,
+		},
+		DeploymentAssociate: models.DeploymentAssociate{
+			DeploymentId: opt.DeploymentId,
+		},
+		Role: opt.Role,
+	}
+	err = mustGetSession(ctx).Create(member).Error
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *deploymentMemberService) Update(ctx context.Context, m *models.DeploymentMember, operatorId uint, opt UpdateDeploymentMemberOption) (*models.DeploymentMember, error) {
+	err := s.getBaseDB(ctx).Where("id = ?", m.ID).Updates(map[string]interface{}{
+		"role": opt.Role,
+	}).Error
+	if err == nil {
+		m.Role = opt.Role
+	}
+	return m, err
+}
+
+func (s *deploymentMemberService) Get(ctx context.Context, id uint) (*models.DeploymentMember, error) {
+	var member models.DeploymentMember
+	err := getBaseQuery(ctx, s).Where("id = ?", id).First(&member).Error
+	return &member, err
+}
+
+func (s *deploymentMemberService) GetBy(ctx context.Context, userId, deploymentId uint) (*models.DeploymentMember, error) {
+	var member models.DeploymentMember
+	err := getBaseQuery(ctx, s).Where("deployment_id = ?", deploymentId).Where("user_id = ?", userId).First(&member).Error
+	return &member, err
+}
+
+func (s *deploymentMemberService) List(ctx context.Context, opt ListDeploymentMemberOption) ([]*models.DeploymentMember, error) {
+	members := make([]*models.DeploymentMember, 0)
+	query := getBaseQuery(ctx, s)
+	// the rows of the user groups are listed by the UserGroupMemberService
+	query = query.Where("user_group_id IS NULL")
+	if opt.DeploymentId != nil {
+		query = query.Where("deployment_id = ?", *opt.DeploymentId)
+	}
+	if opt.UserId != nil {
+		query = query.Where("user_id = ?", *opt.UserId)
+	}
+	if opt.Roles != nil {
+		query = query.Where("role in (?)", *opt.Roles)
+	}
+	err := query.Order("id DESC").Find(&members).Error
+	return members, err
+}
+
+func (s *deploymentMemberService) GetOrganization(ctx context.Context, resourceId uint) (*models.Organization, error) {
+	deployment, err := DeploymentService.Get(ctx, resourceId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment")
+	}
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster")
+	}
+	return OrganizationService.GetAssociatedOrganization(ctx, cluster)
+}
+
+func (s *deploymentMemberService) CheckRoles(ctx context.Context, userId, resourceId uint, roles []modelschemas.MemberRole) (bool, error) {
+	q := s.getBaseDB(ctx).
+		Where("(user_id = ? OR user_group_id in (?))", userId, UserGroupMemberService.userGroupIdsOfUser(ctx, userId)).
+		Where("deployment_id = ?", resourceId).
+		Where("role in (?)", roles)
+	var total int64
+	err := q.Count(&total).Error
+	return total > 0, err
+}
+
+// IsRestricted reports whether the deployment has members, either users or user groups.
+// A restricted deployment can only be changed by its members, the others fall back to the cluster permissions.
+func (s *deploymentMemberService) IsRestricted(ctx context.Context, deploymentId uint) (bool, error) {
+	var total int64
+	err := s.getBaseDB(ctx).Where("deployment_id = ?", deploymentId).Count(&total).Error
+	return total > 0, err
+}
+
+func (s *deploymentMemberService) Delete(ctx context.Context, m *models.DeploymentMember, operatorId uint) (*models.DeploymentMember, error) {
+	err := s.getBaseDB(ctx).Unscoped().Delete(m).Error
+	return m, err
+}
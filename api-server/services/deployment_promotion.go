@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentPromotionService struct{}
+
+var DeploymentPromotionService = deploymentPromotionService{}
+
+func (s *deploymentPromotionService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.DeploymentPromotion{})
+}
+
+type PromoteDeploymentRevisionOption struct {
+	CreatorId                uint
+	SourceDeploymentRevision *models.DeploymentRevision
+	TargetClusterId          uint
+	TargetKubeNamespace      string
+	TargetDeploymentName     string
+	Overrides                *models.DeploymentPromotionOverrides
+}
+
+type ListDeploymentPromotionOption struct {
+	BaseListOption
+	// DeploymentId matches the promotions from and to the deployment
+	DeploymentId *uint
+}
+
+// Promote deploys the targets of the source revision with the overrides as a new revision of the target deployment,
+// the new revision is pending when the target cluster requires the creator to get an approval,
+// the target deployment is created with the description and the labels of the source one when it does not exist
+func (s *deploymentPromotionService) Promote(ctx context.Context, opt PromoteDeploymentRevisionOption) (promotion *models.DeploymentPromotion, targetDeploymentCreated bool, err error) {
+	sourceDeployment, err := DeploymentService.GetAssociatedDeployment(ctx, opt.SourceDeploymentRevision)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "get source deployment")
+	}
+	sourceDeploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(opt.SourceDeploymentRevision.ID),
+	})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "list source deployment targets")
+	}
+	if len(sourceDeploymentTargets) == 0 {
+		return nil, false, errors.Errorf("deployment revision %s has no targets", opt.SourceDeploymentRevision.Uid)
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { df(err) }()
+
+	targetDeployment, err := DeploymentService.GetByName(ctx, opt.TargetClusterId, opt.TargetKubeNamespace, opt.TargetDeploymentName)
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			return nil, false, err
+		}
+		resourceType := modelschemas.ResourceTypeDeployment
+		var labels []*models.Label
+		labels, _, err = LabelService.List(ctx, ListLabelOption{
+			ResourceType: &resourceType,
+			ResourceId:   utils.UintPtr(sourceDeployment.ID),
+		})
+		if err != nil {
+			return nil, false, errors.Wrap(err, "list source deployment labels")
+		}
+		labelItems := make(modelschemas.LabelItemsSchema, 0, len(labels))
+		for _, label := range labels {
+			labelItems = append(labelItems, modelschemas.LabelItemSchema{
+				Key:   label.Key,
+				Value: label.Value,
+			})
+		}
+		targetDeployment, err = DeploymentService.Create(ctx, CreateDeploymentOption{
+			CreatorId:     opt.CreatorId,
+			ClusterId:     opt.TargetClusterId,
+			Name:          opt.TargetDeploymentName,
+			Description:   sourceDeployment.Description,
+			Labels:        labelItems,
+			KubeNamespace: opt.TargetKubeNamespace,
+		})
+		if err != nil {
+			return nil, false, errors.Wrap(err, "create target deployment")
+		}
+		targetDeploymentCreated = true
+	}
+	if targetDeployment.ID == sourceDeployment.ID {
+		err = errors.New("cannot promote a deployment revision to its own deployment")
+		return nil, false, err
+	}
+
+	targetDeploymentTargets := make([]*models.DeploymentTarget, 0, len(sourceDeploymentTargets))
+	for _, sourceDeploymentTarget := range sourceDeploymentTargets {
+		targetDeploymentTarget := *sourceDeploymentTarget
+		targetDeploymentTarget.Config = applyDeploymentPromotionOverrides(sourceDeploymentTarget.Config, opt.Overrides)
+		targetDeploymentTargets = append(targetDeploymentTargets, &targetDeploymentTarget)
+	}
+
+	targetDeploymentRevision, err := DeploymentRevisionService.submitAsNewRevision(ctx, targetDeployment.ID, opt.CreatorId, nil, targetDeploymentTargets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	promotion = &models.DeploymentPromotion{
+		CreatorAssociate: models.CreatorAssociate{
+			CreatorId: opt.CreatorId,
+		},
+		SourceDeploymentId:         sourceDeployment.ID,
+		SourceDeploymentRevisionId: opt.SourceDeploymentRevision.ID,
+		TargetDeploymentId:         targetDeployment.ID,
+		TargetDeploymentRevisionId: targetDeploymentRevision.ID,
+		Overrides:                  opt.Overrides,
+	}
+	err = mustGetSession(ctx).Create(promotion).Error
+	if err != nil {
+		return nil, false, errors.Wrap(err, "create deployment promotion")
+	}
+	return promotion, targetDeploymentCreated, nil
+}
+
+func (s *deploymentPromotionService) List(ctx context.Context, opt ListDeploymentPromotionOption) ([]*models.DeploymentPromotion, uint, error) {
+	query := s.getBaseDB(ctx)
+	if opt.DeploymentId != nil {
+		query = query.Where("source_deployment_id = ? OR target_deployment_id = ?", *opt.DeploymentId, *opt.DeploymentId)
+	}
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	promotions := make([]*models.DeploymentPromotion, 0)
+	query = opt.BindQueryWithLimit(query).Order("id DESC")
+	err = query.Find(&promotions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return promotions, uint(total), err
+}
+
+func mergeDeploymentPromotionHPAConf(hpaConf, overrides *modelschemas.DeploymentTargetHPAConf) *modelschemas.DeploymentTargetHPAConf {
+	if overrides == nil {
+		return hpaConf
+	}
+	if hpaConf == nil {
+		hpaConf = &modelschemas.DeploymentTargetHPAConf{}
+	}
+	if overrides.CPU != nil {
+		hpaConf.CPU = overrides.CPU
+	}
+	if overrides.GPU != nil {
+		hpaConf.GPU = overrides.GPU
+	}
+	if overrides.Memory != nil {
+		hpaConf.Memory = overrides.Memory
+	}
+	if overrides.QPS != nil {
+		hpaConf.QPS = overrides.QPS
+	}
+	if overrides.MinReplicas != nil {
+		hpaConf.MinReplicas = overrides.MinReplicas
+	}
+	if overrides.MaxReplicas != nil {
+		hpaConf.MaxReplicas = overrides.MaxReplicas
+	}
+	return hpaConf
+}
+
+func mergeDeploymentPromotionEnvs(envs, overrides *[]*modelschemas.LabelItemSchema) *[]*modelschemas.LabelItemSchema {
+	if overrides == nil {
+		return envs
+	}
+	merged := make([]*modelschemas.LabelItemSchema, 0)
+	if envs != nil {
+		merged = append(merged, *envs...)
+	}
+	for _, override := range *overrides {
+		found := false
+		for idx, env := range merged {
+			if env.Key == override.Key {
+				merged[idx] = override
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, override)
+		}
+	}
+	return &merged
+}
+
+// applyDeploymentPromotionOverrides returns a copy of the config with the overrides applied, the config is not modified
+func applyDeploymentPromotionOverrides(config *modelschemas.DeploymentTargetConfig, overrides *models.DeploymentPromotionOverrides) *modelschemas.DeploymentTargetConfig {
+	if config == nil {
+		config = &modelschemas.DeploymentTargetConfig{}
+	}
+	config = config.DeepCopy()
+	if overrides == nil {
+		return config
+	}
+	if overrides.Resources != nil {
+		config.Resources = overrides.Resources.DeepCopy()
+	}
+	config.HPAConf = mergeDeploymentPromotionHPAConf(config.HPAConf, overrides.HPAConf.DeepCopy())
+	config.Envs = mergeDeploymentPromotionEnvs(config.Envs, overrides.Envs)
+	// DeepCopy shares the runners map with the source config
+	runners := make(map[string]modelschemas.DeploymentTargetRunnerConfig, len(config.Runners))
+	for name, runner := range config.Runners {
+		runners[name] = *runner.DeepCopy()
+	}
+	config.Runners = runners
+	for name, runnerOverrides := range overrides.Runners {
+		runner := config.Runners[name]
+		if runnerOverrides.Resources != nil {
+			runner.Resources = runnerOverrides.Resources.DeepCopy()
+		}
+		runner.HPAConf = mergeDeploymentPromotionHPAConf(runner.HPAConf, runnerOverrides.HPAConf.DeepCopy())
+		runner.Envs = mergeDeploymentPromotionEnvs(runner.Envs, runnerOverrides.Envs)
+		config.Runners[name] = runner
+	}
+	return config
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
@@ -30,13 +31,19 @@ func (s *deploymentRevisionService) getBaseDB(ctx context.Context) *gorm.DB {
 }
 
 type CreateDeploymentRevisionOption struct {
-	CreatorId    uint
-	DeploymentId uint
-	Status       modelschemas.DeploymentRevisionStatus
+	CreatorId              uint
+	DeploymentId           uint
+	Status                 modelschemas.DeploymentRevisionStatus
+	RollbackFromRevisionId *uint
 }
 
 type UpdateDeploymentRevisionOption struct {
-	Status *modelschemas.DeploymentRevisionStatus
+	// FromStatus makes the update fail with consts.ErrConflict when the revision is no longer of this status
+	FromStatus    *modelschemas.DeploymentRevisionStatus
+	Status        *modelschemas.DeploymentRevisionStatus
+	ReviewerId    *uint
+	ReviewedAt    *time.Time
+	ReviewComment *string
 }
 
 type ListDeploymentRevisionOption struct {
@@ -56,7 +63,8 @@ func (*deploymentRevisionService) Create(ctx context.Context, opt CreateDeployme
 		DeploymentAssociate: models.DeploymentAssociate{
 			DeploymentId: opt.DeploymentId,
 		},
-		Status: opt.Status,
+		Status:                 opt.Status,
+		RollbackFromRevisionId: opt.RollbackFromRevisionId,
 	}
 	err := mustGetSession(ctx).Create(&deploymentRevision).Error
 	if err != nil {
@@ -76,12 +84,55 @@ func (s *deploymentRevisionService) Update(ctx context.Context, deploymentRevisi
 			}
 		}()
 	}
+	if opt.ReviewerId != nil {
+		updaters["reviewer_id"] = *opt.ReviewerId
+		defer func() {
+			if err == nil {
+				deploymentRevision.ReviewerId = opt.ReviewerId
+			}
+		}()
+	}
+	if opt.ReviewedAt != nil {
+		updaters["reviewed_at"] = *opt.ReviewedAt
+		defer func() {
+			if err == nil {
+				deploymentRevision.ReviewedAt = opt.ReviewedAt
+			}
+		}()
+	}
+	if opt.ReviewComment != nil {
+		updaters["review_comment"] = *opt.ReviewComment
+		defer func() {
+			if err == nil {
+				deploymentRevision.ReviewComment = *opt.ReviewComment
+			}
+		}()
+	}
 
 	if len(updaters) == 0 {
 		return deploymentRevision, nil
 	}
 
-	err = s.getBaseDB(ctx).Where("id = ?", deploymentRevision.ID).Updates(updaters).Error
+	query := s.getBaseDB(ctx).Where("id = ?", deploymentRevision.ID)
+	if opt.FromStatus != nil {
+		query = query.Where("status = ?", *opt.FromStatus)
+	}
+	res := query.Updates(updaters)
+	err = res.Error
+	if err != nil {
+		return nil, err
+	}
+	if opt.FromStatus != nil && res.RowsAffected == 0 {
+		err = errors.Wrapf(consts.ErrConflict, "deployment revision %s is no longer %s", deploymentRevision.Uid, *opt.FromStatus)
+		return nil, err
+	}
+
+	// the deployment schema embeds its latest revision
+	deployment, err := DeploymentService.GetAssociatedDeployment(ctx, deploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	err = NotificationService.Publish(ctx, modelschemas.ResourceTypeDeployment, deployment.Uid)
 	if err != nil {
 		return nil, err
 	}
@@ -358,12 +409,31 @@ func (s *deploymentRevisionService) Deploy(ctx context.Context, deploymentRevisi
 		err = errors.Wrapf(err, "make sure kube namespace %s", kubeNs)
 		return
 	}
+	// the status of the deployment is synced from the informers of its namespace
+	DeploymentStatusReconciler.Watch(cluster.ID, kubeNs)
 
 	deployOption, err := s.GetDeployOption(ctx, deploymentRevision, force)
 	if err != nil {
 		return
 	}
 
+	oldDeploymentRevisionIds := make([]uint, 0, len(oldDeploymentRevisions))
+	for _, oldDeploymentRevision := range oldDeploymentRevisions {
+		if oldDeploymentRevision.ID != deploymentRevision.ID {
+			oldDeploymentRevisionIds = append(oldDeploymentRevisionIds, oldDeploymentRevision.ID)
+		}
+	}
+	if len(oldDeploymentRevisionIds) > 0 {
+		var oldDeploymentTargets []*models.DeploymentTarget
+		oldDeploymentTargets, _, err = DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+			DeploymentRevisionIds: &oldDeploymentRevisionIds,
+		})
+		if err != nil {
+			return
+		}
+		deployOption.AsleepTargetTypes = getAsleepDeploymentTargetTypes(oldDeploymentTargets)
+	}
+
 	if len(deploymentTargets) == 0 {
 		deploymentTargets, _, err = DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
 			DeploymentRevisionId: utils.UintPtr(deploymentRevision.ID),
@@ -384,6 +454,124 @@ func (s *deploymentRevisionService) Deploy(ctx context.Context, deploymentRevisi
 	return nil
 }
 
+// Rollback copies the targets of the revision into a new revision and deploys it,
+// the new revision is pending when the cluster requires the creator to get an approval
+func (s *deploymentRevisionService) Rollback(ctx context.Context, deploymentRevision *models.DeploymentRevision, creatorId uint) (*models.DeploymentRevision, error) {
+	if deploymentRevision.Status == modelschemas.DeploymentRevisionStatusActive {
+		return nil, errors.Errorf("deployment revision %s is already active", deploymentRevision.Uid)
+	}
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(deploymentRevision.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment targets")
+	}
+	if len(deploymentTargets) == 0 {
+		return nil, errors.Errorf("deployment revision %s has no targets", deploymentRevision.Uid)
+	}
+
+	return s.submitAsNewRevision(ctx, deploymentRevision.DeploymentId, creatorId, utils.UintPtr(deploymentRevision.ID), deploymentTargets)
+}
+
+// createAsNewRevision creates a revision of the status with copies of the targets,
+// only the bento, the type, the canary rules and the config of the targets are copied
+func (s *deploymentRevisionService) createAsNewRevision(ctx context.Context, status modelschemas.DeploymentRevisionStatus, deploymentId, creatorId uint, rollbackFromRevisionId *uint, deploymentTargets []*models.DeploymentTarget) (newDeploymentRevision *models.DeploymentRevision, newDeploymentTargets []*models.DeploymentTarget, err error) {
+	newDeploymentRevision, err = s.Create(ctx, CreateDeploymentRevisionOption{
+		CreatorId:              creatorId,
+		DeploymentId:           deploymentId,
+		Status:                 status,
+		RollbackFromRevisionId: rollbackFromRevisionId,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create deployment revision")
+	}
+
+	newDeploymentTargets = make([]*models.DeploymentTarget, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		var config *modelschemas.DeploymentTargetConfig
+		if deploymentTarget.Config != nil {
+			// the kube resource version belongs to the old revision, the new one is deployed as an update
+			config_ := *deploymentTarget.Config
+			config_.KubeResourceUid = ""
+			config_.KubeResourceVersion = ""
+			config = &config_
+		}
+		var newDeploymentTarget *models.DeploymentTarget
+		newDeploymentTarget, err = DeploymentTargetService.Create(ctx, CreateDeploymentTargetOption{
+			CreatorId:            creatorId,
+			DeploymentId:         deploymentId,
+			DeploymentRevisionId: newDeploymentRevision.ID,
+			BentoId:              deploymentTarget.BentoId,
+			Type:                 deploymentTarget.Type,
+			CanaryRules:          deploymentTarget.CanaryRules,
+			Config:               config,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "create deployment target")
+		}
+		newDeploymentTargets = append(newDeploymentTargets, newDeploymentTarget)
+	}
+	return newDeploymentRevision, newDeploymentTargets, nil
+}
+
+// deployAsNewRevision creates an active revision with copies of the targets and deploys it
+func (s *deploymentRevisionService) deployAsNewRevision(ctx context.Context, deploymentId, creatorId uint, rollbackFromRevisionId *uint, deploymentTargets []*models.DeploymentTarget) (newDeploymentRevision *models.DeploymentRevision, err error) {
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	newDeploymentRevision, newDeploymentTargets, err := s.createAsNewRevision(ctx, modelschemas.DeploymentRevisionStatusActive, deploymentId, creatorId, rollbackFromRevisionId, deploymentTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Deploy(ctx, newDeploymentRevision, newDeploymentTargets, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "deploy deployment revision")
+	}
+	return newDeploymentRevision, nil
+}
+
+// submitAsNewRevision deploys copies of the targets as a new revision like deployAsNewRevision,
+// unless the cluster requires the creator to get an approval, then the new revision is left pending
+func (s *deploymentRevisionService) submitAsNewRevision(ctx context.Context, deploymentId, creatorId uint, rollbackFromRevisionId *uint, deploymentTargets []*models.DeploymentTarget) (newDeploymentRevision *models.DeploymentRevision, err error) {
+	deployment, err := DeploymentService.Get(ctx, deploymentId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get deployment")
+	}
+	creator, err := UserService.Get(ctx, creatorId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get creator")
+	}
+	requiresApproval, err := DeploymentApprovalService.RequiresApproval(ctx, deployment, creator)
+	if err != nil {
+		return nil, err
+	}
+	if !requiresApproval {
+		return s.deployAsNewRevision(ctx, deploymentId, creatorId, rollbackFromRevisionId, deploymentTargets)
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	newDeploymentRevision, _, err = s.createAsNewRevision(ctx, models.DeploymentRevisionStatusPending, deploymentId, creatorId, rollbackFromRevisionId, deploymentTargets)
+	if err != nil {
+		return nil, err
+	}
+	err = DeploymentApprovalService.Submit(ctx, newDeploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	return newDeploymentRevision, nil
+}
+
 func (s *deploymentRevisionService) GetKubeCliSet(ctx context.Context, deploymentRevision *models.DeploymentRevision) (kubeCli *kubernetes.Clientset, restConfig *rest.Config, err error) {
 	deployment, err := DeploymentService.GetAssociatedDeployment(ctx, deploymentRevision)
 	if err != nil {
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+// deploymentTargetDiffView is what is compared between the targets of two revisions,
+// the envs are keyed by name so that reordering them is not reported as a change
+type deploymentTargetDiffView struct {
+	Bento       string                                    `json:"bento"`
+	CanaryRules *modelschemas.DeploymentTargetCanaryRules `json:"canary_rules"`
+	Envs        map[string]string                         `json:"envs"`
+	Config      *modelschemas.DeploymentTargetConfig      `json:"config"`
+}
+
+// getDeploymentTargetDiffKeys keys the targets by type, the targets of the same type are numbered in creation order
+func getDeploymentTargetDiffKeys(deploymentTargets []*models.DeploymentTarget) []string {
+	keys := make([]string, 0, len(deploymentTargets))
+	seen := make(map[modelschemas.DeploymentTargetType]int, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		key := string(deploymentTarget.Type)
+		if n := seen[deploymentTarget.Type]; n > 0 {
+			key = fmt.Sprintf("%s-%d", key, n)
+		}
+		seen[deploymentTarget.Type]++
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *deploymentRevisionService) listDeploymentTargetsForDiff(ctx context.Context, deploymentRevision *models.DeploymentRevision) ([]*models.DeploymentTarget, error) {
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(deploymentRevision.ID),
+	})
+	return deploymentTargets, errors.Wrap(err, "list deployment targets")
+}
+
+func (s *deploymentRevisionService) getDiffViews(ctx context.Context, deploymentRevision *models.DeploymentRevision) (map[string]*deploymentTargetDiffView, error) {
+	deploymentTargets, err := s.listDeploymentTargetsForDiff(ctx, deploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	return getDeploymentTargetDiffViews(ctx, deploymentTargets)
+}
+
+// getDeploymentTargetDiffViews also works with targets which are not saved yet if their bento is cached
+func getDeploymentTargetDiffViews(ctx context.Context, deploymentTargets []*models.DeploymentTarget) (map[string]*deploymentTargetDiffView, error) {
+	keys := getDeploymentTargetDiffKeys(deploymentTargets)
+	views := make(map[string]*deploymentTargetDiffView, len(deploymentTargets))
+	for idx, deploymentTarget := range deploymentTargets {
+		bento, err := BentoService.GetAssociatedBento(ctx, deploymentTarget)
+		if err != nil {
+			return nil, errors.Wrap(err, "get associated bento")
+		}
+		tag, err := BentoService.GetTag(ctx, bento)
+		if err != nil {
+			return nil, errors.Wrap(err, "get bento tag")
+		}
+		view := &deploymentTargetDiffView{
+			Bento:       string(tag),
+			CanaryRules: deploymentTarget.CanaryRules,
+		}
+		if deploymentTarget.Config != nil {
+			config := *deploymentTarget.Config
+			// the kube resource version changes on every deploy, it is not a change of the revision
+			config.KubeResourceUid = ""
+			config.KubeResourceVersion = ""
+			if config.Envs != nil {
+				view.Envs = make(map[string]string, len(*config.Envs))
+				for _, env := range *config.Envs {
+					view.Envs[env.Key] = env.Value
+				}
+				config.Envs = nil
+			}
+			view.Config = &config
+		}
+		views[keys[idx]] = view
+	}
+	return views, nil
+}
+
+// Diff compares the targets of two revisions, the paths of the diff items start with targets.<target type>
+func (s *deploymentRevisionService) Diff(ctx context.Context, oldDeploymentRevision, newDeploymentRevision *models.DeploymentRevision) ([]*utils.DiffItem, error) {
+	oldViews, err := s.getDiffViews(ctx, oldDeploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	newViews, err := s.getDiffViews(ctx, newDeploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	return utils.DiffJSON(map[string]interface{}{"targets": oldViews}, map[string]interface{}{"targets": newViews})
+}
+
+// DiffLive compares the BentoDeployment CRs rendered from the revision with the live ones to detect drift,
+// the fields which are not rendered from the revision are left to the defaulting of the operator and are not reported
+func (s *deploymentRevisionService) DiffLive(ctx context.Context, deploymentRevision *models.DeploymentRevision) ([]*utils.DiffItem, error) {
+	deployment, err := DeploymentService.GetAssociatedDeployment(ctx, deploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	yataiDeploymentComp, err := YataiComponentService.GetByName(ctx, cluster.ID, string(modelschemas.YataiComponentNameDeployment))
+	if err != nil {
+		return nil, errors.Wrap(err, "get yatai deployment component")
+	}
+	if yataiDeploymentComp.Manifest == nil || yataiDeploymentComp.Manifest.LatestCRDVersion != "v2alpha1" {
+		return nil, errors.New("diffing against the live deployment requires the v2alpha1 BentoDeployment CRD")
+	}
+	cli, err := DeploymentService.GetKubeBentoDeploymentV2alpha1Cli(ctx, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube bento deployment cli")
+	}
+
+	deploymentTargets, err := s.listDeploymentTargetsForDiff(ctx, deploymentRevision)
+	if err != nil {
+		return nil, err
+	}
+	keys := getDeploymentTargetDiffKeys(deploymentTargets)
+	res := make([]*utils.DiffItem, 0)
+	for idx, deploymentTarget := range deploymentTargets {
+		path := fmt.Sprintf("targets.%s", keys[idx])
+		expected, _, err := KubeBentoDeploymentService.transformToBentoDeploymentV2alpha1(ctx, deploymentTarget)
+		if err != nil {
+			return nil, errors.Wrap(err, "transform to kube bento deployment")
+		}
+		live, err := cli.Get(ctx, expected.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			res = append(res, &utils.DiffItem{
+				Path: path,
+				Old:  expected.Spec,
+				New:  nil,
+			})
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "get kube bento deployment %s", expected.Name)
+		}
+		items, err := utils.DiffJSON(expected.Spec, live.Spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item.Old == nil {
+				continue
+			}
+			item.Path = fmt.Sprintf("%s.spec.%s", path, item.Path)
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,601 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	apiv1 "k8s.io/api/core/v1"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+// deploymentRolloutStepLease is how long a replica owns a rollout step, the step is retried by any replica once it expires
+const deploymentRolloutStepLease = 5 * time.Minute
+
+type deploymentRolloutService struct{}
+
+var DeploymentRolloutService = deploymentRolloutService{}
+
+func (s *deploymentRolloutService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.DeploymentRollout{})
+}
+
+type CreateDeploymentRolloutOption struct {
+	CreatorId          uint
+	DeploymentRevision *models.DeploymentRevision
+	Steps              models.DeploymentRolloutSteps
+}
+
+type UpdateDeploymentRolloutOption struct {
+	DeploymentRevisionId     *uint
+	CanaryDeploymentTargetId *uint
+	CurrentStep              *int
+	Status                   *models.DeploymentRolloutStatus
+	Message                  *string
+	StepStartedAt            **time.Time
+	NextStepAt               **time.Time
+	FinishedAt               **time.Time
+}
+
+type ListDeploymentRolloutOption struct {
+	BaseListOption
+	DeploymentId *uint
+	Status       *models.DeploymentRolloutStatus
+}
+
+// Create starts a rollout of the canary target of the active revision, the weight of the first step is deployed at once as a new revision
+func (s *deploymentRolloutService) Create(ctx context.Context, opt CreateDeploymentRolloutOption) (rollout *models.DeploymentRollout, err error) {
+	if opt.DeploymentRevision.Status != modelschemas.DeploymentRevisionStatusActive {
+		return nil, errors.Errorf("deployment revision %s is not active", opt.DeploymentRevision.Uid)
+	}
+	if len(opt.Steps) == 0 {
+		return nil, errors.New("the rollout has no steps")
+	}
+	var lastWeight uint
+	for idx, step := range opt.Steps {
+		if step == nil {
+			return nil, errors.Errorf("step %d is empty", idx)
+		}
+		if step.Weight > 100 {
+			return nil, errors.Errorf("the weight %d of step %d is greater than 100", step.Weight, idx)
+		}
+		if step.Weight < lastWeight {
+			return nil, errors.Errorf("the weight %d of step %d is lower than the weight of the previous step", step.Weight, idx)
+		}
+		lastWeight = step.Weight
+	}
+
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(opt.DeploymentRevision.ID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployment targets")
+	}
+	var canaryDeploymentTarget *models.DeploymentTarget
+	hasStable := false
+	for _, deploymentTarget := range deploymentTargets {
+		if deploymentTarget.Type != modelschemas.DeploymentTargetTypeCanary {
+			hasStable = true
+			continue
+		}
+		if canaryDeploymentTarget != nil {
+			return nil, errors.Errorf("deployment revision %s has more than one canary target", opt.DeploymentRevision.Uid)
+		}
+		canaryDeploymentTarget = deploymentTarget
+	}
+	if canaryDeploymentTarget == nil || !hasStable {
+		return nil, errors.Errorf("deployment revision %s should have a stable target and a canary target", opt.DeploymentRevision.Uid)
+	}
+
+	_, err = s.GetRunning(ctx, opt.DeploymentRevision.DeploymentId)
+	if err == nil {
+		return nil, errors.New("the deployment already has a running rollout")
+	}
+	if !utils.IsNotFound(err) {
+		return nil, errors.Wrap(err, "get running rollout")
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { df(err) }()
+
+	now := time.Now()
+	nextStepAt := now.Add(time.Duration(opt.Steps[0].PauseSeconds) * time.Second)
+	rollout = &models.DeploymentRollout{
+		CreatorAssociate: models.CreatorAssociate{
+			CreatorId: opt.CreatorId,
+		},
+		DeploymentAssociate: models.DeploymentAssociate{
+			DeploymentId: opt.DeploymentRevision.DeploymentId,
+		},
+		DeploymentRevisionAssociate: models.DeploymentRevisionAssociate{
+			DeploymentRevisionId: opt.DeploymentRevision.ID,
+		},
+		CanaryDeploymentTargetId: canaryDeploymentTarget.ID,
+		Steps:                    opt.Steps,
+		CurrentStep:              0,
+		Status:                   models.DeploymentRolloutStatusRunning,
+		StepStartedAt:            &now,
+		NextStepAt:               &nextStepAt,
+	}
+	err = mustGetSession(ctx).Create(rollout).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "create deployment rollout")
+	}
+
+	err = s.setCanaryWeight(ctx, rollout, opt.Steps[0].Weight)
+	return rollout, err
+}
+
+func (s *deploymentRolloutService) Update(ctx context.Context, rollout *models.DeploymentRollout, opt UpdateDeploymentRolloutOption) (*models.DeploymentRollout, error) {
+	var err error
+	updaters := make(map[string]interface{})
+	if opt.DeploymentRevisionId != nil {
+		updaters["deployment_revision_id"] = *opt.DeploymentRevisionId
+		defer func() {
+			if err == nil {
+				rollout.DeploymentRevisionId = *opt.DeploymentRevisionId
+			}
+		}()
+	}
+	if opt.CanaryDeploymentTargetId != nil {
+		updaters["canary_deployment_target_id"] = *opt.CanaryDeploymentTargetId
+		defer func() {
+			if err == nil {
+				rollout.CanaryDeploymentTargetId = *opt.CanaryDeploymentTargetId
+			}
+		}()
+	}
+	if opt.CurrentStep != nil {
+		updaters["current_step"] = *opt.CurrentStep
+		defer func() {
+			if err == nil {
+				rollout.CurrentStep = *opt.CurrentStep
+			}
+		}()
+	}
+	if opt.Status != nil {
+		updaters["status"] = *opt.Status
+		defer func() {
+			if err == nil {
+				rollout.Status = *opt.Status
+			}
+		}()
+	}
+	if opt.Message != nil {
+		updaters["message"] = *opt.Message
+		defer func() {
+			if err == nil {
+				rollout.Message = *opt.Message
+			}
+		}()
+	}
+	if opt.StepStartedAt != nil {
+		updaters["step_started_at"] = *opt.StepStartedAt
+		defer func() {
+			if err == nil {
+				rollout.StepStartedAt = *opt.StepStartedAt
+			}
+		}()
+	}
+	if opt.NextStepAt != nil {
+		updaters["next_step_at"] = *opt.NextStepAt
+		defer func() {
+			if err == nil {
+				rollout.NextStepAt = *opt.NextStepAt
+			}
+		}()
+	}
+	if opt.FinishedAt != nil {
+		updaters["finished_at"] = *opt.FinishedAt
+		defer func() {
+			if err == nil {
+				rollout.FinishedAt = *opt.FinishedAt
+			}
+		}()
+	}
+
+	if len(updaters) == 0 {
+		return rollout, nil
+	}
+
+	err = s.getBaseDB(ctx).Where("id = ?", rollout.ID).Updates(updaters).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.publish(ctx, rollout)
+	return rollout, err
+}
+
+func (s *deploymentRolloutService) publish(ctx context.Context, rollout *models.DeploymentRollout) error {
+	return NotificationService.Publish(ctx, models.ResourceTypeDeploymentRollout, rollout.Uid)
+}
+
+func (s *deploymentRolloutService) Get(ctx context.Context, id uint) (*models.DeploymentRollout, error) {
+	var rollout models.DeploymentRollout
+	err := s.getBaseDB(ctx).Where("id = ?", id).First(&rollout).Error
+	if err != nil {
+		return nil, err
+	}
+	if rollout.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &rollout, nil
+}
+
+func (s *deploymentRolloutService) GetByUid(ctx context.Context, uid string) (*models.DeploymentRollout, error) {
+	var rollout models.DeploymentRollout
+	err := s.getBaseDB(ctx).Where("uid = ?", uid).First(&rollout).Error
+	if err != nil {
+		return nil, err
+	}
+	if rollout.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &rollout, nil
+}
+
+func (s *deploymentRolloutService) GetRunning(ctx context.Context, deploymentId uint) (*models.DeploymentRollout, error) {
+	var rollout models.DeploymentRollout
+	err := s.getBaseDB(ctx).Where("deployment_id = ?", deploymentId).Where("status = ?", models.DeploymentRolloutStatusRunning).First(&rollout).Error
+	if err != nil {
+		return nil, err
+	}
+	if rollout.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &rollout, nil
+}
+
+func (s *deploymentRolloutService) ListByUids(ctx context.Context, uids []string) ([]*models.DeploymentRollout, error) {
+	rollouts := make([]*models.DeploymentRollout, 0, len(uids))
+	if len(uids) == 0 {
+		return rollouts, nil
+	}
+	err := s.getBaseDB(ctx).Where("uid in (?)", uids).Find(&rollouts).Error
+	return rollouts, err
+}
+
+func (s *deploymentRolloutService) List(ctx context.Context, opt ListDeploymentRolloutOption) ([]*models.DeploymentRollout, uint, error) {
+	query := s.getBaseDB(ctx)
+	if opt.DeploymentId != nil {
+		query = query.Where("deployment_id = ?", *opt.DeploymentId)
+	}
+	if opt.Status != nil {
+		query = query.Where("status = ?", *opt.Status)
+	}
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	rollouts := make([]*models.DeploymentRollout, 0)
+	query = opt.BindQueryWithLimit(query).Order("id DESC")
+	err = query.Find(&rollouts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return rollouts, uint(total), err
+}
+
+// getCanaryRulesWithWeight returns a copy of the canary rules whose weight rule is replaced by the weight
+func getCanaryRulesWithWeight(canaryRules *modelschemas.DeploymentTargetCanaryRules, weight uint) *modelschemas.DeploymentTargetCanaryRules {
+	res := make(modelschemas.DeploymentTargetCanaryRules, 0)
+	if canaryRules != nil {
+		for _, rule := range *canaryRules {
+			if rule.Type == modelschemas.DeploymentTargetCanaryRuleTypeWeight {
+				continue
+			}
+			res = append(res, rule)
+		}
+	}
+	res = append(res, &modelschemas.DeploymentTargetCanaryRule{
+		Type:   modelschemas.DeploymentTargetCanaryRuleTypeWeight,
+		Weight: &weight,
+	})
+	return &res
+}
+
+// setCanaryWeight deploys the targets of the rollout revision as a new revision whose canary has the weight,
+// the deployed revisions are never changed, the rollout follows the new revision and its canary target
+func (s *deploymentRolloutService) setCanaryWeight(ctx context.Context, rollout *models.DeploymentRollout, weight uint) (err error) {
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(rollout.DeploymentRevisionId),
+	})
+	if err != nil {
+		return errors.Wrap(err, "list deployment targets")
+	}
+	newDeploymentTargets := make([]*models.DeploymentTarget, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		if deploymentTarget.ID == rollout.CanaryDeploymentTargetId {
+			canaryDeploymentTarget := *deploymentTarget
+			canaryDeploymentTarget.CanaryRules = getCanaryRulesWithWeight(deploymentTarget.CanaryRules, weight)
+			deploymentTarget = &canaryDeploymentTarget
+		}
+		newDeploymentTargets = append(newDeploymentTargets, deploymentTarget)
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { df(err) }()
+
+	newDeploymentRevision, err := DeploymentRevisionService.deployAsNewRevision(ctx, rollout.DeploymentId, rollout.CreatorId, nil, newDeploymentTargets)
+	if err != nil {
+		return errors.Wrapf(err, "deploy canary with weight %d", weight)
+	}
+	canaryType := modelschemas.DeploymentTargetTypeCanary
+	canaryDeploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(newDeploymentRevision.ID),
+		Type:                 &canaryType,
+	})
+	if err != nil {
+		return errors.Wrap(err, "list canary deployment targets")
+	}
+	if len(canaryDeploymentTargets) != 1 {
+		return errors.Errorf("deployment revision %s should have one canary target", newDeploymentRevision.Uid)
+	}
+	_, err = s.Update(ctx, rollout, UpdateDeploymentRolloutOption{
+		DeploymentRevisionId:     utils.UintPtr(newDeploymentRevision.ID),
+		CanaryDeploymentTargetId: utils.UintPtr(canaryDeploymentTargets[0].ID),
+	})
+	return errors.Wrap(err, "update deployment rollout")
+}
+
+// CheckCanaryHealth returns the reason why the canary is unhealthy, or an empty reason when it is healthy,
+// the canary pods should all be running and have no warning event since the step started
+func (s *deploymentRolloutService) CheckCanaryHealth(ctx context.Context, rollout *models.DeploymentRollout) (reason string, err error) {
+	deployment, err := DeploymentService.GetAssociatedDeployment(ctx, rollout)
+	if err != nil {
+		return "", errors.Wrap(err, "get associated deployment")
+	}
+	cluster, err := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err != nil {
+		return "", errors.Wrap(err, "get associated cluster")
+	}
+	_, podLister, err := GetPodInformer(ctx, cluster, DeploymentService.GetKubeNamespace(deployment))
+	if err != nil {
+		return "", errors.Wrap(err, "get pod informer")
+	}
+	pods, err := KubePodService.ListPodsByDeployment(ctx, podLister, deployment)
+	if err != nil {
+		return "", errors.Wrap(err, "list pods")
+	}
+	canaryPods := make([]apiv1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Pod.Labels[commonconsts.KubeLabelYataiBentoDeploymentTargetType] != string(modelschemas.DeploymentTargetTypeCanary) {
+			continue
+		}
+		if pod.Status.Status != modelschemas.KubePodActualStatusRunning {
+			return fmt.Sprintf("canary pod %s is %s", pod.Pod.Name, pod.Status.Status), nil
+		}
+		canaryPods = append(canaryPods, pod.Pod)
+	}
+	if len(canaryPods) == 0 {
+		return "no canary pod is running", nil
+	}
+
+	// the warnings of the pod statuses only cover the pods which are not ready, a pod which restarted since is still reported
+	events, err := KubeEventService.ListAllKubeEventsByDeployment(ctx, deployment)
+	if err != nil {
+		return "", errors.Wrap(err, "list kube events")
+	}
+	warningsMapping := KubeEventService.GetKubePodsEventsMapping(KubeEventService.FilterWarningKubeEvents(events), canaryPods)
+	for _, pod := range canaryPods {
+		for _, warning := range warningsMapping[pod.UID] {
+			if rollout.StepStartedAt != nil && warning.LastTimestamp.Time.Before(*rollout.StepStartedAt) {
+				continue
+			}
+			return fmt.Sprintf("canary pod %s has a warning event %s: %s", pod.Name, warning.Reason, warning.Message), nil
+		}
+	}
+	return "", nil
+}
+
+// finish replaces the active revision by a new one with the given targets and ends the rollout with the status.
+// A promotion waits for an approval when the cluster requires the operator to get one,
+// a rollback or an abort only removes the canary and is deployed at once, so that an unhealthy canary stops serving.
+func (s *deploymentRolloutService) finish(ctx context.Context, rollout *models.DeploymentRollout, operatorId uint, status models.DeploymentRolloutStatus, message string) (err error) {
+	deploymentRevision, err := DeploymentRevisionService.GetAssociatedDeploymentRevision(ctx, rollout)
+	if err != nil {
+		return errors.Wrap(err, "get associated deployment revision")
+	}
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(deploymentRevision.ID),
+	})
+	if err != nil {
+		return errors.Wrap(err, "list deployment targets")
+	}
+	newDeploymentTargets := make([]*models.DeploymentTarget, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		isCanary := deploymentTarget.ID == rollout.CanaryDeploymentTargetId
+		if status == models.DeploymentRolloutStatusPromoted {
+			// the canary becomes the only stable target
+			if !isCanary {
+				continue
+			}
+			deploymentTarget.Type = modelschemas.DeploymentTargetTypeStable
+			deploymentTarget.CanaryRules = nil
+		} else if isCanary {
+			continue
+		}
+		newDeploymentTargets = append(newDeploymentTargets, deploymentTarget)
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { df(err) }()
+
+	var newDeploymentRevision *models.DeploymentRevision
+	if status == models.DeploymentRolloutStatusPromoted {
+		newDeploymentRevision, err = DeploymentRevisionService.submitAsNewRevision(ctx, rollout.DeploymentId, operatorId, nil, newDeploymentTargets)
+	} else {
+		newDeploymentRevision, err = DeploymentRevisionService.deployAsNewRevision(ctx, rollout.DeploymentId, operatorId, nil, newDeploymentTargets)
+	}
+	if err != nil {
+		return err
+	}
+	if pendingApprovalMessage := getPendingApprovalMessage(newDeploymentRevision); pendingApprovalMessage != "" {
+		message = fmt.Sprintf("%s, %s", message, pendingApprovalMessage)
+	}
+
+	now := time.Now()
+	nowPtr := &now
+	var nilTime *time.Time
+	_, err = s.Update(ctx, rollout, UpdateDeploymentRolloutOption{
+		Status:     &status,
+		Message:    &message,
+		NextStepAt: &nilTime,
+		FinishedAt: &nowPtr,
+	})
+	return errors.Wrap(err, "update deployment rollout")
+}
+
+func (s *deploymentRolloutService) checkRunning(ctx context.Context, rollout *models.DeploymentRollout) error {
+	if rollout.Status != models.DeploymentRolloutStatusRunning {
+		return errors.Errorf("deployment rollout %s is already %s", rollout.Uid, rollout.Status)
+	}
+	deploymentRevision, err := DeploymentRevisionService.GetAssociatedDeploymentRevision(ctx, rollout)
+	if err != nil {
+		return errors.Wrap(err, "get associated deployment revision")
+	}
+	if deploymentRevision.Status != modelschemas.DeploymentRevisionStatusActive {
+		return errors.Errorf("deployment revision %s of the rollout is no longer active", deploymentRevision.Uid)
+	}
+	return nil
+}
+
+// Promote makes the canary the stable target at once, whatever the remaining steps
+func (s *deploymentRolloutService) Promote(ctx context.Context, rollout *models.DeploymentRollout, operatorId uint) error {
+	if err := s.checkRunning(ctx, rollout); err != nil {
+		return err
+	}
+	return s.finish(ctx, rollout, operatorId, models.DeploymentRolloutStatusPromoted, "promoted manually")
+}
+
+// Abort removes the canary and keeps the stable targets
+func (s *deploymentRolloutService) Abort(ctx context.Context, rollout *models.DeploymentRollout, operatorId uint) error {
+	if err := s.checkRunning(ctx, rollout); err != nil {
+		return err
+	}
+	return s.finish(ctx, rollout, operatorId, models.DeploymentRolloutStatusAborted, "aborted manually")
+}
+
+// claim takes the lease of the current step, it fails when another replica has taken it first
+func (s *deploymentRolloutService) claim(ctx context.Context, rollout *models.DeploymentRollout) (bool, error) {
+	leaseUntil := time.Now().Add(deploymentRolloutStepLease)
+	res := s.getBaseDB(ctx).
+		Where("id = ?", rollout.ID).
+		Where("status = ?", models.DeploymentRolloutStatusRunning).
+		Where("next_step_at = ?", rollout.NextStepAt).
+		Update("next_step_at", leaseUntil)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+	rollout.NextStepAt = &leaseUntil
+	return true, nil
+}
+
+// Advance checks the canary once the current step is over, then either moves to the next step,
+// promotes the canary after the last step, or rolls back to the stable targets when the canary is unhealthy
+func (s *deploymentRolloutService) Advance(ctx context.Context, rollout *models.DeploymentRollout) error {
+	claimed, err := s.claim(ctx, rollout)
+	if err != nil {
+		return errors.Wrap(err, "claim deployment rollout")
+	}
+	if !claimed {
+		return nil
+	}
+
+	deploymentRevision, err := DeploymentRevisionService.GetAssociatedDeploymentRevision(ctx, rollout)
+	if err != nil {
+		return errors.Wrap(err, "get associated deployment revision")
+	}
+	if deploymentRevision.Status != modelschemas.DeploymentRevisionStatusActive {
+		// the deployment has been changed or rolled back meanwhile, there is nothing left to roll out
+		now := time.Now()
+		nowPtr := &now
+		var nilTime *time.Time
+		_, err = s.Update(ctx, rollout, UpdateDeploymentRolloutOption{
+			Status:     models.DeploymentRolloutStatusPtr(models.DeploymentRolloutStatusAborted),
+			Message:    utils.StringPtr("the deployment revision of the rollout has been replaced"),
+			NextStepAt: &nilTime,
+			FinishedAt: &nowPtr,
+		})
+		return errors.Wrap(err, "update deployment rollout")
+	}
+
+	reason, err := s.CheckCanaryHealth(ctx, rollout)
+	if err != nil {
+		return errors.Wrap(err, "check canary health")
+	}
+	if reason != "" {
+		return s.finish(ctx, rollout, rollout.CreatorId, models.DeploymentRolloutStatusRolledBack, reason)
+	}
+
+	if rollout.CurrentStep+1 >= len(rollout.Steps) {
+		return s.finish(ctx, rollout, rollout.CreatorId, models.DeploymentRolloutStatusPromoted, "all the steps passed")
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { df(err) }()
+
+	step := rollout.CurrentStep + 1
+	err = s.setCanaryWeight(ctx, rollout, rollout.Steps[step].Weight)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	nowPtr := &now
+	nextStepAt := now.Add(time.Duration(rollout.Steps[step].PauseSeconds) * time.Second)
+	nextStepAtPtr := &nextStepAt
+	_, err = s.Update(ctx, rollout, UpdateDeploymentRolloutOption{
+		CurrentStep:   &step,
+		StepStartedAt: &nowPtr,
+		NextStepAt:    &nextStepAtPtr,
+	})
+	return errors.Wrap(err, "update deployment rollout")
+}
+
+// AdvanceAll advances the running rollouts whose current step is over
+func (s *deploymentRolloutService) AdvanceAll(ctx context.Context) error {
+	rollouts := make([]*models.DeploymentRollout, 0)
+	err := s.getBaseDB(ctx).
+		Where("status = ?", models.DeploymentRolloutStatusRunning).
+		Where("next_step_at <= ?", time.Now()).
+		Order("id ASC").
+		Find(&rollouts).Error
+	if err != nil {
+		return errors.Wrap(err, "list due deployment rollouts")
+	}
+	for _, rollout := range rollouts {
+		if err := s.Advance(ctx, rollout); err != nil {
+			logrus.Errorf("advance deployment rollout %s: %s", rollout.Uid, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+)
+
+func TestGetCanaryRulesWithWeight(t *testing.T) {
+	oldWeight := uint(10)
+	header := "X-Canary"
+	canaryRules := &modelschemas.DeploymentTargetCanaryRules{
+		{
+			Type:   modelschemas.DeploymentTargetCanaryRuleTypeWeight,
+			Weight: &oldWeight,
+		},
+		{
+			Type:   modelschemas.DeploymentTargetCanaryRuleTypeHeader,
+			Header: &header,
+		},
+	}
+
+	res := getCanaryRulesWithWeight(canaryRules, 50)
+	if len(*res) != 2 {
+		t.Fatalf("expected the header rule and the weight rule, got %d rules", len(*res))
+	}
+	for _, rule := range *res {
+		switch rule.Type {
+		case modelschemas.DeploymentTargetCanaryRuleTypeWeight:
+			if *rule.Weight != 50 {
+				t.Errorf("expected the weight 50, got %d", *rule.Weight)
+			}
+		case modelschemas.DeploymentTargetCanaryRuleTypeHeader:
+			if *rule.Header != header {
+				t.Errorf("expected the header rule to be kept, got %s", *rule.Header)
+			}
+		default:
+			t.Errorf("unexpected rule %s", rule.Type)
+		}
+	}
+
+	// the rules of the deployed revision are left untouched
+	if len(*canaryRules) != 2 || *(*canaryRules)[0].Weight != 10 {
+		t.Errorf("expected the original canary rules to be unchanged, got %+v", *canaryRules)
+	}
+
+	res = getCanaryRulesWithWeight(nil, 20)
+	if len(*res) != 1 || *(*res)[0].Weight != 20 {
+		t.Errorf("expected a single weight rule without canary rules, got %+v", *res)
+	}
+}
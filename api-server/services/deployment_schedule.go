@@ -0,0 +1,618 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tianweidut/cron"
+	"gorm.io/gorm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/utils/pointer"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+type deploymentScheduleService struct{}
+
+var DeploymentScheduleService = deploymentScheduleService{}
+
+func (s *deploymentScheduleService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.DeploymentSchedule{})
+}
+
+type CreateDeploymentScheduleOption struct {
+	CreatorId    uint
+	DeploymentId uint
+	Name         string
+	SleepCron    string
+	WakeCron     string
+	Timezone     string
+	Enabled      bool
+}
+
+type UpdateDeploymentScheduleOption struct {
+	SleepCron *string
+	WakeCron  *string
+	Timezone  *string
+	Enabled   *bool
+}
+
+type ListDeploymentScheduleOption struct {
+	BaseListOption
+	DeploymentId *uint
+}
+
+// getDeploymentScheduleNextTimes returns the next sleep and wake up times after now
+func getDeploymentScheduleNextTimes(sleepCron, wakeCron, timezone string, now time.Time) (nextSleepAt, nextWakeAt time.Time, err error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		err = errors.Wrapf(err, "load timezone %s", timezone)
+		return
+	}
+	sleepSchedule, err := cron.ParseStandard(sleepCron)
+	if err != nil {
+		err = errors.Wrapf(err, "parse sleep cron %s", sleepCron)
+		return
+	}
+	wakeSchedule, err := cron.ParseStandard(wakeCron)
+	if err != nil {
+		err = errors.Wrapf(err, "parse wake cron %s", wakeCron)
+		return
+	}
+	now = now.In(location)
+	nextSleepAt = sleepSchedule.Next(now)
+	nextWakeAt = wakeSchedule.Next(now)
+	if nextSleepAt.IsZero() || nextWakeAt.IsZero() {
+		err = errors.New("the cron specs never run")
+	}
+	return
+}
+
+func (s *deploymentScheduleService) Create(ctx context.Context, opt CreateDeploymentScheduleOption) (*models.DeploymentSchedule, error) {
+	if opt.Timezone == "" {
+		opt.Timezone = "UTC"
+	}
+	nextSleepAt, nextWakeAt, err := getDeploymentScheduleNextTimes(opt.SleepCron, opt.WakeCron, opt.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule := &models.DeploymentSchedule{
+		ResourceMixin: models.ResourceMixin{
+			Name: opt.Name,
+		},
+		CreatorAssociate: models.CreatorAssociate{
+			CreatorId: opt.CreatorId,
+		},
+		DeploymentAssociate: models.DeploymentAssociate{
+			DeploymentId: opt.DeploymentId,
+		},
+		SleepCron:   opt.SleepCron,
+		WakeCron:    opt.WakeCron,
+		Timezone:    opt.Timezone,
+		Enabled:     opt.Enabled,
+		State:       models.DeploymentScheduleStateAwake,
+		NextSleepAt: nextSleepAt,
+		NextWakeAt:  nextWakeAt,
+	}
+	err = mustGetSession(ctx).Create(schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *deploymentScheduleService) Update(ctx context.Context, schedule *models.DeploymentSchedule, opt UpdateDeploymentScheduleOption) (*models.DeploymentSchedule, error) {
+	var err error
+	updaters := make(map[string]interface{})
+	sleepCron, wakeCron, timezone := schedule.SleepCron, schedule.WakeCron, schedule.Timezone
+	if opt.SleepCron != nil {
+		sleepCron = *opt.SleepCron
+		updaters["sleep_cron"] = sleepCron
+		defer func() {
+			if err == nil {
+				schedule.SleepCron = sleepCron
+			}
+		}()
+	}
+	if opt.WakeCron != nil {
+		wakeCron = *opt.WakeCron
+		updaters["wake_cron"] = wakeCron
+		defer func() {
+			if err == nil {
+				schedule.WakeCron = wakeCron
+			}
+		}()
+	}
+	if opt.Timezone != nil {
+		timezone = *opt.Timezone
+		updaters["timezone"] = timezone
+		defer func() {
+			if err == nil {
+				schedule.Timezone = timezone
+			}
+		}()
+	}
+	if opt.Enabled != nil {
+		updaters["enabled"] = *opt.Enabled
+		defer func() {
+			if err == nil {
+				schedule.Enabled = *opt.Enabled
+			}
+		}()
+	}
+	if len(updaters) == 0 {
+		return schedule, nil
+	}
+	// the missed runs of a disabled schedule are not caught up when it is enabled again
+	nextSleepAt, nextWakeAt, err := getDeploymentScheduleNextTimes(sleepCron, wakeCron, timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	updaters["next_sleep_at"] = nextSleepAt
+	updaters["next_wake_at"] = nextWakeAt
+	defer func() {
+		if err == nil {
+			schedule.NextSleepAt = nextSleepAt
+			schedule.NextWakeAt = nextWakeAt
+		}
+	}()
+
+	err = s.getBaseDB(ctx).Where("id = ?", schedule.ID).Updates(updaters).Error
+	return schedule, err
+}
+
+func (s *deploymentScheduleService) Get(ctx context.Context, id uint) (*models.DeploymentSchedule, error) {
+	var schedule models.DeploymentSchedule
+	err := getBaseQuery(ctx, s).Where("id = ?", id).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	if schedule.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &schedule, nil
+}
+
+func (s *deploymentScheduleService) GetByUid(ctx context.Context, uid string) (*models.DeploymentSchedule, error) {
+	var schedule models.DeploymentSchedule
+	err := getBaseQuery(ctx, s).Where("uid = ?", uid).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	if schedule.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &schedule, nil
+}
+
+func (s *deploymentScheduleService) List(ctx context.Context, opt ListDeploymentScheduleOption) ([]*models.DeploymentSchedule, uint, error) {
+	query := s.getBaseDB(ctx)
+	if opt.DeploymentId != nil {
+		query = query.Where("deployment_id = ?", *opt.DeploymentId)
+	}
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	schedules := make([]*models.DeploymentSchedule, 0)
+	query = opt.BindQueryWithLimit(query).Order("id DESC")
+	err = query.Find(&schedules).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return schedules, uint(total), err
+}
+
+func (s *deploymentScheduleService) Delete(ctx context.Context, schedule *models.DeploymentSchedule) (*models.DeploymentSchedule, error) {
+	err := mustGetSession(ctx).Delete(schedule).Error
+	return schedule, err
+}
+
+// listDue lists the enabled schedules with a sleep or a wake up due
+func (s *deploymentScheduleService) listDue(ctx context.Context) ([]*models.DeploymentSchedule, error) {
+	now := time.Now()
+	schedules := make([]*models.DeploymentSchedule, 0)
+	err := s.getBaseDB(ctx).Where("enabled").Where("next_sleep_at <= ? OR next_wake_at <= ?", now, now).Order("id").Find(&schedules).Error
+	return schedules, err
+}
+
+// claim moves the next times of the schedule forward, only one replica succeeds for a given run
+func (s *deploymentScheduleService) claim(ctx context.Context, schedule *models.DeploymentSchedule, now time.Time) (bool, error) {
+	nextSleepAt, nextWakeAt, err := getDeploymentScheduleNextTimes(schedule.SleepCron, schedule.WakeCron, schedule.Timezone, now)
+	if err != nil {
+		return false, err
+	}
+	res := s.getBaseDB(ctx).
+		Where("id = ?", schedule.ID).
+		Where("next_sleep_at = ?", schedule.NextSleepAt).
+		Where("next_wake_at = ?", schedule.NextWakeAt).
+		Updates(map[string]interface{}{
+			"next_sleep_at": nextSleepAt,
+			"next_wake_at":  nextWakeAt,
+			"last_run_at":   now,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+	schedule.NextSleepAt = nextSleepAt
+	schedule.NextWakeAt = nextWakeAt
+	schedule.LastRunAt = &now
+	return true, nil
+}
+
+// Run runs the last due action of the schedule, the runs missed while the api server was down are not replayed
+func (s *deploymentScheduleService) Run(ctx context.Context, schedule *models.DeploymentSchedule) error {
+	now := time.Now()
+	sleepDue := !schedule.NextSleepAt.After(now)
+	wakeDue := !schedule.NextWakeAt.After(now)
+	if !sleepDue && !wakeDue {
+		return nil
+	}
+	sleep := sleepDue && (!wakeDue || schedule.NextSleepAt.After(schedule.NextWakeAt))
+
+	claimed, err := s.claim(ctx, schedule, now)
+	if err != nil {
+		return errors.Wrap(err, "claim deployment schedule")
+	}
+	if !claimed {
+		return nil
+	}
+
+	var message, operationName string
+	var deploymentRevision *models.DeploymentRevision
+	if sleep {
+		operationName = "scaled to zero by schedule"
+		deploymentRevision, message, err = s.sleep(ctx, schedule)
+		if err == nil && message != "" {
+			operationName = "skipped scheduled scale to zero"
+		}
+		if err == nil && deploymentRevision != nil && deploymentRevision.Status == models.DeploymentRevisionStatusPending {
+			operationName = "requested approval for scheduled scale to zero"
+		}
+	} else {
+		operationName = "woke up by schedule"
+		deploymentRevision, message, err = s.wake(ctx, schedule)
+		if err == nil && message != "" {
+			operationName = "skipped scheduled wake up"
+		}
+		if err == nil && deploymentRevision != nil && deploymentRevision.Status == models.DeploymentRevisionStatusPending {
+			operationName = "requested approval for scheduled wake up"
+		}
+	}
+	if err == nil && deploymentRevision != nil {
+		message = getPendingApprovalMessage(deploymentRevision)
+	}
+	if err != nil {
+		message = err.Error()
+	}
+	err_ := s.getBaseDB(ctx).Where("id = ?", schedule.ID).Update("message", message).Error
+	if err_ != nil {
+		logrus.Errorf("update deployment schedule %d message: %v", schedule.ID, err_)
+	}
+	schedule.Message = message
+	s.createEvent(ctx, schedule, operationName, err)
+	return err
+}
+
+func (s *deploymentScheduleService) createEvent(ctx context.Context, schedule *models.DeploymentSchedule, operationName string, err error) {
+	deployment, err_ := DeploymentService.GetAssociatedDeployment(ctx, schedule)
+	if err_ != nil {
+		logrus.Errorf("get deployment schedule associated deployment: %v", err_)
+		return
+	}
+	cluster, err_ := ClusterService.GetAssociatedCluster(ctx, deployment)
+	if err_ != nil {
+		logrus.Errorf("get associated cluster: %v", err_)
+		return
+	}
+	createEventOpt := CreateEventOption{
+		CreatorId:      schedule.CreatorId,
+		OrganizationId: &cluster.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeDeployment,
+		ResourceId:     deployment.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  operationName,
+	}
+	if err != nil {
+		createEventOpt.Status = modelschemas.EventStatusFailed
+	}
+	if _, err_ := EventService.Create(ctx, createEventOpt); err_ != nil {
+		logrus.Errorf("create event failed: %v", err_)
+	}
+}
+
+func (s *deploymentScheduleService) getActiveDeploymentRevision(ctx context.Context, deploymentId uint) (*models.DeploymentRevision, error) {
+	deploymentRevisions, _, err := DeploymentRevisionService.List(ctx, ListDeploymentRevisionOption{
+		DeploymentId: utils.UintPtr(deploymentId),
+		Status:       modelschemas.DeploymentRevisionStatusPtr(modelschemas.DeploymentRevisionStatusActive),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list active deployment revisions")
+	}
+	if len(deploymentRevisions) == 0 {
+		return nil, nil
+	}
+	return deploymentRevisions[0], nil
+}
+
+// scaleToZeroHPAConf is only used by the sleep of the schedules, the dry run rejects the hpa confs with no replicas
+func scaleToZeroHPAConf(hpaConf *modelschemas.DeploymentTargetHPAConf) *modelschemas.DeploymentTargetHPAConf {
+	var hpaConf_ modelschemas.DeploymentTargetHPAConf
+	if hpaConf != nil {
+		hpaConf_ = *hpaConf
+	}
+	zero := int32(0)
+	hpaConf_.MinReplicas = &zero
+	hpaConf_.MaxReplicas = &zero
+	return &hpaConf_
+}
+
+// getAsleepDeploymentTargetConfig returns a copy of the config with the api server and the runners scaled to zero
+func getAsleepDeploymentTargetConfig(config *modelschemas.DeploymentTargetConfig) *modelschemas.DeploymentTargetConfig {
+	var config_ modelschemas.DeploymentTargetConfig
+	if config != nil {
+		config_ = *config
+	}
+	config_.HPAConf = scaleToZeroHPAConf(config_.HPAConf)
+	runners := make(map[string]modelschemas.DeploymentTargetRunnerConfig, len(config_.Runners))
+	for name, runner := range config_.Runners {
+		runner.HPAConf = scaleToZeroHPAConf(runner.HPAConf)
+		runners[name] = runner
+	}
+	config_.Runners = runners
+	return &config_
+}
+
+func isHPAConfScaledToZero(hpaConf *modelschemas.DeploymentTargetHPAConf) bool {
+	return hpaConf != nil && hpaConf.MaxReplicas != nil && *hpaConf.MaxReplicas == 0
+}
+
+// getKubeHPAConf renders the hpa conf of a target scaled to zero with one replica, because the kube HPAs need at least one replica.
+// The Deployments of the target are scaled to zero by scaleKubeDeployments instead, which makes their HPAs inactive.
+func getKubeHPAConf(hpaConf *modelschemas.DeploymentTargetHPAConf) *modelschemas.DeploymentTargetHPAConf {
+	if !isHPAConfScaledToZero(hpaConf) {
+		return hpaConf
+	}
+	hpaConf_ := *hpaConf
+	hpaConf_.MinReplicas = pointer.Int32(1)
+	hpaConf_.MaxReplicas = pointer.Int32(1)
+	return &hpaConf_
+}
+
+// isDeploymentTargetConfigAsleep tells whether the api server or a runner of the target is scaled to zero
+func isDeploymentTargetConfigAsleep(config *modelschemas.DeploymentTargetConfig) bool {
+	if config == nil {
+		return false
+	}
+	if isHPAConfScaledToZero(config.HPAConf) {
+		return true
+	}
+	for _, runner := range config.Runners {
+		if isHPAConfScaledToZero(runner.HPAConf) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAsleepDeploymentTargetTypes returns the types of the targets which are scaled to zero,
+// the kube Deployments of the targets of these types are left at zero until a deploy scales them back up
+func getAsleepDeploymentTargetTypes(deploymentTargets []*models.DeploymentTarget) map[modelschemas.DeploymentTargetType]bool {
+	res := make(map[modelschemas.DeploymentTargetType]bool)
+	for _, deploymentTarget := range deploymentTargets {
+		if isDeploymentTargetConfigAsleep(deploymentTarget.Config) {
+			res[deploymentTarget.Type] = true
+		}
+	}
+	return res
+}
+
+// getKubeTargetTypeLabelValues returns the values of the target type label of the kube Deployments of the target type,
+// the operator labels the Deployments of the stable target as production ones
+func getKubeTargetTypeLabelValues(deploymentTargetType modelschemas.DeploymentTargetType) []string {
+	if deploymentTargetType == modelschemas.DeploymentTargetTypeStable {
+		return []string{string(deploymentTargetType), "production"}
+	}
+	return []string{string(deploymentTargetType)}
+}
+
+// scaleKubeDeployments scales the kube Deployments of the target to zero when their component is scaled to zero,
+// and scales the ones left at zero back to their min replicas otherwise, the HPAs do not scale a Deployment with no replicas.
+// The Deployments which the operator has not created yet are left to it.
+func scaleKubeDeployments(ctx context.Context, cli appsv1client.DeploymentInterface, deploymentName string, deploymentTargetType modelschemas.DeploymentTargetType, config *modelschemas.DeploymentTargetConfig) error {
+	kubeDeployments, err := cli.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s = %s, %s in (%s)", commonconsts.KubeLabelYataiBentoDeployment, deploymentName, commonconsts.KubeLabelYataiBentoDeploymentTargetType, strings.Join(getKubeTargetTypeLabelValues(deploymentTargetType), ", ")),
+	})
+	if err != nil {
+		return errors.Wrap(err, "list kube deployments")
+	}
+	for _, kubeDeployment := range kubeDeployments.Items {
+		var hpaConf *modelschemas.DeploymentTargetHPAConf
+		if config != nil {
+			if kubeDeployment.Labels[commonconsts.KubeLabelYataiBentoDeploymentComponentType] == commonconsts.YataiBentoDeploymentComponentRunner {
+				hpaConf = config.Runners[kubeDeployment.Labels[commonconsts.KubeLabelYataiBentoDeploymentComponentName]].HPAConf
+			} else {
+				hpaConf = config.HPAConf
+			}
+		}
+		replicas := int32(1)
+		if kubeDeployment.Spec.Replicas != nil {
+			replicas = *kubeDeployment.Spec.Replicas
+		}
+		var desiredReplicas int32
+		switch {
+		case isHPAConfScaledToZero(hpaConf):
+			desiredReplicas = 0
+		case replicas == 0:
+			desiredReplicas = 1
+			if hpaConf != nil && hpaConf.MinReplicas != nil && *hpaConf.MinReplicas > 1 {
+				desiredReplicas = *hpaConf.MinReplicas
+			}
+		default:
+			continue
+		}
+		if replicas == desiredReplicas {
+			continue
+		}
+		patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, desiredReplicas)
+		_, err = cli.Patch(ctx, kubeDeployment.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "scale kube deployment %s to %d replicas", kubeDeployment.Name, desiredReplicas)
+		}
+	}
+	return nil
+}
+
+// sleep deploys a copy of the active revision with no replicas and returns it, the returned message tells why it was skipped.
+// The deploy scales the kube Deployments to zero, their HPAs are kept with one replica.
+// The copy waits for an approval when the cluster requires the creator of the schedule to get one.
+func (s *deploymentScheduleService) sleep(ctx context.Context, schedule *models.DeploymentSchedule) (*models.DeploymentRevision, string, error) {
+	if schedule.State == models.DeploymentScheduleStateAsleep {
+		return nil, "the deployment is already asleep", nil
+	}
+	deploymentRevision, err := s.getActiveDeploymentRevision(ctx, schedule.DeploymentId)
+	if err != nil {
+		return nil, "", err
+	}
+	if deploymentRevision == nil {
+		return nil, "the deployment has no active revision", nil
+	}
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: utils.UintPtr(deploymentRevision.ID),
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "list deployment targets")
+	}
+	asleepDeploymentTargets := make([]*models.DeploymentTarget, 0, len(deploymentTargets))
+	for _, deploymentTarget := range deploymentTargets {
+		asleepDeploymentTarget := *deploymentTarget
+		asleepDeploymentTarget.Config = getAsleepDeploymentTargetConfig(deploymentTarget.Config)
+		asleepDeploymentTargets = append(asleepDeploymentTargets, &asleepDeploymentTarget)
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { df(err) }()
+
+	asleepDeploymentRevision, err := DeploymentRevisionService.submitAsNewRevision(ctx, schedule.DeploymentId, schedule.CreatorId, nil, asleepDeploymentTargets)
+	if err != nil {
+		return nil, "", err
+	}
+	err = s.getBaseDB(ctx).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+		"state":                         models.DeploymentScheduleStateAsleep,
+		"awake_deployment_revision_id":  deploymentRevision.ID,
+		"asleep_deployment_revision_id": asleepDeploymentRevision.ID,
+	}).Error
+	if err != nil {
+		return nil, "", errors.Wrap(err, "update deployment schedule state")
+	}
+	schedule.State = models.DeploymentScheduleStateAsleep
+	schedule.AwakeDeploymentRevisionId = &deploymentRevision.ID
+	schedule.AsleepDeploymentRevisionId = &asleepDeploymentRevision.ID
+	return asleepDeploymentRevision, "", nil
+}
+
+// wake redeploys the targets of the revision which was active before the sleep,
+// the deployment is left as it is when it was updated in the meantime.
+// Like for the sleep, the redeploy waits for an approval when the cluster requires one.
+func (s *deploymentScheduleService) wake(ctx context.Context, schedule *models.DeploymentSchedule) (newDeploymentRevision *models.DeploymentRevision, message string, err error) {
+	if schedule.State == models.DeploymentScheduleStateAwake {
+		return nil, "the deployment is already awake", nil
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { df(err) }()
+
+	err = s.getBaseDB(ctx).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+		"state":                         models.DeploymentScheduleStateAwake,
+		"awake_deployment_revision_id":  nil,
+		"asleep_deployment_revision_id": nil,
+	}).Error
+	if err != nil {
+		return nil, "", errors.Wrap(err, "update deployment schedule state")
+	}
+	awakeDeploymentRevisionId := schedule.AwakeDeploymentRevisionId
+	asleepDeploymentRevisionId := schedule.AsleepDeploymentRevisionId
+	defer func() {
+		if err == nil {
+			schedule.State = models.DeploymentScheduleStateAwake
+			schedule.AwakeDeploymentRevisionId = nil
+			schedule.AsleepDeploymentRevisionId = nil
+		}
+	}()
+
+	if asleepDeploymentRevisionId != nil {
+		asleepDeploymentRevision, err := DeploymentRevisionService.Get(ctx, *asleepDeploymentRevisionId)
+		if err != nil && !utils.IsNotFound(err) {
+			return nil, "", errors.Wrap(err, "get asleep deployment revision")
+		}
+		if err == nil && asleepDeploymentRevision.Status == models.DeploymentRevisionStatusPending {
+			// the sleep was not approved in time, it must not be approved once the deployment is expected to be awake
+			_, err = DeploymentRevisionService.Update(ctx, asleepDeploymentRevision, UpdateDeploymentRevisionOption{
+				Status: modelschemas.DeploymentRevisionStatusPtr(modelschemas.DeploymentRevisionStatusInactive),
+			})
+			if err != nil {
+				return nil, "", errors.Wrap(err, "withdraw asleep deployment revision")
+			}
+			return nil, "the scale to zero was not approved, it is withdrawn", nil
+		}
+	}
+
+	deploymentRevision, err := s.getActiveDeploymentRevision(ctx, schedule.DeploymentId)
+	if err != nil {
+		return nil, "", err
+	}
+	if deploymentRevision == nil || asleepDeploymentRevisionId == nil || deploymentRevision.ID != *asleepDeploymentRevisionId {
+		return nil, "the deployment was updated while asleep, it is not restored", nil
+	}
+	if awakeDeploymentRevisionId == nil {
+		return nil, "the revision to restore was deleted", nil
+	}
+	deploymentTargets, _, err := DeploymentTargetService.List(ctx, ListDeploymentTargetOption{
+		DeploymentRevisionId: awakeDeploymentRevisionId,
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "list deployment targets")
+	}
+	newDeploymentRevision, err = DeploymentRevisionService.submitAsNewRevision(ctx, schedule.DeploymentId, schedule.CreatorId, nil, deploymentTargets)
+	if err != nil {
+		return nil, "", err
+	}
+	return newDeploymentRevision, "", nil
+}
+
+// RunAll runs the due schedules one by one, a failed schedule does not block the others
+func (s *deploymentScheduleService) RunAll(ctx context.Context) error {
+	schedules, err := s.listDue(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list due deployment schedules")
+	}
+	for _, schedule := range schedules {
+		err = s.Run(ctx, schedule)
+		if err != nil {
+			logrus.Errorf("run deployment schedule %d: %v", schedule.ID, err)
+		}
+	}
+	return nil
+}
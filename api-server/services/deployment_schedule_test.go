@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+
+	servingv2alpha1 "github.com/bentoml/yatai-deployment/apis/serving/v2alpha1"
+)
+
+const scheduleTestNamespace = "yatai"
+
+func newScheduleTestKubeDeployment(name string, replicas *int32, labels map[string]string) *appsv1.Deployment {
+	labels[commonconsts.KubeLabelYataiBentoDeployment] = "iris"
+	if _, ok := labels[commonconsts.KubeLabelYataiBentoDeploymentTargetType]; !ok {
+		labels[commonconsts.KubeLabelYataiBentoDeploymentTargetType] = "production"
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: scheduleTestNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas,
+		},
+	}
+}
+
+func getScheduleTestReplicas(t *testing.T, kubeCli *kubefake.Clientset) map[string]int32 {
+	kubeDeployments, err := kubeCli.AppsV1().Deployments(scheduleTestNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list kube deployments: %v", err)
+	}
+	res := make(map[string]int32, len(kubeDeployments.Items))
+	for _, kubeDeployment := range kubeDeployments.Items {
+		if kubeDeployment.Spec.Replicas == nil {
+			t.Fatalf("kube deployment %s has no replicas", kubeDeployment.Name)
+		}
+		res[kubeDeployment.Name] = *kubeDeployment.Spec.Replicas
+	}
+	return res
+}
+
+func TestScaleKubeDeploymentsSleepAndWake(t *testing.T) {
+	kubeCli := kubefake.NewSimpleClientset([]runtime.Object{
+		newScheduleTestKubeDeployment("iris", pointer.Int32(3), map[string]string{
+			commonconsts.KubeLabelYataiBentoDeploymentComponentType: commonconsts.YataiBentoDeploymentComponentApiServer,
+		}),
+		newScheduleTestKubeDeployment("iris-runner-0", nil, map[string]string{
+			commonconsts.KubeLabelYataiBentoDeploymentComponentType: commonconsts.YataiBentoDeploymentComponentRunner,
+			commonconsts.KubeLabelYataiBentoDeploymentComponentName: "classifier",
+		}),
+		// the canary target is handled on its own
+		newScheduleTestKubeDeployment("iris-canary", pointer.Int32(1), map[string]string{
+			commonconsts.KubeLabelYataiBentoDeploymentComponentType: commonconsts.YataiBentoDeploymentComponentApiServer,
+			commonconsts.KubeLabelYataiBentoDeploymentTargetType:    string(modelschemas.DeploymentTargetTypeCanary),
+		}),
+		// another deployment of the namespace is not touched
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: scheduleTestNamespace,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: pointer.Int32(2),
+			},
+		},
+	}...)
+	cli := kubeCli.AppsV1().Deployments(scheduleTestNamespace)
+
+	awakeConfig := &modelschemas.DeploymentTargetConfig{
+		HPAConf: &modelschemas.DeploymentTargetHPAConf{
+			MinReplicas: pointer.Int32(2),
+			MaxReplicas: pointer.Int32(5),
+		},
+		Runners: map[string]modelschemas.DeploymentTargetRunnerConfig{
+			"classifier": {
+				HPAConf: &modelschemas.DeploymentTargetHPAConf{
+					MinReplicas: pointer.Int32(1),
+					MaxReplicas: pointer.Int32(2),
+				},
+			},
+		},
+	}
+	asleepConfig := getAsleepDeploymentTargetConfig(awakeConfig)
+
+	// the rendered HPAs are still accepted by the kube api server
+	for path, hpaConf := range map[string]*modelschemas.DeploymentTargetHPAConf{
+		"spec.autoscaling":            asleepConfig.HPAConf,
+		"spec.runners[0].autoscaling": asleepConfig.Runners["classifier"].HPAConf,
+	} {
+		kubeHPAConf := getKubeHPAConf(hpaConf)
+		if *kubeHPAConf.MinReplicas != 1 || *kubeHPAConf.MaxReplicas != 1 {
+			t.Errorf("%s: expected the HPA to be rendered with 1 replica, got min %d max %d", path, *kubeHPAConf.MinReplicas, *kubeHPAConf.MaxReplicas)
+		}
+		errs := validateAutoscaling(path, &servingv2alpha1.Autoscaling{
+			MinReplicas: *kubeHPAConf.MinReplicas,
+			MaxReplicas: *kubeHPAConf.MaxReplicas,
+		})
+		if len(errs) != 0 {
+			t.Errorf("%s: expected the rendered HPA to be valid, got %v", path, errs)
+		}
+	}
+	if *awakeConfig.HPAConf.MaxReplicas != 5 {
+		t.Errorf("expected the awake config to be left untouched, got max %d", *awakeConfig.HPAConf.MaxReplicas)
+	}
+
+	err := scaleKubeDeployments(context.Background(), cli, "iris", modelschemas.DeploymentTargetTypeStable, asleepConfig)
+	if err != nil {
+		t.Fatalf("sleep: %v", err)
+	}
+	replicas := getScheduleTestReplicas(t, kubeCli)
+	if replicas["iris"] != 0 || replicas["iris-runner-0"] != 0 {
+		t.Errorf("expected the kube deployments to be scaled to zero on sleep, got %v", replicas)
+	}
+	if replicas["other"] != 2 {
+		t.Errorf("expected the other kube deployment to keep its replicas, got %d", replicas["other"])
+	}
+	if replicas["iris-canary"] != 1 {
+		t.Errorf("expected the kube deployment of the canary target to keep its replicas, got %d", replicas["iris-canary"])
+	}
+
+	err = scaleKubeDeployments(context.Background(), cli, "iris", modelschemas.DeploymentTargetTypeCanary, asleepConfig)
+	if err != nil {
+		t.Fatalf("sleep canary: %v", err)
+	}
+	if replicas = getScheduleTestReplicas(t, kubeCli); replicas["iris-canary"] != 0 {
+		t.Errorf("expected the kube deployment of the canary target to be scaled to zero, got %d", replicas["iris-canary"])
+	}
+
+	err = scaleKubeDeployments(context.Background(), cli, "iris", modelschemas.DeploymentTargetTypeStable, awakeConfig)
+	if err != nil {
+		t.Fatalf("wake: %v", err)
+	}
+	replicas = getScheduleTestReplicas(t, kubeCli)
+	if replicas["iris"] != 2 || replicas["iris-runner-0"] != 1 {
+		t.Errorf("expected the kube deployments to be scaled back to their min replicas on wake, got %v", replicas)
+	}
+	if replicas["iris-canary"] != 0 {
+		t.Errorf("expected the kube deployment of the canary target to be left asleep, got %d", replicas["iris-canary"])
+	}
+
+	// the replicas set by the HPAs are left to them
+	_, err = cli.Update(context.Background(), newScheduleTestKubeDeployment("iris", pointer.Int32(4), map[string]string{
+		commonconsts.KubeLabelYataiBentoDeploymentComponentType: commonconsts.YataiBentoDeploymentComponentApiServer,
+	}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("update kube deployment: %v", err)
+	}
+	err = scaleKubeDeployments(context.Background(), cli, "iris", modelschemas.DeploymentTargetTypeStable, awakeConfig)
+	if err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if replicas = getScheduleTestReplicas(t, kubeCli); replicas["iris"] != 4 {
+		t.Errorf("expected the replicas set by the HPA to be kept, got %d", replicas["iris"])
+	}
+}
+
+func TestGetAsleepDeploymentTargetTypes(t *testing.T) {
+	awakeConfig := &modelschemas.DeploymentTargetConfig{
+		HPAConf: &modelschemas.DeploymentTargetHPAConf{
+			MinReplicas: pointer.Int32(1),
+			MaxReplicas: pointer.Int32(2),
+		},
+	}
+	// a runner scaled to zero is enough for the kube deployments to be scaled by hand
+	runnerAsleepConfig := &modelschemas.DeploymentTargetConfig{
+		Runners: map[string]modelschemas.DeploymentTargetRunnerConfig{
+			"classifier": {
+				HPAConf: &modelschemas.DeploymentTargetHPAConf{
+					MinReplicas: pointer.Int32(0),
+					MaxReplicas: pointer.Int32(0),
+				},
+			},
+		},
+	}
+	if isDeploymentTargetConfigAsleep(awakeConfig) || isDeploymentTargetConfigAsleep(nil) {
+		t.Error("expected the awake configs not to be asleep")
+	}
+	if !isDeploymentTargetConfigAsleep(getAsleepDeploymentTargetConfig(awakeConfig)) || !isDeploymentTargetConfigAsleep(runnerAsleepConfig) {
+		t.Error("expected the configs scaled to zero to be asleep")
+	}
+
+	asleepTypes := getAsleepDeploymentTargetTypes([]*models.DeploymentTarget{
+		{Type: modelschemas.DeploymentTargetTypeStable, Config: awakeConfig},
+		{Type: modelschemas.DeploymentTargetTypeCanary, Config: runnerAsleepConfig},
+	})
+	if len(asleepTypes) != 1 || !asleepTypes[modelschemas.DeploymentTargetTypeCanary] {
+		t.Errorf("expected only the canary target to be asleep, got %v", asleepTypes)
+	}
+}
+
+func TestValidateDeploymentTargetHPAConfs(t *testing.T) {
+	config := &modelschemas.DeploymentTargetConfig{
+		HPAConf: &modelschemas.DeploymentTargetHPAConf{
+			MinReplicas: pointer.Int32(0),
+			MaxReplicas: pointer.Int32(0),
+		},
+		Runners: map[string]modelschemas.DeploymentTargetRunnerConfig{
+			"classifier": {
+				HPAConf: &modelschemas.DeploymentTargetHPAConf{
+					MinReplicas: pointer.Int32(0),
+					MaxReplicas: pointer.Int32(0),
+				},
+			},
+			"tokenizer": {
+				HPAConf: &modelschemas.DeploymentTargetHPAConf{
+					MinReplicas: pointer.Int32(0),
+					MaxReplicas: pointer.Int32(1),
+				},
+			},
+		},
+	}
+	errs := validateDeploymentTargetHPAConfs(config)
+	if len(errs) != 2 {
+		t.Errorf("expected 2 validation errors for the hpa confs with no replicas, got %v", errs)
+	}
+	if errs = validateDeploymentTargetHPAConfs(nil); len(errs) != 0 {
+		t.Errorf("expected no validation errors without a config, got %v", errs)
+	}
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	commonconsts "github.com/bentoml/yatai-common/consts"
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+const deploymentStatusReconcileDebounce = 2 * time.Second
+
+type deploymentStatusReconcileKey struct {
+	clusterId     uint
+	kubeNamespace string
+	name          string
+}
+
+type deploymentStatusReconcileState struct {
+	timer   *time.Timer
+	running bool
+	dirty   bool
+}
+
+// deploymentStatusReconciler syncs the status of a deployment when its pods or its BentoDeployment change,
+// the events of a deployment are debounced so that a rollout of many pods only syncs it a few times
+type deploymentStatusReconciler struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	stopped bool
+	wg      sync.WaitGroup
+	watched map[string]struct{}
+	states  map[deploymentStatusReconcileKey]*deploymentStatusReconcileState
+	// removeHandlers removes the event handlers from the informers of the KubeInformerManager
+	removeHandlers []func()
+	logger         *logrus.Entry
+}
+
+var DeploymentStatusReconciler = &deploymentStatusReconciler{
+	watched: make(map[string]struct{}),
+	states:  make(map[deploymentStatusReconcileKey]*deploymentStatusReconcileState),
+	logger:  logrus.New().WithField("reconciler", "deployment status"),
+}
+
+// Start watches the namespaces of the existing deployments, the informers are stopped when the ctx is done
+func (r *deploymentStatusReconciler) Start(ctx context.Context) error {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.stopped = false
+	r.mu.Unlock()
+	return r.WatchAll(ctx)
+}
+
+// Stop drops the pending syncs and waits for the running ones until the ctx is done
+func (r *deploymentStatusReconciler) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopped = true
+	for key, state := range r.states {
+		if state.timer != nil && state.timer.Stop() {
+			r.wg.Done()
+		}
+		delete(r.states, key)
+	}
+	for _, removeHandler := range r.removeHandlers {
+		removeHandler()
+	}
+	r.removeHandlers = nil
+	r.watched = make(map[string]struct{})
+	r.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "wait for deployment status syncs")
+	}
+}
+
+// active must be called with the lock held
+func (r *deploymentStatusReconciler) active() bool {
+	return r.ctx != nil && r.ctx.Err() == nil && !r.stopped
+}
+
+// WatchAll watches the namespaces of all the deployments, it also picks up the namespaces missed by Watch
+func (r *deploymentStatusReconciler) WatchAll(ctx context.Context) error {
+	namespaces, err := DeploymentService.ListClusterKubeNamespaces(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list deployment kube namespaces")
+	}
+	for _, namespace := range namespaces {
+		r.Watch(namespace.ClusterId, namespace.KubeNamespace)
+	}
+	return nil
+}
+
+// Watch starts the informers of the namespace in the background if they are not started yet
+func (r *deploymentStatusReconciler) Watch(clusterId uint, kubeNamespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active() {
+		return
+	}
+	watchKey := fmt.Sprintf("%d:%s", clusterId, kubeNamespace)
+	if _, ok := r.watched[watchKey]; ok {
+		return
+	}
+	r.watched[watchKey] = struct{}{}
+	ctx := r.ctx
+	go func() {
+		err := r.watch(ctx, clusterId, kubeNamespace)
+		if err != nil {
+			r.logger.Errorf("watch namespace %s of cluster %d: %s", kubeNamespace, clusterId, err.Error())
+			// let the next WatchAll retry
+			r.mu.Lock()
+			delete(r.watched, watchKey)
+			r.mu.Unlock()
+		}
+	}()
+}
+
+func (r *deploymentStatusReconciler) watch(ctx context.Context, clusterId uint, kubeNamespace string) error {
+	cluster, err := ClusterService.Get(ctx, clusterId)
+	if err != nil {
+		return errors.Wrap(err, "get cluster")
+	}
+	yataiDeploymentComp, err := YataiComponentService.GetByName(ctx, cluster.ID, string(modelschemas.YataiComponentNameDeployment))
+	if err != nil {
+		return errors.Wrap(err, "get yatai deployment component")
+	}
+	crdVersion := ""
+	if yataiDeploymentComp.Manifest != nil {
+		crdVersion = yataiDeploymentComp.Manifest.LatestCRDVersion
+	}
+
+	podInformer, _, err := GetPodInformer(ctx, cluster, kubeNamespace)
+	if err != nil {
+		return errors.Wrap(err, "get pod informer")
+	}
+	err = r.addEventHandler(podInformer.Informer(), r.podEventHandler(cluster.ID))
+	if err != nil {
+		return errors.Wrap(err, "add pod event handler")
+	}
+
+	// the image builder pods of the old CRDs are in a namespace shared by all the deployments of the cluster
+	if strings.HasPrefix(crdVersion, "v1alpha") {
+		builderWatchKey := fmt.Sprintf("%d:%s", cluster.ID, commonconsts.DefaultKubeNamespaceImageBuilders)
+		r.mu.Lock()
+		_, ok := r.watched[builderWatchKey]
+		r.watched[builderWatchKey] = struct{}{}
+		r.mu.Unlock()
+		if !ok {
+			builderPodInformer, _, err := GetPodInformer(ctx, cluster, commonconsts.DefaultKubeNamespaceImageBuilders)
+			if err != nil {
+				r.mu.Lock()
+				delete(r.watched, builderWatchKey)
+				r.mu.Unlock()
+				return errors.Wrap(err, "get image builder pod informer")
+			}
+			err = r.addEventHandler(builderPodInformer.Informer(), r.podEventHandler(cluster.ID))
+			if err != nil {
+				r.mu.Lock()
+				delete(r.watched, builderWatchKey)
+				r.mu.Unlock()
+				return errors.Wrap(err, "add image builder pod event handler")
+			}
+		}
+	}
+
+	bentoDeploymentInformer, err := GetBentoDeploymentInformer(ctx, cluster, kubeNamespace, crdVersion)
+	if err != nil {
+		return errors.Wrap(err, "get bento deployment informer")
+	}
+	err = r.addEventHandler(bentoDeploymentInformer, r.bentoDeploymentEventHandler(cluster.ID))
+	if err != nil {
+		return errors.Wrap(err, "add bento deployment event handler")
+	}
+	return nil
+}
+
+func (r *deploymentStatusReconciler) addEventHandler(informer cache.SharedIndexInformer, handler cache.ResourceEventHandler) error {
+	removeHandler, err := KubeInformerManager.AddEventHandler(informer, handler)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active() {
+		removeHandler()
+		return nil
+	}
+	r.removeHandlers = append(r.removeHandlers, removeHandler)
+	return nil
+}
+
+func eventHandlerFuncs(onChange func(object metav1.Object)) cache.ResourceEventHandler {
+	handle := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		object, err := meta.Accessor(obj)
+		if err != nil {
+			return
+		}
+		onChange(object)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: handle,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			handle(newObj)
+		},
+		DeleteFunc: handle,
+	}
+}
+
+func (r *deploymentStatusReconciler) podEventHandler(clusterId uint) cache.ResourceEventHandler {
+	return eventHandlerFuncs(func(pod metav1.Object) {
+		labels := pod.GetLabels()
+		if deploymentName, ok := labels[commonconsts.KubeLabelYataiBentoDeployment]; ok {
+			r.Enqueue(clusterId, pod.GetNamespace(), deploymentName)
+			return
+		}
+		if _, ok := labels[commonconsts.KubeLabelYataiBentoRepository]; ok {
+			// the image builder pods are not labelled with the deployments which wait for the image,
+			// an empty name stands for all the deployments of the cluster which are waiting for one
+			r.Enqueue(clusterId, "", "")
+		}
+	})
+}
+
+func (r *deploymentStatusReconciler) bentoDeploymentEventHandler(clusterId uint) cache.ResourceEventHandler {
+	// the BentoDeployments are named after the deployments
+	return eventHandlerFuncs(func(bentoDeployment metav1.Object) {
+		r.Enqueue(clusterId, bentoDeployment.GetNamespace(), bentoDeployment.GetName())
+	})
+}
+
+func (r *deploymentStatusReconciler) enqueueImageBuilding(ctx context.Context, clusterId uint) {
+	deployments, _, err := DeploymentService.List(ctx, ListDeploymentOption{
+		ClusterId: utils.UintPtr(clusterId),
+		Statuses: &[]modelschemas.DeploymentStatus{
+			modelschemas.DeploymentStatusDeploying,
+			modelschemas.DeploymentStatusImageBuilding,
+		},
+	})
+	if err != nil {
+		r.logger.Errorf("list the deployments waiting for an image in cluster %d: %s", clusterId, err.Error())
+		return
+	}
+	for _, deployment := range deployments {
+		r.Enqueue(clusterId, deployment.KubeNamespace, deployment.Name)
+	}
+}
+
+// Enqueue schedules a status sync of the deployment, the calls within the debounce window are coalesced
+func (r *deploymentStatusReconciler) Enqueue(clusterId uint, kubeNamespace, name string) {
+	key := deploymentStatusReconcileKey{
+		clusterId:     clusterId,
+		kubeNamespace: kubeNamespace,
+		name:          name,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active() {
+		return
+	}
+	state, ok := r.states[key]
+	if !ok {
+		state = &deploymentStatusReconcileState{}
+		r.states[key] = state
+	}
+	if state.running {
+		state.dirty = true
+		return
+	}
+	if state.timer != nil {
+		return
+	}
+	r.schedule(key, state)
+}
+
+// schedule must be called with the lock held
+func (r *deploymentStatusReconciler) schedule(key deploymentStatusReconcileKey, state *deploymentStatusReconcileState) {
+	r.wg.Add(1)
+	state.timer = time.AfterFunc(deploymentStatusReconcileDebounce, func() {
+		defer r.wg.Done()
+		r.mu.Lock()
+		ctx := r.ctx
+		if r.states[key] != state || !r.active() {
+			r.mu.Unlock()
+			return
+		}
+		state.timer = nil
+		state.running = true
+		r.mu.Unlock()
+
+		r.sync(ctx, key)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		state.running = false
+		if r.states[key] != state {
+			return
+		}
+		if state.dirty && r.active() {
+			state.dirty = false
+			r.schedule(key, state)
+			return
+		}
+		delete(r.states, key)
+	})
+}
+
+func (r *deploymentStatusReconciler) sync(ctx context.Context, key deploymentStatusReconcileKey) {
+	if key.name == "" {
+		r.enqueueImageBuilding(ctx, key.clusterId)
+		return
+	}
+	deployment, err := DeploymentService.GetByName(ctx, key.clusterId, key.kubeNamespace, key.name)
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			r.logger.Errorf("get deployment %s in namespace %s: %s", key.name, key.kubeNamespace, err.Error())
+		}
+		// not deployed by yatai
+		return
+	}
+	_, err = DeploymentService.SyncStatus(ctx, deployment)
+	if err != nil {
+		r.logger.Errorf("sync deployment %d status: %s", deployment.ID, err.Error())
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+// the evidence only keeps the most relevant pods and warnings so that a single transition stays small,
+// the number of transitions of a flapping deployment is bounded by Prune
+const (
+	deploymentStatusEvidenceMaxPods     = 20
+	deploymentStatusEvidenceMaxWarnings = 20
+)
+
+// the transitions older than the retention are pruned, and the oldest ones beyond the cap of a deployment,
+// the last transition of a deployment is always kept since it explains the current status
+const (
+	deploymentStatusTransitionRetention        = 90 * 24 * time.Hour
+	deploymentStatusTransitionMaxPerDeployment = 1000
+)
+
+type deploymentStatusTransitionService struct{}
+
+var DeploymentStatusTransitionService = deploymentStatusTransitionService{}
+
+func (s *deploymentStatusTransitionService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.DeploymentStatusTransition{})
+}
+
+type CreateDeploymentStatusTransitionOption struct {
+	Deployment *models.Deployment
+	FromStatus modelschemas.DeploymentStatus
+	ToStatus   modelschemas.DeploymentStatus
+	Evidence   *models.DeploymentStatusEvidence
+}
+
+type ListDeploymentStatusTransitionOption struct {
+	BaseListOption
+	DeploymentId *uint
+	Since        *time.Time
+	Until        *time.Time
+}
+
+func (s *deploymentStatusTransitionService) Create(ctx context.Context, opt CreateDeploymentStatusTransitionOption) (*models.DeploymentStatusTransition, error) {
+	transition := &models.DeploymentStatusTransition{
+		DeploymentAssociate: models.DeploymentAssociate{
+			DeploymentId:              opt.Deployment.ID,
+			AssociatedDeploymentCache: opt.Deployment,
+		},
+		FromStatus: opt.FromStatus,
+		ToStatus:   opt.ToStatus,
+		Evidence:   opt.Evidence,
+	}
+	err := mustGetSession(ctx).Create(transition).Error
+	if err != nil {
+		return nil, err
+	}
+	err = NotificationService.Publish(ctx, models.ResourceTypeDeploymentStatusTransition, opt.Deployment.Uid)
+	return transition, err
+}
+
+func (s *deploymentStatusTransitionService) List(ctx context.Context, opt ListDeploymentStatusTransitionOption) ([]*models.DeploymentStatusTransition, uint, error) {
+	query := s.getBaseDB(ctx)
+	if opt.DeploymentId != nil {
+		query = query.Where("deployment_id = ?", *opt.DeploymentId)
+	}
+	if opt.Since != nil {
+		query = query.Where("created_at >= ?", *opt.Since)
+	}
+	if opt.Until != nil {
+		query = query.Where("created_at < ?", *opt.Until)
+	}
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	transitions := make([]*models.DeploymentStatusTransition, 0)
+	query = opt.BindQueryWithLimit(query).Order("id DESC")
+	err = query.Find(&transitions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return transitions, uint(total), err
+}
+
+// ListLatestByDeploymentIds lists the last transition of each deployment
+func (s *deploymentStatusTransitionService) ListLatestByDeploymentIds(ctx context.Context, deploymentIds []uint) ([]*models.DeploymentStatusTransition, error) {
+	transitions := make([]*models.DeploymentStatusTransition, 0, len(deploymentIds))
+	if len(deploymentIds) == 0 {
+		return transitions, nil
+	}
+	err := s.getBaseDB(ctx).Select("DISTINCT ON (deployment_id) *").Where("deployment_id IN (?)", deploymentIds).Order("deployment_id, id DESC").Find(&transitions).Error
+	return transitions, err
+}
+
+// Prune deletes the transitions older than the retention and the ones beyond the cap of each deployment,
+// it returns the number of deleted transitions
+func (s *deploymentStatusTransitionService) Prune(ctx context.Context) (int64, error) {
+	db := mustGetSession(ctx)
+	res := db.Exec(`DELETE FROM deployment_status_transition
+		WHERE created_at < ?
+		AND id NOT IN (SELECT MAX(id) FROM deployment_status_transition GROUP BY deployment_id)`, time.Now().Add(-deploymentStatusTransitionRetention))
+	if res.Error != nil {
+		return 0, errors.Wrap(res.Error, "delete expired deployment status transitions")
+	}
+	deleted := res.RowsAffected
+	res = db.Exec(`DELETE FROM deployment_status_transition
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY deployment_id ORDER BY id DESC) AS row_number
+				FROM deployment_status_transition
+			) ranked WHERE ranked.row_number > ?
+		)`, deploymentStatusTransitionMaxPerDeployment)
+	if res.Error != nil {
+		return deleted, errors.Wrap(res.Error, "delete the deployment status transitions beyond the cap")
+	}
+	return deleted + res.RowsAffected, nil
+}
+
+// makeDeploymentStatusEvidence keeps the pods and the latest warnings the status was computed from, the unhealthy pods first
+func makeDeploymentStatusEvidence(pods []*models.KubePodWithStatus) *models.DeploymentStatusEvidence {
+	evidence := &models.DeploymentStatusEvidence{
+		Pods:     make([]*models.DeploymentStatusEvidencePod, 0, len(pods)),
+		Warnings: make([]*models.DeploymentStatusEvidenceEvent, 0),
+	}
+	pods_ := make([]*models.KubePodWithStatus, len(pods))
+	copy(pods_, pods)
+	sort.SliceStable(pods_, func(i, j int) bool {
+		iRunning := pods_[i].Status.Status == modelschemas.KubePodActualStatusRunning
+		jRunning := pods_[j].Status.Status == modelschemas.KubePodActualStatusRunning
+		return !iRunning && jRunning
+	})
+	warnings := make([]apiv1.Event, 0)
+	for _, pod := range pods_ {
+		warnings = append(warnings, pod.Warnings...)
+		if len(evidence.Pods) >= deploymentStatusEvidenceMaxPods {
+			continue
+		}
+		var restartCount int32
+		for _, containerStatus := range pod.Pod.Status.ContainerStatuses {
+			restartCount += containerStatus.RestartCount
+		}
+		evidence.Pods = append(evidence.Pods, &models.DeploymentStatusEvidencePod{
+			Name:         pod.Pod.Name,
+			Status:       pod.Status.Status,
+			RestartCount: restartCount,
+		})
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].LastTimestamp.After(warnings[j].LastTimestamp.Time)
+	})
+	for _, warning := range warnings {
+		if len(evidence.Warnings) >= deploymentStatusEvidenceMaxWarnings {
+			break
+		}
+		var lastTimestamp *time.Time
+		if !warning.LastTimestamp.IsZero() {
+			lastTimestamp_ := warning.LastTimestamp.Time
+			lastTimestamp = &lastTimestamp_
+		}
+		evidence.Warnings = append(evidence.Warnings, &models.DeploymentStatusEvidenceEvent{
+			InvolvedObject: warning.InvolvedObject.Name,
+			Reason:         warning.Reason,
+			Message:        warning.Message,
+			Count:          warning.Count,
+			LastTimestamp:  lastTimestamp,
+		})
+	}
+	return evidence
+}
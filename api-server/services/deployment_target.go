@@ -35,7 +35,8 @@ type CreateDeploymentTargetOption struct {
 }
 
 type UpdateDeploymentTargetOption struct {
-	Config **modelschemas.DeploymentTargetConfig
+	Config      **modelschemas.DeploymentTargetConfig
+	CanaryRules **modelschemas.DeploymentTargetCanaryRules
 }
 
 type ListDeploymentTargetOption struct {
@@ -45,6 +46,7 @@ type ListDeploymentTargetOption struct {
 	DeploymentIds            *[]uint
 	DeploymentRevisionId     *uint
 	DeploymentRevisionIds    *[]uint
+	BentoIds                 *[]uint
 	Type                     *modelschemas.DeploymentTargetType
 }
 
@@ -134,6 +136,9 @@ func (s *deploymentTargetService) List(ctx context.Context, opt ListDeploymentTa
 	if opt.DeploymentRevisionIds != nil {
 		query = query.Where("deployment_target.deployment_revision_id in (?)", *opt.DeploymentRevisionIds)
 	}
+	if opt.BentoIds != nil {
+		query = query.Where("deployment_target.bento_id in (?)", *opt.BentoIds)
+	}
 	if opt.Type != nil {
 		query = query.Where("deployment_target.type = ?", *opt.Type)
 	}
@@ -209,6 +214,15 @@ func (s *deploymentTargetService) Update(ctx context.Context, b *models.Deployme
 		}()
 	}
 
+	if opt.CanaryRules != nil {
+		updaters["canary_rules"] = *opt.CanaryRules
+		defer func() {
+			if err == nil {
+				b.CanaryRules = *opt.CanaryRules
+			}
+		}()
+	}
+
 	if len(updaters) == 0 {
 		return b, nil
 	}
@@ -218,6 +232,17 @@ func (s *deploymentTargetService) Update(ctx context.Context, b *models.Deployme
 	return b, err
 }
 
+// GetExpectedCRDVersion returns the BentoDeployment CRD version which Deploy deploys with on the cluster of the yatai-deployment component
+func (s *deploymentTargetService) GetExpectedCRDVersion(yataiDeploymentComp *models.YataiComponent) string {
+	if yataiDeploymentComp.Manifest != nil {
+		switch yataiDeploymentComp.Manifest.LatestCRDVersion {
+		case "v2alpha1", "v1alpha3":
+			return yataiDeploymentComp.Manifest.LatestCRDVersion
+		}
+	}
+	return "v1alpha2"
+}
+
 func (s *deploymentTargetService) Deploy(ctx context.Context, deploymentTarget *models.DeploymentTarget, deployOption *models.DeployOption) (deploymentTarget_ *models.DeploymentTarget, err error) {
 	deploymentTarget_ = deploymentTarget
 
@@ -237,12 +262,12 @@ func (s *deploymentTargetService) Deploy(ctx context.Context, deploymentTarget *
 		err = errors.Wrap(err, "get yatai deployment component")
 		return
 	}
-	// nolint: gocritic
-	if yataiDeploymentComp.Manifest != nil && yataiDeploymentComp.Manifest.LatestCRDVersion == "v2alpha1" {
+	switch s.GetExpectedCRDVersion(yataiDeploymentComp) {
+	case "v2alpha1":
 		_, err = KubeBentoDeploymentService.DeployV2alpha1(ctx, deploymentTarget, deployOption)
-	} else if yataiDeploymentComp.Manifest != nil && yataiDeploymentComp.Manifest.LatestCRDVersion == "v1alpha3" {
+	case "v1alpha3":
 		_, err = KubeBentoDeploymentService.DeployV1alpha3(ctx, deploymentTarget, deployOption)
-	} else {
+	default:
 		_, err = KubeBentoDeploymentService.DeployV1alpha2(ctx, deploymentTarget, deployOption)
 	}
 
@@ -251,6 +276,21 @@ func (s *deploymentTargetService) Deploy(ctx context.Context, deploymentTarget *
 		return
 	}
 
+	// the kube Deployments are only scaled by hand when the target falls asleep or wakes up, the HPAs scale them otherwise
+	if !isDeploymentTargetConfigAsleep(deploymentTarget.Config) && !deployOption.AsleepTargetTypes[deploymentTarget.Type] {
+		return
+	}
+	kubeCli, _, err := ClusterService.GetKubeCliSet(ctx, cluster)
+	if err != nil {
+		err = errors.Wrap(err, "get kube cli set")
+		return
+	}
+	err = scaleKubeDeployments(ctx, kubeCli.AppsV1().Deployments(DeploymentService.GetKubeNamespace(deployment)), deployment.Name, deploymentTarget.Type, deploymentTarget.Config)
+	if err != nil {
+		err = errors.Wrap(err, "failed to scale kube deployments")
+		return
+	}
+
 	return
 }
 
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+
+	"github.com/bentoml/yatai/api-server/config"
+)
+
+type healthService struct {
+	draining atomic.Bool
+
+	migrationsMu sync.Mutex
+	migrated     bool
+}
+
+var HealthService = healthService{}
+
+// SetDraining makes the server report not ready, so that the load balancers stop routing new requests to it
+func (s *healthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+func (s *healthService) IsDraining() bool {
+	return s.draining.Load()
+}
+
+func (s *healthService) CheckDatabase(ctx context.Context) error {
+	rawDb, err := mustGetDB(ctx).DB()
+	if err != nil {
+		return errors.Wrap(err, "get db")
+	}
+	return errors.Wrap(rawDb.PingContext(ctx), "ping db")
+}
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_.+\.up\.sql$`)
+
+func getLatestMigrationVersion(migrationDir string) (uint, error) {
+	entries, err := os.ReadDir(migrationDir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "read migration dir %s", migrationDir)
+	}
+	var latest uint
+	for _, entry := range entries {
+		matches := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "parse migration version of %s", entry.Name())
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+// CheckMigrations reports an error until the database is migrated up to the latest migration, the success is cached because migrations never go backward
+func (s *healthService) CheckMigrations(ctx context.Context) error {
+	s.migrationsMu.Lock()
+	defer s.migrationsMu.Unlock()
+	if s.migrated {
+		return nil
+	}
+	migrationDir := config.YataiConfig.Server.MigrationDir
+	latest, err := getLatestMigrationVersion(migrationDir)
+	if err != nil {
+		return err
+	}
+	uri, err := getDBURI()
+	if err != nil {
+		return errors.Wrap(err, "get db uri")
+	}
+	m, err := migrate.New(fmt.Sprintf("file://%s", migrationDir), uri)
+	if err != nil {
+		return errors.Wrap(err, "create migrate")
+	}
+	defer m.Close()
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return errors.Wrap(err, "get migration version")
+	}
+	if dirty {
+		return errors.Errorf("migration %d is dirty", version)
+	}
+	if version < latest {
+		return errors.Errorf("migration version %d is behind the latest migration %d", version, latest)
+	}
+	s.migrated = true
+	return nil
+}
+
+// CheckS3 checks the bucket of the default organization, it does nothing when the s3 is not configured
+func (s *healthService) CheckS3(ctx context.Context) error {
+	if config.YataiConfig.S3 == nil {
+		return nil
+	}
+	defaultOrg, err := OrganizationService.GetDefault(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get default organization")
+	}
+	s3Config, err := OrganizationService.GetS3Config(ctx, defaultOrg)
+	if err != nil {
+		return errors.Wrap(err, "get s3 config")
+	}
+	minioClient, err := s3Config.GetMinioClient()
+	if err != nil {
+		return err
+	}
+	exists, err := minioClient.BucketExists(ctx, s3Config.BentosBucketName)
+	if err != nil {
+		return errors.Wrapf(err, "get bucket %s exist", s3Config.BentosBucketName)
+	}
+	if !exists {
+		return errors.Errorf("bucket %s does not exist", s3Config.BentosBucketName)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/config"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+	"github.com/bentoml/yatai/common/oidc"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+const (
+	IdentityProviderTypeOIDC = "oidc"
+
+	defaultIdentityProviderGroupsClaim = "groups"
+)
+
+// ExternalIdentity is the user information asserted by an identity provider after a successful login
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Groups        []string
+	Claims        map[string]interface{}
+}
+
+// IIdentityProvider is implemented by every login method delegated to an external identity provider
+type IIdentityProvider interface {
+	GetName() string
+	GetDisplayName() string
+	AuthCodeURL(state, nonce string) string
+	Authenticate(ctx context.Context, code, nonce string) (*ExternalIdentity, error)
+}
+
+type identityProviderFactory func(ctx context.Context, conf config.YataiIdentityProviderConfigYaml) (IIdentityProvider, error)
+
+var identityProviderFactories = map[string]identityProviderFactory{
+	IdentityProviderTypeOIDC: newOIDCIdentityProvider,
+}
+
+// RegisterIdentityProviderType makes a new kind of identity provider available to the identity_providers config
+func RegisterIdentityProviderType(type_ string, factory func(ctx context.Context, conf config.YataiIdentityProviderConfigYaml) (IIdentityProvider, error)) {
+	identityProviderFactories[type_] = factory
+}
+
+type oidcIdentityProvider struct {
+	conf     config.YataiIdentityProviderConfigYaml
+	provider *oidc.Provider
+}
+
+func newOIDCIdentityProvider(ctx context.Context, conf config.YataiIdentityProviderConfigYaml) (IIdentityProvider, error) {
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       conf.Issuer,
+		ClientId:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectURL,
+		Scopes:       conf.Scopes,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &oidcIdentityProvider{
+		conf:     conf,
+		provider: provider,
+	}, nil
+}
+
+func (p *oidcIdentityProvider) GetName() string {
+	return p.conf.Name
+}
+
+func (p *oidcIdentityProvider) GetDisplayName() string {
+	return p.conf.DisplayName
+}
+
+func (p *oidcIdentityProvider) AuthCodeURL(state, nonce string) string {
+	return p.provider.AuthCodeURL(state, nonce)
+}
+
+func (p *oidcIdentityProvider) Authenticate(ctx context.Context, code, nonce string) (*ExternalIdentity, error) {
+	claims, err := p.provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		return nil, err
+	}
+	username := claims.PreferredUsername
+	if p.conf.UsernameClaim != "" {
+		username = claims.StringClaim(p.conf.UsernameClaim)
+	}
+	groupsClaim := p.conf.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = defaultIdentityProviderGroupsClaim
+	}
+	return &ExternalIdentity{
+		Provider:      p.conf.Name,
+		Subject:       claims.Subject,
+		Username:      username,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+		Groups:        claims.StringsClaim(groupsClaim),
+		Claims:        claims.Raw,
+	}, nil
+}
+
+type identityProviderService struct {
+	mu        sync.Mutex
+	providers map[string]IIdentityProvider
+}
+
+var IdentityProviderService = &identityProviderService{
+	providers: make(map[string]IIdentityProvider),
+}
+
+func (s *identityProviderService) ListConfigs() []config.YataiIdentityProviderConfigYaml {
+	return config.YataiConfig.IdentityProviders
+}
+
+func (s *identityProviderService) GetConfig(name string) (*config.YataiIdentityProviderConfigYaml, error) {
+	for _, conf := range config.YataiConfig.IdentityProviders {
+		if conf.Name == name {
+			conf := conf
+			return &conf, nil
+		}
+	}
+	return nil, errors.Wrapf(consts.ErrNotFound, "identity provider %s", name)
+}
+
+// Get initializes the identity provider on first use, a failed initialization is retried by the next call
+func (s *identityProviderService) Get(ctx context.Context, name string) (IIdentityProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if provider, ok := s.providers[name]; ok {
+		return provider, nil
+	}
+	conf, err := s.GetConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	type_ := conf.Type
+	if type_ == "" {
+		type_ = IdentityProviderTypeOIDC
+	}
+	factory, ok := identityProviderFactories[type_]
+	if !ok {
+		return nil, errors.Errorf("unknown identity provider type %s", type_)
+	}
+	provider, err := factory(ctx, *conf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "initialize identity provider %s", name)
+	}
+	s.providers[name] = provider
+	return provider, nil
+}
+
+// LoginUser returns the user linked to the external identity, the user is provisioned on the first login.
+// An existing user is linked by email only if the identity provider has verified that email.
+// The memberships granted by the role mappings of the identity provider are synced at each login.
+func (s *identityProviderService) LoginUser(ctx context.Context, identity *ExternalIdentity) (user *models.User, err error) {
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+
+	user, userIdentity, err := s.getOrCreateUserIdentity(ctx, identity)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	nowPtr := &now
+	_, err = UserIdentityService.Update(ctx, userIdentity, UpdateUserIdentityOption{
+		LatestLoginAt: &nowPtr,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "update user identity")
+		return
+	}
+	err = s.SyncMembers(ctx, user, identity)
+	if err != nil {
+		err = errors.Wrap(err, "sync members")
+	}
+	return
+}
+
+func (s *identityProviderService) getOrCreateUserIdentity(ctx context.Context, identity *ExternalIdentity) (*models.User, *models.UserIdentity, error) {
+	userIdentity, err := UserIdentityService.GetBySubject(ctx, identity.Provider, identity.Subject)
+	if err != nil && !utils.IsNotFound(err) {
+		return nil, nil, errors.Wrap(err, "get user identity")
+	}
+	if err == nil {
+		user, err := UserService.GetAssociatedUser(ctx, userIdentity)
+		if err != nil {
+			return nil, nil, err
+		}
+		return user, userIdentity, nil
+	}
+
+	var user *models.User
+	if identity.Email != "" && identity.EmailVerified {
+		user, err = UserService.GetByEmail(ctx, identity.Email)
+		if err != nil && !utils.IsNotFound(err) {
+			return nil, nil, errors.Wrap(err, "get user by email")
+		}
+		if err != nil {
+			user = nil
+		}
+	}
+	if user == nil {
+		user, err = s.provisionUser(ctx, identity)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "provision user")
+		}
+	}
+	userIdentity, err = UserIdentityService.Create(ctx, CreateUserIdentityOption{
+		UserId:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create user identity")
+	}
+	return user, userIdentity, nil
+}
+
+var invalidUsernameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func (s *identityProviderService) provisionUser(ctx context.Context, identity *ExternalIdentity) (*models.User, error) {
+	baseName := identity.Username
+	if baseName == "" && identity.Email != "" {
+		baseName, _, _ = strings.Cut(identity.Email, "@")
+	}
+	baseName = strings.Trim(invalidUsernameChars.ReplaceAllString(strings.ToLower(baseName), "-"), "-")
+	if baseName == "" {
+		baseName = fmt.Sprintf("%s-user", identity.Provider)
+	}
+	name := baseName
+	for i := 2; ; i++ {
+		_, err := UserService.GetByName(ctx, name)
+		if utils.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "get user %s", name)
+		}
+		name = fmt.Sprintf("%s-%d", baseName, i)
+	}
+	var email *string
+	if identity.Email != "" && identity.EmailVerified {
+		email = &identity.Email
+	}
+	// the user can only log in through the identity provider, so the password is random and never shown
+	return UserService.Create(ctx, CreateUserOption{
+		Name:            name,
+		FirstName:       identity.FirstName,
+		LastName:        identity.LastName,
+		Email:           email,
+		Password:        utils.RandString(32),
+		Perm:            modelschemas.UserPermPtr(modelschemas.UserPermDefault),
+		IsEmailVerified: email != nil,
+	})
+}
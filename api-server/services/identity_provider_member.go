@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/config"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+var memberRoleRanks = map[modelschemas.MemberRole]int{
+	modelschemas.MemberRoleGuest:     1,
+	modelschemas.MemberRoleDeveloper: 2,
+	modelschemas.MemberRoleAdmin:     3,
+}
+
+type memberRoleGrant struct {
+	creatorId uint
+	role      modelschemas.MemberRole
+}
+
+func mergeMemberRoleGrant(grants map[uint]memberRoleGrant, resourceId uint, grant memberRoleGrant) {
+	if old, ok := grants[resourceId]; ok && memberRoleRanks[old.role] >= memberRoleRanks[grant.role] {
+		return
+	}
+	grants[resourceId] = grant
+}
+
+// resolveRoleMappings returns the highest role granted by the groups of the identity on each organization and cluster
+func (s *identityProviderService) resolveRoleMappings(ctx context.Context, conf *config.YataiIdentityProviderConfigYaml, groups []string) (organizationGrants, clusterGrants map[uint]memberRoleGrant, err error) {
+	organizationGrants = make(map[uint]memberRoleGrant)
+	clusterGrants = make(map[uint]memberRoleGrant)
+	groupSet := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		groupSet[group] = struct{}{}
+	}
+	logger := logrus.WithField("identityProvider", conf.Name)
+	for _, mapping := range conf.RoleMappings {
+		if _, ok := groupSet[mapping.Group]; !ok {
+			continue
+		}
+		role := modelschemas.MemberRole(mapping.Role)
+		if _, ok := memberRoleRanks[role]; !ok {
+			logger.Warnf("ignore the role mapping of group %s: unknown role %s", mapping.Group, mapping.Role)
+			continue
+		}
+		organization, err := OrganizationService.GetByName(ctx, mapping.Organization)
+		if utils.IsNotFound(err) {
+			logger.Warnf("ignore the role mapping of group %s: organization %s not found", mapping.Group, mapping.Organization)
+			continue
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "get organization %s", mapping.Organization)
+		}
+		if mapping.Cluster == "" {
+			mergeMemberRoleGrant(organizationGrants, organization.ID, memberRoleGrant{
+				creatorId: organization.CreatorId,
+				role:      role,
+			})
+			continue
+		}
+		cluster, err := ClusterService.GetByName(ctx, organization.ID, mapping.Cluster)
+		if utils.IsNotFound(err) {
+			logger.Warnf("ignore the role mapping of group %s: cluster %s not found in organization %s", mapping.Group, mapping.Cluster, mapping.Organization)
+			continue
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "get cluster %s", mapping.Cluster)
+		}
+		mergeMemberRoleGrant(clusterGrants, cluster.ID, memberRoleGrant{
+			creatorId: cluster.CreatorId,
+			role:      role,
+		})
+	}
+	return organizationGrants, clusterGrants, nil
+}
+
+func isMemberManagedBy(member *string, identityProvider string) bool {
+	return member != nil && *member == identityProvider
+}
+
+// SyncMembers makes the memberships granted by the role mappings of the identity provider match the current groups of the user.
+// Memberships created by hand are never touched, so an admin can always override a role mapping for a single user.
+func (s *identityProviderService) SyncMembers(ctx context.Context, user *models.User, identity *ExternalIdentity) (err error) {
+	conf, err := s.GetConfig(identity.Provider)
+	if err != nil {
+		return err
+	}
+	organizationGrants, clusterGrants, err := s.resolveRoleMappings(ctx, conf, identity.Groups)
+	if err != nil {
+		return err
+	}
+
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+
+	organizationMembers, err := OrganizationMemberService.List(ctx, ListOrganizationMemberOption{
+		UserId: &user.ID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "list organization members")
+		return
+	}
+	for _, member := range organizationMembers {
+		if member.DeletedAt.Valid {
+			continue
+		}
+		grant, granted := organizationGrants[member.OrganizationId]
+		delete(organizationGrants, member.OrganizationId)
+		if !isMemberManagedBy(member.IdentityProvider, conf.Name) {
+			continue
+		}
+		if !granted {
+			_, err = OrganizationMemberService.Delete(ctx, member, user.ID)
+		} else if member.Role != grant.role {
+			_, err = OrganizationMemberService.Update(ctx, member, user.ID, UpdateOrganizationMemberOption{
+				Role: grant.role,
+			})
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "sync organization member %d", member.ID)
+			return
+		}
+	}
+	for organizationId, grant := range organizationGrants {
+		_, err = OrganizationMemberService.Create(ctx, user.ID, CreateOrganizationMemberOption{
+			CreatorId:        grant.creatorId,
+			UserId:           user.ID,
+			OrganizationId:   organizationId,
+			Role:             grant.role,
+			IdentityProvider: &conf.Name,
+		})
+		if err != nil {
+			err = errors.Wrapf(err, "create organization member of organization %d", organizationId)
+			return
+		}
+	}
+
+	clusterMembers, err := ClusterMemberService.List(ctx, ListClusterMemberOption{
+		UserId: &user.ID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "list cluster members")
+		return
+	}
+	for _, member := range clusterMembers {
+		if member.DeletedAt.Valid {
+			continue
+		}
+		grant, granted := clusterGrants[member.ClusterId]
+		delete(clusterGrants, member.ClusterId)
+		if !isMemberManagedBy(member.IdentityProvider, conf.Name) {
+			continue
+		}
+		if !granted {
+			_, err = ClusterMemberService.Delete(ctx, member, user.ID)
+		} else if member.Role != grant.role {
+			_, err = ClusterMemberService.Update(ctx, member, user.ID, UpdateClusterMemberOption{
+				Role: grant.role,
+			})
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "sync cluster member %d", member.ID)
+			return
+		}
+	}
+	for clusterId, grant := range clusterGrants {
+		_, err = ClusterMemberService.Create(ctx, user.ID, CreateClusterMemberOption{
+			CreatorId:        grant.creatorId,
+			UserId:           user.ID,
+			ClusterId:        clusterId,
+			Role:             grant.role,
+			IdentityProvider: &conf.Name,
+		})
+		if err != nil {
+			err = errors.Wrapf(err, "create cluster member of cluster %d", clusterId)
+			return
+		}
+	}
+	return
+}
@@ -44,7 +44,7 @@ func (s *kubeBentoDeploymentService) transformToBentoDeploymentV1alpha2(ctx cont
 
 	var autoscalingSpec *modelschemas.DeploymentTargetHPAConf
 	if deploymentTarget.Config != nil {
-		autoscalingSpec = deploymentTarget.Config.HPAConf
+		autoscalingSpec = getKubeHPAConf(deploymentTarget.Config.HPAConf)
 	}
 
 	envs := make([]modelschemas.LabelItemSchema, 0)
@@ -72,7 +72,7 @@ func (s *kubeBentoDeploymentService) transformToBentoDeploymentV1alpha2(ctx cont
 			runners = append(runners, servingv1alpha2.BentoDeploymentRunnerSpec{
 				Name:        name,
 				Resources:   servingconversion.ConvertFromDeploymentTargetResources(runner.Resources),
-				Autoscaling: runner.HPAConf,
+				Autoscaling: getKubeHPAConf(runner.HPAConf),
 				Envs:        &envs_,
 			})
 		}
@@ -530,98 +530,103 @@ func (s *kubeBentoDeploymentService) DeployV2alpha1(ctx context.Context, deploym
 			return
 		}
 	} else {
-		kubeBentoDeployment.SetResourceVersion(oldKubeBentoDeployment.GetResourceVersion())
-		if kubeBentoDeployment.Annotations == nil {
-			kubeBentoDeployment.Annotations = map[string]string{}
-		}
-		for k, v := range oldKubeBentoDeployment.Annotations {
-			if _, ok := kubeBentoDeployment.Annotations[k]; !ok {
-				kubeBentoDeployment.Annotations[k] = v
-			}
+		s.mergeOldKubeBentoDeploymentV2alpha1(deploymentTarget, kubeBentoDeployment, oldKubeBentoDeployment)
+		kubeBentoDeployment, err = cli.Update(ctx, kubeBentoDeployment, metav1.UpdateOptions{})
+		if err != nil {
+			err = errors.Wrapf(err, "failed to update kube bento deployment %s", kubeBentoDeployment.Name)
+			return
 		}
-		if kubeBentoDeployment.Labels == nil {
-			kubeBentoDeployment.Labels = map[string]string{}
+	}
+	return
+}
+
+// mergeOldKubeBentoDeploymentV2alpha1 keeps what is managed outside of yatai on the live BentoDeployment when it is updated
+func (s *kubeBentoDeploymentService) mergeOldKubeBentoDeploymentV2alpha1(deploymentTarget *models.DeploymentTarget, kubeBentoDeployment, oldKubeBentoDeployment *servingv2alpha1.BentoDeployment) {
+	kubeBentoDeployment.SetResourceVersion(oldKubeBentoDeployment.GetResourceVersion())
+	if kubeBentoDeployment.Annotations == nil {
+		kubeBentoDeployment.Annotations = map[string]string{}
+	}
+	for k, v := range oldKubeBentoDeployment.Annotations {
+		if _, ok := kubeBentoDeployment.Annotations[k]; !ok {
+			kubeBentoDeployment.Annotations[k] = v
 		}
-		for k, v := range oldKubeBentoDeployment.Labels {
-			if _, ok := kubeBentoDeployment.Labels[k]; !ok {
-				kubeBentoDeployment.Labels[k] = v
-			}
+	}
+	if kubeBentoDeployment.Labels == nil {
+		kubeBentoDeployment.Labels = map[string]string{}
+	}
+	for k, v := range oldKubeBentoDeployment.Labels {
+		if _, ok := kubeBentoDeployment.Labels[k]; !ok {
+			kubeBentoDeployment.Labels[k] = v
 		}
-		// copy old spec annotations
-		if kubeBentoDeployment.Spec.Annotations == nil {
-			kubeBentoDeployment.Spec.Annotations = make(map[string]string)
+	}
+	// copy old spec annotations
+	if kubeBentoDeployment.Spec.Annotations == nil {
+		kubeBentoDeployment.Spec.Annotations = make(map[string]string)
+	}
+	for k, v := range oldKubeBentoDeployment.Spec.Annotations {
+		if _, ok := kubeBentoDeployment.Spec.Annotations[k]; !ok {
+			kubeBentoDeployment.Spec.Annotations[k] = v
 		}
-		for k, v := range oldKubeBentoDeployment.Spec.Annotations {
-			if _, ok := kubeBentoDeployment.Spec.Annotations[k]; !ok {
-				kubeBentoDeployment.Spec.Annotations[k] = v
-			}
+	}
+	kubeBentoDeployment.Spec.Labels = oldKubeBentoDeployment.Spec.Labels
+	kubeBentoDeployment.Spec.Ingress.Annotations = oldKubeBentoDeployment.Spec.Ingress.Annotations
+	kubeBentoDeployment.Spec.Ingress.Labels = oldKubeBentoDeployment.Spec.Ingress.Labels
+	kubeBentoDeployment.Spec.Ingress.TLS = oldKubeBentoDeployment.Spec.Ingress.TLS
+	currentAutoscaling := kubeBentoDeployment.Spec.Autoscaling
+	kubeBentoDeployment.Spec.Autoscaling = oldKubeBentoDeployment.Spec.Autoscaling
+	if currentAutoscaling != nil {
+		if kubeBentoDeployment.Spec.Autoscaling == nil {
+			kubeBentoDeployment.Spec.Autoscaling = currentAutoscaling
+		} else {
+			kubeBentoDeployment.Spec.Autoscaling.MinReplicas = currentAutoscaling.MinReplicas
+			kubeBentoDeployment.Spec.Autoscaling.MaxReplicas = currentAutoscaling.MaxReplicas
 		}
-		kubeBentoDeployment.Spec.Labels = oldKubeBentoDeployment.Spec.Labels
-		kubeBentoDeployment.Spec.Ingress.Annotations = oldKubeBentoDeployment.Spec.Ingress.Annotations
-		kubeBentoDeployment.Spec.Ingress.Labels = oldKubeBentoDeployment.Spec.Ingress.Labels
-		kubeBentoDeployment.Spec.Ingress.TLS = oldKubeBentoDeployment.Spec.Ingress.TLS
-		currentAutoscaling := kubeBentoDeployment.Spec.Autoscaling
-		kubeBentoDeployment.Spec.Autoscaling = oldKubeBentoDeployment.Spec.Autoscaling
-		if currentAutoscaling != nil {
-			if kubeBentoDeployment.Spec.Autoscaling == nil {
-				kubeBentoDeployment.Spec.Autoscaling = currentAutoscaling
-			} else {
-				kubeBentoDeployment.Spec.Autoscaling.MinReplicas = currentAutoscaling.MinReplicas
-				kubeBentoDeployment.Spec.Autoscaling.MaxReplicas = currentAutoscaling.MaxReplicas
-			}
+	}
+	for idx, runner := range kubeBentoDeployment.Spec.Runners {
+		var runnerConfig *modelschemas.DeploymentTargetRunnerConfig
+		if deploymentTarget.Config != nil {
+			runnerConfig_ := deploymentTarget.Config.Runners[runner.Name]
+			runnerConfig = &runnerConfig_
 		}
-		for idx, runner := range kubeBentoDeployment.Spec.Runners {
-			var runnerConfig *modelschemas.DeploymentTargetRunnerConfig
-			if deploymentTarget.Config != nil {
-				runnerConfig_ := deploymentTarget.Config.Runners[runner.Name]
-				runnerConfig = &runnerConfig_
-			}
-			for _, oldRunner := range oldKubeBentoDeployment.Spec.Runners {
-				if runner.Name == oldRunner.Name {
-					if kubeBentoDeployment.Spec.Runners[idx].Annotations == nil {
-						kubeBentoDeployment.Spec.Runners[idx].Annotations = make(map[string]string)
+		for _, oldRunner := range oldKubeBentoDeployment.Spec.Runners {
+			if runner.Name == oldRunner.Name {
+				if kubeBentoDeployment.Spec.Runners[idx].Annotations == nil {
+					kubeBentoDeployment.Spec.Runners[idx].Annotations = make(map[string]string)
+				}
+				if runnerConfig != nil {
+					if runnerConfig.EnableDebugMode != nil && *runnerConfig.EnableDebugMode {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugMode] = commonconsts.KubeLabelValueTrue
+					} else {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugMode] = commonconsts.KubeLabelValueFalse
 					}
-					if runnerConfig != nil {
-						if runnerConfig.EnableDebugMode != nil && *runnerConfig.EnableDebugMode {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugMode] = commonconsts.KubeLabelValueTrue
-						} else {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugMode] = commonconsts.KubeLabelValueFalse
-						}
-						if runnerConfig.EnableStealingTrafficDebugMode != nil && *runnerConfig.EnableStealingTrafficDebugMode {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableStealingTrafficDebugMode] = commonconsts.KubeLabelValueTrue
-						} else {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableStealingTrafficDebugMode] = commonconsts.KubeLabelValueFalse
-						}
-						if runnerConfig.EnableDebugPodReceiveProductionTraffic != nil && *runnerConfig.EnableDebugPodReceiveProductionTraffic {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugPodReceiveProductionTraffic] = commonconsts.KubeLabelValueTrue
-						} else {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugPodReceiveProductionTraffic] = commonconsts.KubeLabelValueFalse
-						}
+					if runnerConfig.EnableStealingTrafficDebugMode != nil && *runnerConfig.EnableStealingTrafficDebugMode {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableStealingTrafficDebugMode] = commonconsts.KubeLabelValueTrue
+					} else {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableStealingTrafficDebugMode] = commonconsts.KubeLabelValueFalse
 					}
-					for k, v := range oldRunner.Annotations {
-						if _, ok := kubeBentoDeployment.Spec.Runners[idx].Annotations[k]; !ok {
-							kubeBentoDeployment.Spec.Runners[idx].Annotations[k] = v
-						}
+					if runnerConfig.EnableDebugPodReceiveProductionTraffic != nil && *runnerConfig.EnableDebugPodReceiveProductionTraffic {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugPodReceiveProductionTraffic] = commonconsts.KubeLabelValueTrue
+					} else {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[KubeAnnotationEnableDebugPodReceiveProductionTraffic] = commonconsts.KubeLabelValueFalse
 					}
-					kubeBentoDeployment.Spec.Runners[idx].Labels = oldRunner.Labels
-					currentAutoscaling := kubeBentoDeployment.Spec.Runners[idx].Autoscaling
-					kubeBentoDeployment.Spec.Runners[idx].Autoscaling = oldRunner.Autoscaling
-					if currentAutoscaling != nil {
-						if kubeBentoDeployment.Spec.Runners[idx].Autoscaling == nil {
-							kubeBentoDeployment.Spec.Runners[idx].Autoscaling = currentAutoscaling
-						} else {
-							kubeBentoDeployment.Spec.Runners[idx].Autoscaling.MinReplicas = currentAutoscaling.MinReplicas
-							kubeBentoDeployment.Spec.Runners[idx].Autoscaling.MaxReplicas = currentAutoscaling.MaxReplicas
-						}
+				}
+				for k, v := range oldRunner.Annotations {
+					if _, ok := kubeBentoDeployment.Spec.Runners[idx].Annotations[k]; !ok {
+						kubeBentoDeployment.Spec.Runners[idx].Annotations[k] = v
+					}
+				}
+				kubeBentoDeployment.Spec.Runners[idx].Labels = oldRunner.Labels
+				currentAutoscaling := kubeBentoDeployment.Spec.Runners[idx].Autoscaling
+				kubeBentoDeployment.Spec.Runners[idx].Autoscaling = oldRunner.Autoscaling
+				if currentAutoscaling != nil {
+					if kubeBentoDeployment.Spec.Runners[idx].Autoscaling == nil {
+						kubeBentoDeployment.Spec.Runners[idx].Autoscaling = currentAutoscaling
+					} else {
+						kubeBentoDeployment.Spec.Runners[idx].Autoscaling.MinReplicas = currentAutoscaling.MinReplicas
+						kubeBentoDeployment.Spec.Runners[idx].Autoscaling.MaxReplicas = currentAutoscaling.MaxReplicas
 					}
 				}
 			}
 		}
-		kubeBentoDeployment, err = cli.Update(ctx, kubeBentoDeployment, metav1.UpdateOptions{})
-		if err != nil {
-			err = errors.Wrapf(err, "failed to update kube bento deployment %s", kubeBentoDeployment.Name)
-			return
-		}
 	}
-	return
 }
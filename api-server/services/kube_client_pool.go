@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/sync/errsgroup"
+)
+
+const kubeClusterProbeTimeout = 5 * time.Second
+
+// KubeClusterHealth is the result of the last probe of the kube api server of a cluster
+type KubeClusterHealth struct {
+	Reachable bool `json:"reachable"`
+	// LatencyMs is the duration of the probe in milliseconds
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type kubeClientPoolEntry struct {
+	// kubeConfig is the stored kube config of the cluster the clients were built from
+	kubeConfig string
+	clientSet  *kubernetes.Clientset
+	restConfig *rest.Config
+}
+
+// kubeClientPool keeps the kube clients of the clusters for the lifetime of the api server,
+// the clients of a cluster are rebuilt when its kube config changes
+type kubeClientPool struct {
+	mu      sync.RWMutex
+	entries map[uint]*kubeClientPoolEntry
+	health  map[uint]*KubeClusterHealth
+	logger  *logrus.Entry
+}
+
+var KubeClientPool = &kubeClientPool{
+	entries: make(map[uint]*kubeClientPoolEntry),
+	health:  make(map[uint]*KubeClusterHealth),
+	logger:  logrus.New().WithField("pool", "kube client"),
+}
+
+// Get returns the clients of the cluster, the rest config is a copy which can be modified by the caller
+func (p *kubeClientPool) Get(c *models.Cluster) (*kubernetes.Clientset, *rest.Config, error) {
+	p.mu.RLock()
+	entry, ok := p.entries[c.ID]
+	p.mu.RUnlock()
+	// the kube config is compared to catch the updates made by the other replicas
+	if ok && entry.kubeConfig == c.KubeConfig {
+		return entry.clientSet, rest.CopyConfig(entry.restConfig), nil
+	}
+
+	clientSet, restConfig, err := ClusterService.newKubeCliSet(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.mu.Lock()
+	p.entries[c.ID] = &kubeClientPoolEntry{
+		kubeConfig: c.KubeConfig,
+		clientSet:  clientSet,
+		restConfig: restConfig,
+	}
+	p.mu.Unlock()
+	return clientSet, rest.CopyConfig(restConfig), nil
+}
+
+// Invalidate drops the clients and the health of the cluster, they are rebuilt by the next Get
+func (p *kubeClientPool) Invalidate(clusterId uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.entries, clusterId)
+	delete(p.health, clusterId)
+}
+
+// GetHealth returns the last probe result of the cluster, it is nil until the cluster is probed
+func (p *kubeClientPool) GetHealth(clusterId uint) *KubeClusterHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	health, ok := p.health[clusterId]
+	if !ok {
+		return nil
+	}
+	health_ := *health
+	return &health_
+}
+
+// Probe requests the readiness endpoint of the kube api server of the cluster and records its latency
+func (p *kubeClientPool) Probe(ctx context.Context, c *models.Cluster) *KubeClusterHealth {
+	health := &KubeClusterHealth{}
+	clientSet, _, err := p.Get(c)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, kubeClusterProbeTimeout)
+		defer cancel()
+		startedAt := time.Now()
+		_, err = clientSet.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+		health.LatencyMs = time.Since(startedAt).Milliseconds()
+		err = errors.Wrap(err, "request the kube api server readiness")
+	}
+	health.Reachable = err == nil
+	if err != nil {
+		health.Error = err.Error()
+	}
+	health.CheckedAt = time.Now()
+
+	p.mu.Lock()
+	p.health[c.ID] = health
+	p.mu.Unlock()
+	return health
+}
+
+// ProbeAll probes all the clusters and drops the clients of the clusters which no longer exist
+func (p *kubeClientPool) ProbeAll(ctx context.Context) error {
+	clusters, _, err := ClusterService.List(ctx, ListClusterOption{})
+	if err != nil {
+		return errors.Wrap(err, "list clusters")
+	}
+
+	clusterIds := make(map[uint]struct{}, len(clusters))
+	for _, cluster := range clusters {
+		clusterIds[cluster.ID] = struct{}{}
+	}
+	p.mu.Lock()
+	for clusterId := range p.entries {
+		if _, ok := clusterIds[clusterId]; !ok {
+			delete(p.entries, clusterId)
+			delete(p.health, clusterId)
+		}
+	}
+	p.mu.Unlock()
+
+	var eg errsgroup.Group
+	eg.SetPoolSize(10)
+	for _, cluster := range clusters {
+		cluster := cluster
+		eg.Go(func() error {
+			health := p.Probe(ctx, cluster)
+			if !health.Reachable {
+				p.logger.Warnf("cluster %s is unreachable: %s", cluster.Name, health.Error)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
@@ -2,11 +2,9 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/viney-shih/go-lock"
 	"k8s.io/client-go/informers"
 	informerAppsV1 "k8s.io/client-go/informers/apps/v1"
 	informerCoreV1 "k8s.io/client-go/informers/core/v1"
@@ -16,44 +14,27 @@ import (
 	listerNetworkingV1 "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 
+	servingversioned "github.com/bentoml/yatai-deployment/generated/serving/clientset/versioned"
+	servinginformers "github.com/bentoml/yatai-deployment/generated/serving/informers/externalversions"
+
 	"github.com/bentoml/yatai/api-server/models"
 )
 
-type CacheKey string
-
-var (
-	informerSyncTimeout = 30 * time.Second
-
-	informerFactoryCache   = make(map[CacheKey]informers.SharedInformerFactory)
-	informerFactoryCacheRW = lock.NewCASMutex()
-)
+var informerSyncTimeout = 30 * time.Second
 
 type getSharedInformerFactoryOption struct {
 	cluster   *models.Cluster
 	namespace *string
 }
 
-func getSharedInformerFactory(ctx context.Context, option *getSharedInformerFactoryOption) (informers.SharedInformerFactory, error) {
-	org, err := OrganizationService.GetAssociatedOrganization(ctx, option.cluster)
-	if err != nil {
-		err = errors.Wrapf(err, "get associated organization for cluster %d", option.cluster.ID)
-		return nil, err
+func getSharedInformerFactory(ctx context.Context, option *getSharedInformerFactoryOption) (*kubeInformerFactoryEntry, informers.SharedInformerFactory, error) {
+	key := kubeInformerFactoryKey{
+		clusterId: option.cluster.ID,
 	}
-	var cacheKey CacheKey
 	if option.namespace != nil {
-		cacheKey = CacheKey(fmt.Sprintf("%s:%s:%s", org.Name, option.cluster.Name, *option.namespace))
-	} else {
-		cacheKey = CacheKey(fmt.Sprintf("%s:%s", org.Name, option.cluster.Name))
-	}
-
-	if locked := informerFactoryCacheRW.TryLockWithContext(ctx); !locked {
-		return nil, errors.New("failed to get informer factory cache lock")
+		key.namespace = *option.namespace
 	}
-	defer informerFactoryCacheRW.Unlock()
-
-	var factory informers.SharedInformerFactory
-	var ok bool
-	if factory, ok = informerFactoryCache[cacheKey]; !ok {
+	entry, err := KubeInformerManager.getFactory(option.cluster, key, func() (kubeInformerFactory, error) {
 		clientset, _, err := ClusterService.GetKubeCliSet(ctx, option.cluster)
 		if err != nil {
 			err = errors.Wrapf(err, "get kubernetes client set for cluster %d", option.cluster.ID)
@@ -63,28 +44,21 @@ func getSharedInformerFactory(ctx context.Context, option *getSharedInformerFact
 		if option.namespace != nil {
 			informerOptions = append(informerOptions, informers.WithNamespace(*option.namespace))
 		}
-		factory = informers.NewSharedInformerFactoryWithOptions(clientset, 0, informerOptions...)
+		return informers.NewSharedInformerFactoryWithOptions(clientset, 0, informerOptions...), nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return factory, nil
+	return entry, entry.factory.(informers.SharedInformerFactory), nil
 }
 
-func startAndSyncInformer(ctx context.Context, informer cache.SharedIndexInformer) (err error) {
-	go informer.Run(ctx.Done())
-
-	ctx_, cancel := context.WithTimeout(ctx, informerSyncTimeout)
-	defer cancel()
-
-	if !cache.WaitForCacheSync(ctx_.Done(), informer.HasSynced) {
-		err = errors.New("Timed out waiting for caches to sync informer")
-		return err
-	}
-
-	return nil
+// startAndSyncInformer runs the informer until it is evicted by the KubeInformerManager, the ctx only bounds the wait for the sync
+func startAndSyncInformer(ctx context.Context, entry *kubeInformerFactoryEntry, resource string, informer cache.SharedIndexInformer) error {
+	return KubeInformerManager.startAndSync(ctx, entry, resource, informer)
 }
 
 func GetPodInformer(ctx context.Context, cluster *models.Cluster, namespace string) (informerCoreV1.PodInformer, listerCoreV1.PodNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   cluster,
 		namespace: &namespace,
 	})
@@ -92,7 +66,7 @@ func GetPodInformer(ctx context.Context, cluster *models.Cluster, namespace stri
 		return nil, nil, err
 	}
 	podInformer := factory.Core().V1().Pods()
-	err = startAndSyncInformer(ctx, podInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "pods", podInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -100,7 +74,7 @@ func GetPodInformer(ctx context.Context, cluster *models.Cluster, namespace stri
 }
 
 func GetDeploymentInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerAppsV1.DeploymentInformer, listerAppsV1.DeploymentNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -108,7 +82,7 @@ func GetDeploymentInformer(ctx context.Context, kubeCluster *models.Cluster, nam
 		return nil, nil, err
 	}
 	deploymentInformer := factory.Apps().V1().Deployments()
-	err = startAndSyncInformer(ctx, deploymentInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "deployments", deploymentInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -116,7 +90,7 @@ func GetDeploymentInformer(ctx context.Context, kubeCluster *models.Cluster, nam
 }
 
 func GetStatefulSetInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerAppsV1.StatefulSetInformer, listerAppsV1.StatefulSetNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -124,7 +98,7 @@ func GetStatefulSetInformer(ctx context.Context, kubeCluster *models.Cluster, na
 		return nil, nil, err
 	}
 	statefulSetInformer := factory.Apps().V1().StatefulSets()
-	err = startAndSyncInformer(ctx, statefulSetInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "statefulsets", statefulSetInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -132,7 +106,7 @@ func GetStatefulSetInformer(ctx context.Context, kubeCluster *models.Cluster, na
 }
 
 func GetIngressInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerNetworkingV1.IngressInformer, listerNetworkingV1.IngressNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -140,7 +114,7 @@ func GetIngressInformer(ctx context.Context, kubeCluster *models.Cluster, namesp
 		return nil, nil, err
 	}
 	ingressInformer := factory.Networking().V1().Ingresses()
-	err = startAndSyncInformer(ctx, ingressInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "ingresses", ingressInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -148,7 +122,7 @@ func GetIngressInformer(ctx context.Context, kubeCluster *models.Cluster, namesp
 }
 
 func GetDaemonSetInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerAppsV1.DaemonSetInformer, listerAppsV1.DaemonSetNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -156,7 +130,7 @@ func GetDaemonSetInformer(ctx context.Context, kubeCluster *models.Cluster, name
 		return nil, nil, err
 	}
 	daemonSetInformer := factory.Apps().V1().DaemonSets()
-	err = startAndSyncInformer(ctx, daemonSetInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "daemonsets", daemonSetInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -164,7 +138,7 @@ func GetDaemonSetInformer(ctx context.Context, kubeCluster *models.Cluster, name
 }
 
 func GetEventInformer(ctx context.Context, cluster *models.Cluster, namespace string) (informerCoreV1.EventInformer, listerCoreV1.EventNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   cluster,
 		namespace: &namespace,
 	})
@@ -172,7 +146,7 @@ func GetEventInformer(ctx context.Context, cluster *models.Cluster, namespace st
 		return nil, nil, err
 	}
 	eventInformer := factory.Core().V1().Events()
-	err = startAndSyncInformer(ctx, eventInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "events", eventInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -180,7 +154,7 @@ func GetEventInformer(ctx context.Context, cluster *models.Cluster, namespace st
 }
 
 func GetNodeEventInformer(ctx context.Context, kubeCluster *models.Cluster) (informerCoreV1.EventInformer, listerCoreV1.EventLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: nil,
 	})
@@ -188,7 +162,7 @@ func GetNodeEventInformer(ctx context.Context, kubeCluster *models.Cluster) (inf
 		return nil, nil, err
 	}
 	eventInformer := factory.Core().V1().Events()
-	err = startAndSyncInformer(ctx, eventInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "events", eventInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -196,7 +170,7 @@ func GetNodeEventInformer(ctx context.Context, kubeCluster *models.Cluster) (inf
 }
 
 func GetSecretInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerCoreV1.SecretInformer, listerCoreV1.SecretNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -204,7 +178,7 @@ func GetSecretInformer(ctx context.Context, kubeCluster *models.Cluster, namespa
 		return nil, nil, err
 	}
 	secretInformer := factory.Core().V1().Secrets()
-	err = startAndSyncInformer(ctx, secretInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "secrets", secretInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -212,7 +186,7 @@ func GetSecretInformer(ctx context.Context, kubeCluster *models.Cluster, namespa
 }
 
 func GetConfigMapInformer(ctx context.Context, kubeCluster *models.Cluster, namespace string) (informerCoreV1.ConfigMapInformer, listerCoreV1.ConfigMapNamespaceLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: &namespace,
 	})
@@ -220,7 +194,7 @@ func GetConfigMapInformer(ctx context.Context, kubeCluster *models.Cluster, name
 		return nil, nil, err
 	}
 	configMapInformer := factory.Core().V1().ConfigMaps()
-	err = startAndSyncInformer(ctx, configMapInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "configmaps", configMapInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -228,7 +202,7 @@ func GetConfigMapInformer(ctx context.Context, kubeCluster *models.Cluster, name
 }
 
 func GetNodeInformer(ctx context.Context, kubeCluster *models.Cluster) (informerCoreV1.NodeInformer, listerCoreV1.NodeLister, error) {
-	factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
+	entry, factory, err := getSharedInformerFactory(ctx, &getSharedInformerFactoryOption{
 		cluster:   kubeCluster,
 		namespace: nil,
 	})
@@ -236,9 +210,58 @@ func GetNodeInformer(ctx context.Context, kubeCluster *models.Cluster) (informer
 		return nil, nil, err
 	}
 	nodeInformer := factory.Core().V1().Nodes()
-	err = startAndSyncInformer(ctx, nodeInformer.Informer())
+	err = startAndSyncInformer(ctx, entry, "nodes", nodeInformer.Informer())
 	if err != nil {
 		return nil, nil, err
 	}
 	return nodeInformer, nodeInformer.Lister(), nil
 }
+
+func getServingSharedInformerFactory(ctx context.Context, cluster *models.Cluster, namespace string) (*kubeInformerFactoryEntry, servinginformers.SharedInformerFactory, error) {
+	key := kubeInformerFactoryKey{
+		clusterId: cluster.ID,
+		namespace: namespace,
+		serving:   true,
+	}
+	entry, err := KubeInformerManager.getFactory(cluster, key, func() (kubeInformerFactory, error) {
+		_, restConf, err := ClusterService.GetKubeCliSet(ctx, cluster)
+		if err != nil {
+			err = errors.Wrapf(err, "get kubernetes client set for cluster %d", cluster.ID)
+			return nil, err
+		}
+		clientset, err := servingversioned.NewForConfig(restConf)
+		if err != nil {
+			err = errors.Wrapf(err, "get bento deployment client set for cluster %d", cluster.ID)
+			return nil, err
+		}
+		return servinginformers.NewSharedInformerFactoryWithOptions(clientset, 0, servinginformers.WithNamespace(namespace)), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, entry.factory.(servinginformers.SharedInformerFactory), nil
+}
+
+// GetBentoDeploymentInformer returns the informer of the BentoDeployments of the given CRD version
+func GetBentoDeploymentInformer(ctx context.Context, cluster *models.Cluster, namespace, crdVersion string) (cache.SharedIndexInformer, error) {
+	entry, factory, err := getServingSharedInformerFactory(ctx, cluster, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var informer cache.SharedIndexInformer
+	switch crdVersion {
+	case "v1alpha2":
+		informer = factory.Serving().V1alpha2().BentoDeployments().Informer()
+	case "v1alpha3":
+		informer = factory.Serving().V1alpha3().BentoDeployments().Informer()
+	case "v2alpha1":
+		informer = factory.Serving().V2alpha1().BentoDeployments().Informer()
+	default:
+		return nil, errors.Errorf("unsupported BentoDeployment CRD version %s", crdVersion)
+	}
+	err = startAndSyncInformer(ctx, entry, "bentodeployments."+crdVersion, informer)
+	if err != nil {
+		return nil, err
+	}
+	return informer, nil
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+const kubeInformerIdleTimeout = 10 * time.Minute
+
+// kubeInformerHandlerQueueMaxEvents bounds the events queued for a handler, the backlog of a handler which can not keep up
+// is dropped and replaced by a replay of the informer store, the handlers only use the events to know what to refresh
+const kubeInformerHandlerQueueMaxEvents = 1000
+
+var (
+	kubeInformerFactoriesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "factories",
+		Help:      "Number of the running kube informer factories, one per cluster namespace",
+	})
+	kubeInformersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "informers",
+		Help:      "Number of the running kube informers",
+	})
+	kubeInformerHandlersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "event_handlers",
+		Help:      "Number of the event handlers registered on the kube informers",
+	})
+	kubeInformerQueuedEventsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "queued_events",
+		Help:      "Number of the events queued for the event handlers which are not handled yet",
+	})
+	kubeInformerDroppedEventsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "dropped_events_total",
+		Help:      "Number of the queued events dropped because their event handler could not keep up, they are replaced by a replay of the informer store",
+	})
+	kubeInformerSyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of the initial list of the kube informers",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"resource"})
+	kubeInformerSyncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "yatai",
+		Subsystem: "kube_informer",
+		Name:      "sync_failures_total",
+		Help:      "Number of the kube informers which failed to sync in time",
+	}, []string{"resource"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		kubeInformerFactoriesGauge,
+		kubeInformersGauge,
+		kubeInformerHandlersGauge,
+		kubeInformerQueuedEventsGauge,
+		kubeInformerDroppedEventsCounter,
+		kubeInformerSyncDuration,
+		kubeInformerSyncFailures,
+	)
+}
+
+// kubeInformerFactory is implemented by the factories of the kube resources and of the BentoDeployments
+type kubeInformerFactory interface {
+	Start(stopCh <-chan struct{})
+}
+
+type kubeInformerFactoryKey struct {
+	clusterId uint
+	// namespace is empty for the cluster wide factories
+	namespace string
+	serving   bool
+}
+
+type kubeInformerFactoryEntry struct {
+	key kubeInformerFactoryKey
+	// kubeConfig is the stored kube config of the cluster the factory was built from
+	kubeConfig string
+	factory    kubeInformerFactory
+	ctx        context.Context
+	cancel     context.CancelFunc
+	informers  map[cache.SharedIndexInformer]*kubeInformerDispatcher
+	// refs counts the event handlers, the factory is evicted once it has none and it has been idle for a while
+	refs       int
+	lastUsedAt time.Time
+	// retired factories are replaced by a new one, they are stopped once their last event handler is removed
+	retired bool
+}
+
+// kubeInformerHandlerQueue runs the events of a handler in its own goroutine in the order they were dispatched,
+// so that a slow handler such as a websocket does not block the informer nor the other handlers
+type kubeInformerHandlerQueue struct {
+	handler cache.ResourceEventHandler
+	// resync replays the informer store to the handler, it replaces the backlog once the queue is full
+	resync    func(handler cache.ResourceEventHandler)
+	maxEvents int
+	mu        sync.Mutex
+	events    []func(handler cache.ResourceEventHandler)
+	// signal is buffered, it wakes up the goroutine once for all the events pushed meanwhile
+	signal   chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newKubeInformerHandlerQueue(handler cache.ResourceEventHandler, resync func(handler cache.ResourceEventHandler)) *kubeInformerHandlerQueue {
+	q := &kubeInformerHandlerQueue{
+		handler:   handler,
+		resync:    resync,
+		maxEvents: kubeInformerHandlerQueueMaxEvents,
+		signal:    make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *kubeInformerHandlerQueue) push(event func(handler cache.ResourceEventHandler)) {
+	q.mu.Lock()
+	if len(q.events) >= q.maxEvents {
+		// the replay is queued before the event, the objects it replays are at least as recent as the dropped events
+		kubeInformerDroppedEventsCounter.Add(float64(len(q.events)))
+		kubeInformerQueuedEventsGauge.Sub(float64(len(q.events)))
+		q.events = []func(handler cache.ResourceEventHandler){q.resync}
+		kubeInformerQueuedEventsGauge.Inc()
+	}
+	q.events = append(q.events, event)
+	kubeInformerQueuedEventsGauge.Inc()
+	q.mu.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *kubeInformerHandlerQueue) pop() (func(handler cache.ResourceEventHandler), bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.events) == 0 {
+		return nil, false
+	}
+	event := q.events[0]
+	q.events[0] = nil
+	q.events = q.events[1:]
+	kubeInformerQueuedEventsGauge.Dec()
+	return event, true
+}
+
+func (q *kubeInformerHandlerQueue) run() {
+	for {
+		select {
+		case <-q.stopCh:
+			return
+		case <-q.signal:
+		}
+		for {
+			select {
+			case <-q.stopCh:
+				return
+			default:
+			}
+			event, ok := q.pop()
+			if !ok {
+				break
+			}
+			event(q.handler)
+		}
+	}
+}
+
+// stop drops the queued events, it does not wait for the event being handled because the handlers may hold the lock of their owner
+func (q *kubeInformerHandlerQueue) stop() {
+	q.stopOnce.Do(func() {
+		close(q.stopCh)
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		kubeInformerQueuedEventsGauge.Sub(float64(len(q.events)))
+		q.events = nil
+	})
+}
+
+// kubeInformerDispatcher fans out the events of an informer to the queues of the handlers,
+// the informers of this client-go version can not remove their handlers
+type kubeInformerDispatcher struct {
+	mu       sync.RWMutex
+	nextId   uint64
+	handlers map[uint64]*kubeInformerHandlerQueue
+}
+
+func (d *kubeInformerDispatcher) dispatch(event func(handler cache.ResourceEventHandler)) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, q := range d.handlers {
+		q.push(event)
+	}
+}
+
+func (d *kubeInformerDispatcher) OnAdd(obj interface{}) {
+	d.dispatch(func(handler cache.ResourceEventHandler) {
+		handler.OnAdd(obj)
+	})
+}
+
+func (d *kubeInformerDispatcher) OnUpdate(oldObj, newObj interface{}) {
+	d.dispatch(func(handler cache.ResourceEventHandler) {
+		handler.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (d *kubeInformerDispatcher) OnDelete(obj interface{}) {
+	d.dispatch(func(handler cache.ResourceEventHandler) {
+		handler.OnDelete(obj)
+	})
+}
+
+// add registers the handler and replays the objects in the store to it like informer.AddEventHandler does,
+// the replayed objects are only queued under the lock so that they come before the next events, the handler runs outside of it
+func (d *kubeInformerDispatcher) add(informer cache.SharedIndexInformer, handler cache.ResourceEventHandler) uint64 {
+	q := newKubeInformerHandlerQueue(handler, func(handler cache.ResourceEventHandler) {
+		for _, obj := range informer.GetStore().List() {
+			handler.OnAdd(obj)
+		}
+	})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextId++
+	d.handlers[d.nextId] = q
+	for _, obj := range informer.GetStore().List() {
+		obj := obj
+		q.push(func(handler cache.ResourceEventHandler) {
+			handler.OnAdd(obj)
+		})
+	}
+	return d.nextId
+}
+
+// remove unregisters the handler, the event it is handling may still complete after it returns
+func (d *kubeInformerDispatcher) remove(id uint64) {
+	d.mu.Lock()
+	q, ok := d.handlers[id]
+	delete(d.handlers, id)
+	d.mu.Unlock()
+	if ok {
+		q.stop()
+	}
+}
+
+// stop stops the queues of all the handlers, the removal of the handlers is left to their owners
+func (d *kubeInformerDispatcher) stop() {
+	d.mu.Lock()
+	handlers := d.handlers
+	d.handlers = make(map[uint64]*kubeInformerHandlerQueue)
+	d.mu.Unlock()
+	for _, q := range handlers {
+		q.stop()
+	}
+}
+
+// kubeInformerManager keeps the informer factories of the clusters for the lifetime of the api server,
+// so that the websockets and the status syncs share the caches instead of listing from the kube api server every time
+type kubeInformerManager struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	stopped   bool
+	factories map[kubeInformerFactoryKey]*kubeInformerFactoryEntry
+	informers map[cache.SharedIndexInformer]*kubeInformerFactoryEntry
+	logger    *logrus.Entry
+}
+
+var KubeInformerManager = &kubeInformerManager{
+	factories: make(map[kubeInformerFactoryKey]*kubeInformerFactoryEntry),
+	informers: make(map[cache.SharedIndexInformer]*kubeInformerFactoryEntry),
+	logger:    logrus.New().WithField("manager", "kube informer"),
+}
+
+// Start makes the informers run until the ctx is done or the manager is stopped,
+// the informers started before run until the manager is stopped
+func (m *kubeInformerManager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ctx = ctx
+	m.stopped = false
+}
+
+// Stop stops all the informers and the queues of their handlers, the informers can not be got after it
+func (m *kubeInformerManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	for _, entry := range m.factories {
+		m.stopEntry(entry)
+	}
+	for _, entry := range m.informers {
+		// the retired ones
+		m.stopEntry(entry)
+	}
+}
+
+// stopEntry must be called with the lock held, the handlers still registered get no more events
+func (m *kubeInformerManager) stopEntry(entry *kubeInformerFactoryEntry) {
+	entry.cancel()
+	for _, dispatcher := range entry.informers {
+		dispatcher.stop()
+	}
+	if m.factories[entry.key] == entry {
+		delete(m.factories, entry.key)
+	}
+	for informer := range entry.informers {
+		delete(m.informers, informer)
+	}
+	m.updateGauges()
+}
+
+// updateGauges must be called with the lock held
+func (m *kubeInformerManager) updateGauges() {
+	kubeInformerFactoriesGauge.Set(float64(len(m.factories)))
+	kubeInformersGauge.Set(float64(len(m.informers)))
+	handlers := 0
+	seen := make(map[*kubeInformerFactoryEntry]struct{})
+	for _, entry := range m.informers {
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		handlers += entry.refs
+	}
+	kubeInformerHandlersGauge.Set(float64(handlers))
+}
+
+// getFactory returns the factory of the key, it is built by newFactory when the cluster has none or its kube config changed
+func (m *kubeInformerManager) getFactory(cluster *models.Cluster, key kubeInformerFactoryKey, newFactory func() (kubeInformerFactory, error)) (*kubeInformerFactoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return nil, errors.New("the kube informer manager is stopped")
+	}
+	entry, ok := m.factories[key]
+	if ok && entry.kubeConfig == cluster.KubeConfig && entry.ctx.Err() == nil {
+		entry.lastUsedAt = time.Now()
+		return entry, nil
+	}
+	if ok {
+		// the kube config is compared to catch the updates made by the other replicas
+		delete(m.factories, key)
+		entry.retired = true
+		if entry.refs == 0 {
+			m.stopEntry(entry)
+		}
+	}
+
+	factory, err := newFactory()
+	if err != nil {
+		return nil, err
+	}
+	parent := m.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	entry = &kubeInformerFactoryEntry{
+		key:        key,
+		kubeConfig: cluster.KubeConfig,
+		factory:    factory,
+		ctx:        ctx,
+		cancel:     cancel,
+		informers:  make(map[cache.SharedIndexInformer]*kubeInformerDispatcher),
+		lastUsedAt: time.Now(),
+	}
+	m.factories[key] = entry
+	m.updateGauges()
+	return entry, nil
+}
+
+// startAndSync starts the informer of the factory if it is not started yet and waits until it synced or the ctx is done
+func (m *kubeInformerManager) startAndSync(ctx context.Context, entry *kubeInformerFactoryEntry, resource string, informer cache.SharedIndexInformer) error {
+	m.mu.Lock()
+	if _, ok := entry.informers[informer]; !ok {
+		dispatcher := &kubeInformerDispatcher{
+			handlers: make(map[uint64]*kubeInformerHandlerQueue),
+		}
+		informer.AddEventHandler(dispatcher)
+		entry.informers[informer] = dispatcher
+		m.informers[informer] = entry
+		m.updateGauges()
+	}
+	m.mu.Unlock()
+
+	// the factory only starts the informers which are not started yet
+	entry.factory.Start(entry.ctx.Done())
+	if informer.HasSynced() {
+		return nil
+	}
+
+	startedAt := time.Now()
+	ctx_, cancel := context.WithTimeout(ctx, informerSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx_.Done(), informer.HasSynced) {
+		kubeInformerSyncFailures.WithLabelValues(resource).Inc()
+		return errors.Errorf("timed out waiting for the %s informer to sync", resource)
+	}
+	kubeInformerSyncDuration.WithLabelValues(resource).Observe(time.Since(startedAt).Seconds())
+	return nil
+}
+
+// AddEventHandler registers the handler on an informer got from this package,
+// the returned func removes it and must be called once the handler is no longer needed
+func (m *kubeInformerManager) AddEventHandler(informer cache.SharedIndexInformer, handler cache.ResourceEventHandler) (func(), error) {
+	m.mu.Lock()
+	entry, ok := m.informers[informer]
+	if !ok {
+		m.mu.Unlock()
+		return nil, errors.New("the informer is not managed or it is already stopped")
+	}
+	dispatcher := entry.informers[informer]
+	entry.refs++
+	m.updateGauges()
+	m.mu.Unlock()
+
+	id := dispatcher.add(informer, handler)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			dispatcher.remove(id)
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			entry.refs--
+			entry.lastUsedAt = time.Now()
+			if entry.refs == 0 && entry.retired {
+				m.stopEntry(entry)
+				return
+			}
+			m.updateGauges()
+		})
+	}, nil
+}
+
+// EvictIdle stops the factories which have no event handler and have not been used for a while
+func (m *kubeInformerManager) EvictIdle() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entry := range m.factories {
+		if entry.refs > 0 || time.Since(entry.lastUsedAt) < kubeInformerIdleTimeout {
+			continue
+		}
+		m.logger.Infof("stop the idle informers of namespace %q of cluster %d", entry.key.namespace, entry.key.clusterId)
+		m.stopEntry(entry)
+	}
+}
+
+// Invalidate stops the factories of the cluster which have no event handler, the others are stopped once their last handler is removed
+func (m *kubeInformerManager) Invalidate(clusterId uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, entry := range m.factories {
+		if key.clusterId != clusterId {
+			continue
+		}
+		delete(m.factories, key)
+		entry.retired = true
+		if entry.refs == 0 {
+			m.stopEntry(entry)
+		}
+	}
+	m.updateGauges()
+}
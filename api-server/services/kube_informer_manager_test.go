@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+func newInformerTestPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "yatai",
+		},
+	}
+}
+
+func receiveInformerTestEvent(t *testing.T, events <-chan string) string {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return ""
+	}
+}
+
+func TestKubeInformerDispatcherSlowHandler(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &corev1.Pod{}, 0, cache.Indexers{})
+	err := informer.GetStore().Add(newInformerTestPod("existing"))
+	if err != nil {
+		t.Fatalf("add pod to store: %v", err)
+	}
+	dispatcher := &kubeInformerDispatcher{
+		handlers: make(map[uint64]*kubeInformerHandlerQueue),
+	}
+
+	unblock := make(chan struct{})
+	slowEvents := make(chan string, 10)
+	slowId := dispatcher.add(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			<-unblock
+			slowEvents <- obj.(*corev1.Pod).Name
+		},
+	})
+	fastEvents := make(chan string, 10)
+	fastId := dispatcher.add(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			fastEvents <- obj.(*corev1.Pod).Name
+		},
+	})
+
+	dispatched := make(chan struct{})
+	go func() {
+		dispatcher.OnAdd(newInformerTestPod("new-1"))
+		dispatcher.OnAdd(newInformerTestPod("new-2"))
+		close(dispatched)
+	}()
+	select {
+	case <-dispatched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dispatch is blocked by the slow handler")
+	}
+
+	// the other handlers get the replayed objects then the new ones while the slow handler is blocked
+	for _, expected := range []string{"existing", "new-1", "new-2"} {
+		if name := receiveInformerTestEvent(t, fastEvents); name != expected {
+			t.Errorf("expected the fast handler to get %s, got %s", expected, name)
+		}
+	}
+
+	close(unblock)
+	for _, expected := range []string{"existing", "new-1", "new-2"} {
+		if name := receiveInformerTestEvent(t, slowEvents); name != expected {
+			t.Errorf("expected the slow handler to get %s, got %s", expected, name)
+		}
+	}
+
+	dispatcher.remove(slowId)
+	dispatcher.remove(fastId)
+	dispatcher.OnAdd(newInformerTestPod("new-3"))
+	select {
+	case name := <-fastEvents:
+		t.Errorf("expected a removed handler to get no event, got %s", name)
+	case name := <-slowEvents:
+		t.Errorf("expected a removed handler to get no event, got %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestKubeInformerHandlerQueueOverflow(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &corev1.Pod{}, 0, cache.Indexers{})
+	for _, name := range []string{"existing-1", "existing-2"} {
+		err := informer.GetStore().Add(newInformerTestPod(name))
+		if err != nil {
+			t.Fatalf("add pod to store: %v", err)
+		}
+	}
+	dispatcher := &kubeInformerDispatcher{
+		handlers: make(map[uint64]*kubeInformerHandlerQueue),
+	}
+
+	blocked := make(chan struct{})
+	unblock := make(chan struct{})
+	events := make(chan string, 100)
+	id := dispatcher.add(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			name := obj.(*corev1.Pod).Name
+			if name == "block" {
+				close(blocked)
+				<-unblock
+			}
+			events <- name
+		},
+	})
+	defer dispatcher.remove(id)
+	q := dispatcher.handlers[id]
+	q.mu.Lock()
+	q.maxEvents = 5
+	q.mu.Unlock()
+	receiveInformerTestEvent(t, events)
+	receiveInformerTestEvent(t, events)
+
+	dispatcher.OnAdd(newInformerTestPod("block"))
+	<-blocked
+	for i := 0; i < 20; i++ {
+		dispatcher.OnAdd(newInformerTestPod(fmt.Sprintf("new-%d", i)))
+		q.mu.Lock()
+		queued := len(q.events)
+		q.mu.Unlock()
+		if queued > q.maxEvents {
+			t.Fatalf("expected at most %d queued events, got %d", q.maxEvents, queued)
+		}
+	}
+
+	// the backlog is replaced by a replay of the store followed by the events pushed after the overflow
+	close(unblock)
+	if name := receiveInformerTestEvent(t, events); name != "block" {
+		t.Fatalf("expected the handler to finish the blocked event, got %s", name)
+	}
+	replayed := map[string]bool{
+		receiveInformerTestEvent(t, events): true,
+		receiveInformerTestEvent(t, events): true,
+	}
+	if !replayed["existing-1"] || !replayed["existing-2"] {
+		t.Errorf("expected the store to be replayed in place of the backlog, got %v", replayed)
+	}
+	for _, expected := range []string{"new-17", "new-18", "new-19"} {
+		if name := receiveInformerTestEvent(t, events); name != expected {
+			t.Errorf("expected the handler to get %s, got %s", expected, name)
+		}
+	}
+	select {
+	case name := <-events:
+		t.Errorf("expected the dropped events not to be handled, got %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func newInformerTestManager() *kubeInformerManager {
+	return &kubeInformerManager{
+		factories: make(map[kubeInformerFactoryKey]*kubeInformerFactoryEntry),
+		informers: make(map[cache.SharedIndexInformer]*kubeInformerFactoryEntry),
+		logger:    logrus.New().WithField("manager", "kube informer"),
+	}
+}
+
+func newInformerTestCluster(kubeConfig string) *models.Cluster {
+	cluster := &models.Cluster{
+		KubeConfig: kubeConfig,
+	}
+	cluster.ID = 1
+	return cluster
+}
+
+// getInformerTestPodInformer gets the pod informer of the cluster from the manager like the services do,
+// the factory lists from a fake clientset holding the given pods
+func getInformerTestPodInformer(t *testing.T, m *kubeInformerManager, cluster *models.Cluster, pods ...runtime.Object) (*kubeInformerFactoryEntry, cache.SharedIndexInformer) {
+	key := kubeInformerFactoryKey{
+		clusterId: cluster.ID,
+		namespace: "yatai",
+	}
+	entry, err := m.getFactory(cluster, key, func() (kubeInformerFactory, error) {
+		return informers.NewSharedInformerFactoryWithOptions(kubefake.NewSimpleClientset(pods...), 0, informers.WithNamespace("yatai")), nil
+	})
+	if err != nil {
+		t.Fatalf("get factory: %v", err)
+	}
+	informer := entry.factory.(informers.SharedInformerFactory).Core().V1().Pods().Informer()
+	err = m.startAndSync(context.Background(), entry, "pods", informer)
+	if err != nil {
+		t.Fatalf("start and sync: %v", err)
+	}
+	return entry, informer
+}
+
+func addInformerTestHandler(t *testing.T, m *kubeInformerManager, informer cache.SharedIndexInformer) (func(), <-chan string) {
+	events := make(chan string, 10)
+	removeHandler, err := m.AddEventHandler(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events <- obj.(*corev1.Pod).Name
+		},
+	})
+	if err != nil {
+		t.Fatalf("add event handler: %v", err)
+	}
+	return removeHandler, events
+}
+
+func TestKubeInformerManagerRefcountAndEvictIdle(t *testing.T) {
+	m := newInformerTestManager()
+	defer m.Stop()
+	cluster := newInformerTestCluster("kube config")
+	entry, informer := getInformerTestPodInformer(t, m, cluster, newInformerTestPod("existing"))
+
+	removeHandler1, events := addInformerTestHandler(t, m, informer)
+	if name := receiveInformerTestEvent(t, events); name != "existing" {
+		t.Errorf("expected the synced pod to be replayed, got %s", name)
+	}
+	removeHandler2, _ := addInformerTestHandler(t, m, informer)
+	if entry.refs != 2 {
+		t.Fatalf("expected 2 refs, got %d", entry.refs)
+	}
+	removeHandler1()
+	removeHandler1()
+	if entry.refs != 1 {
+		t.Fatalf("expected the remove func to be idempotent and leave 1 ref, got %d", entry.refs)
+	}
+
+	// the factories with handlers are never evicted
+	entry.lastUsedAt = time.Now().Add(-2 * kubeInformerIdleTimeout)
+	m.EvictIdle()
+	if entry.ctx.Err() != nil {
+		t.Fatal("expected a factory with a handler not to be evicted")
+	}
+
+	removeHandler2()
+	if entry.refs != 0 {
+		t.Fatalf("expected no refs, got %d", entry.refs)
+	}
+	// the removal of the last handler counts as a use
+	m.EvictIdle()
+	if entry.ctx.Err() != nil {
+		t.Fatal("expected a factory used recently not to be evicted")
+	}
+
+	entry.lastUsedAt = time.Now().Add(-2 * kubeInformerIdleTimeout)
+	m.EvictIdle()
+	if entry.ctx.Err() == nil {
+		t.Fatal("expected the idle factory to be stopped")
+	}
+	if len(m.factories) != 0 || len(m.informers) != 0 {
+		t.Errorf("expected the idle factory to be evicted, got %d factories and %d informers", len(m.factories), len(m.informers))
+	}
+	if _, err := m.AddEventHandler(informer, cache.ResourceEventHandlerFuncs{}); err == nil {
+		t.Error("expected no handler to be added to an evicted informer")
+	}
+}
+
+func TestKubeInformerManagerRetire(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		retire func(m *kubeInformerManager, cluster *models.Cluster)
+	}{
+		{
+			name: "kube config change",
+			retire: func(m *kubeInformerManager, cluster *models.Cluster) {
+				cluster.KubeConfig += " changed"
+			},
+		},
+		{
+			name: "invalidate",
+			retire: func(m *kubeInformerManager, cluster *models.Cluster) {
+				m.Invalidate(cluster.ID)
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := newInformerTestManager()
+			defer m.Stop()
+			cluster := newInformerTestCluster("kube config")
+			entry, informer := getInformerTestPodInformer(t, m, cluster)
+			removeHandler, _ := addInformerTestHandler(t, m, informer)
+
+			c.retire(m, cluster)
+			newEntry, _ := getInformerTestPodInformer(t, m, cluster)
+			if newEntry == entry {
+				t.Fatal("expected a new factory to be built")
+			}
+			if !entry.retired || entry.ctx.Err() != nil {
+				t.Fatal("expected the old factory to be retired but kept running for its handler")
+			}
+
+			removeHandler()
+			if entry.ctx.Err() == nil {
+				t.Error("expected the retired factory to be stopped once its last handler is removed")
+			}
+			if _, ok := m.informers[informer]; ok {
+				t.Error("expected the informer of the retired factory to be forgotten")
+			}
+			if newEntry.ctx.Err() != nil || m.factories[newEntry.key] != newEntry {
+				t.Error("expected the new factory to keep running")
+			}
+
+			// a retired factory without handlers is stopped right away
+			c.retire(m, cluster)
+			getInformerTestPodInformer(t, m, cluster)
+			if newEntry.ctx.Err() == nil {
+				t.Error("expected the retired factory without handlers to be stopped")
+			}
+		})
+	}
+}
+
+func TestKubeInformerManagerStop(t *testing.T) {
+	m := newInformerTestManager()
+	cluster := newInformerTestCluster("kube config")
+	entry, informer := getInformerTestPodInformer(t, m, cluster)
+	removeHandler, events := addInformerTestHandler(t, m, informer)
+	dispatcher := entry.informers[informer]
+
+	m.Stop()
+	if entry.ctx.Err() == nil {
+		t.Fatal("expected the factory to be stopped")
+	}
+	// the handlers which are still registered get no more events
+	dispatcher.OnAdd(newInformerTestPod("new"))
+	select {
+	case name := <-events:
+		t.Errorf("expected a handler of a stopped manager to get no event, got %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+	removeHandler()
+
+	if _, err := m.getFactory(cluster, entry.key, nil); err == nil {
+		t.Error("expected no factory to be got from a stopped manager")
+	}
+}
@@ -143,6 +143,10 @@ func (s *labelService) Delete(ctx context.Context, label *models.Label) (*models
 	return label, s.getBaseDB(ctx).Unscoped().Delete(label).Error
 }
 
+func (s *labelService) DeleteByResource(ctx context.Context, resource models.IResource) error {
+	return s.getBaseDB(ctx).Where("resource_type = ?", resource.GetResourceType()).Where("resource_id = ?", resource.GetId()).Unscoped().Delete(&models.Label{}).Error
+}
+
 func (s *labelService) List(ctx context.Context, opt ListLabelOption) ([]*models.Label, uint, error) {
 	query := getBaseQuery(ctx, s)
 
@@ -16,11 +16,13 @@ import (
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	commonconsts "github.com/bentoml/yatai-common/consts"
 	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai/api-server/models"
 	"github.com/bentoml/yatai/common/consts"
+	"github.com/bentoml/yatai/common/utils"
 )
 
 type modelService struct{}
@@ -429,6 +431,97 @@ func (s *modelService) PreSignDownloadUrl(ctx context.Context, model *models.Mod
 	return
 }
 
+// checkDeletable must be called in the transaction of the deletion, it locks the model so that no bento can pack it meanwhile
+func (s *modelService) checkDeletable(ctx context.Context, model *models.Model) error {
+	err := mustGetSession(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", model.ID).First(&models.Model{}).Error
+	if err != nil {
+		return errors.Wrap(err, "lock model")
+	}
+	bentos, total, err := BentoService.List(ctx, ListBentoOption{
+		BaseListOption: BaseListOption{
+			Count: utils.UintPtr(1),
+		},
+		ModelIds: &[]uint{model.ID},
+	})
+	if err != nil {
+		return errors.Wrap(err, "list bentos")
+	}
+	if len(bentos) > 0 {
+		tag, err := BentoService.GetTag(ctx, bentos[0])
+		if err != nil {
+			return err
+		}
+		return errors.Errorf("model %s is packed into %d bentos (e.g. %s), delete them first", model.Version, total, tag)
+	}
+	return nil
+}
+
+// removeS3ObjectAfterCommit removes the object of the model once its deletion is committed, the object is kept when the deletion is rolled back
+func (s *modelService) removeS3ObjectAfterCommit(ctx context.Context, model *models.Model) error {
+	modelRepository, err := ModelRepositoryService.GetAssociatedModelRepository(ctx, model)
+	if err != nil {
+		return err
+	}
+	org, err := OrganizationService.GetAssociatedOrganization(ctx, modelRepository)
+	if err != nil {
+		return err
+	}
+	s3Config, err := OrganizationService.GetS3Config(ctx, org)
+	if err != nil {
+		return err
+	}
+	minioClient, err := s3Config.GetMinioClient()
+	if err != nil {
+		return errors.Wrap(err, "create s3 client")
+	}
+	bucketName, err := s.GetS3BucketName(ctx, model)
+	if err != nil {
+		return err
+	}
+	objectName, err := s.getS3ObjectName(ctx, model)
+	if err != nil {
+		return err
+	}
+	runAfterCommit(ctx, func() {
+		err := minioClient.RemoveObject(context.Background(), bucketName, objectName, minio.RemoveObjectOptions{})
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+			logrus.Errorf("remove object %s of the deleted model: %s", objectName, err.Error())
+		}
+	})
+	return nil
+}
+
+func (s *modelService) Delete(ctx context.Context, model *models.Model) (m *models.Model, err error) {
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	err = s.checkDeletable(ctx, model)
+	if err != nil {
+		return
+	}
+	err = db.Where("model_id = ?", model.ID).Unscoped().Delete(&models.BentoModelRel{}).Error
+	if err != nil {
+		return
+	}
+	err = LabelService.DeleteByResource(ctx, model)
+	if err != nil {
+		return
+	}
+	err = db.Unscoped().Delete(model).Error
+	if err != nil {
+		return
+	}
+	err = s.removeS3ObjectAfterCommit(ctx, model)
+	if err != nil {
+		return
+	}
+	m = model
+	return
+}
+
 func (s *modelService) getS3ObjectName(ctx context.Context, model *models.Model) (string, error) {
 	modelRepository, err := ModelRepositoryService.GetAssociatedModelRepository(ctx, model)
 	if err != nil {
@@ -544,6 +637,11 @@ func (s *modelService) Update(ctx context.Context, model *models.Model, opt Upda
 		}
 	}
 
+	err = NotificationService.Publish(ctx, modelschemas.ResourceTypeModel, model.Uid)
+	if err != nil {
+		return nil, err
+	}
+
 	if opt.Labels != nil {
 		modelRepository, err := ModelRepositoryService.GetAssociatedModelRepository(ctx, model)
 		if err != nil {
@@ -27,20 +27,22 @@ type CreateModelRepositoryOption struct {
 }
 
 type UpdateModelRepositoryOption struct {
-	Description *string
-	Labels      *modelschemas.LabelItemsSchema
+	Description     *string
+	Labels          *modelschemas.LabelItemsSchema
+	RetentionPolicy **models.RetentionPolicy
 }
 
 type ListModelRepositoryOption struct {
 	BaseListOption
 	BaseListByLabelsOption
-	OrganizationId *uint
-	CreatorId      *uint
-	CreatorIds     *[]uint
-	LastUpdaterIds *[]uint
-	Order          *string
-	Names          *[]string
-	Ids            *[]uint
+	OrganizationId     *uint
+	CreatorId          *uint
+	CreatorIds         *[]uint
+	LastUpdaterIds     *[]uint
+	Order              *string
+	Names              *[]string
+	Ids                *[]uint
+	HasRetentionPolicy *bool
 }
 
 func (*modelRepositoryService) Create(ctx context.Context, opt CreateModelRepositoryOption) (*models.ModelRepository, error) {
@@ -74,6 +76,14 @@ func (s *modelRepositoryService) Update(ctx context.Context, modelRepository *mo
 			}
 		}()
 	}
+	if opt.RetentionPolicy != nil {
+		updaters["retention_policy"] = *opt.RetentionPolicy
+		defer func() {
+			if err == nil {
+				modelRepository.RetentionPolicy = *opt.RetentionPolicy
+			}
+		}()
+	}
 	if len(updaters) == 0 {
 		return modelRepository, nil
 	}
@@ -150,6 +160,13 @@ func (s *modelRepositoryService) List(ctx context.Context, opt ListModelReposito
 	if opt.CreatorIds != nil {
 		query = query.Where("model_repository.creator_id in (?)", *opt.CreatorIds)
 	}
+	if opt.HasRetentionPolicy != nil {
+		if *opt.HasRetentionPolicy {
+			query = query.Where("model_repository.retention_policy IS NOT NULL")
+		} else {
+			query = query.Where("model_repository.retention_policy IS NULL")
+		}
+	}
 	query = query.Joins("LEFT JOIN model ON model.model_repository_id = model_repository.id")
 	query = query.Joins("LEFT OUTER JOIN model m2 ON m2.model_repository_id = model_repository.id AND model.id < m2.id")
 	query = query.Where("m2.id IS NULL")
@@ -178,6 +195,38 @@ func (s *modelRepositoryService) List(ctx context.Context, opt ListModelReposito
 	return modelRepositories, uint(total), nil
 }
 
+func (s *modelRepositoryService) Delete(ctx context.Context, modelRepository *models.ModelRepository) (r *models.ModelRepository, err error) {
+	models_, _, err := ModelService.List(ctx, ListModelOption{
+		ModelRepositoryId: &modelRepository.ID,
+	})
+	if err != nil {
+		return
+	}
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	for _, model := range models_ {
+		_, err = ModelService.Delete(ctx, model)
+		if err != nil {
+			err = errors.Wrapf(err, "delete model %s", model.Version)
+			return
+		}
+	}
+	err = LabelService.DeleteByResource(ctx, modelRepository)
+	if err != nil {
+		return
+	}
+	err = db.Unscoped().Delete(modelRepository).Error
+	if err != nil {
+		return
+	}
+	r = modelRepository
+	return
+}
+
 type IModelRepositoryAssociate interface {
 	GetAssociatedModelRepositoryId() uint
 	GetAssociatedModelRepositoryCache() *models.ModelRepository
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/config"
+)
+
+const (
+	NotificationBackendMemory     = "memory"
+	NotificationBackendPostgresql = "postgresql"
+
+	resourceChangeChannel = "yatai_resource_change"
+)
+
+type ResourceChange struct {
+	ResourceType modelschemas.ResourceType `json:"resource_type"`
+	ResourceUid  string                    `json:"resource_uid"`
+}
+
+// ResourceChangeSubscription coalesces the resource changes until they are popped, so a slow subscriber never blocks the publishers
+type ResourceChangeSubscription struct {
+	mu      sync.Mutex
+	changes map[ResourceChange]struct{}
+	resync  bool
+	notify  chan struct{}
+}
+
+// Notify is signaled when there are changes to pop
+func (s *ResourceChangeSubscription) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Pop returns the pending changes, resync is true when changes may have been lost and the subscriber should reload everything
+func (s *ResourceChangeSubscription) Pop() (changes []ResourceChange, resync bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes = make([]ResourceChange, 0, len(s.changes))
+	for change := range s.changes {
+		changes = append(changes, change)
+	}
+	s.changes = make(map[ResourceChange]struct{})
+	resync = s.resync
+	s.resync = false
+	return
+}
+
+func (s *ResourceChangeSubscription) push(change *ResourceChange) {
+	s.mu.Lock()
+	if change == nil {
+		s.resync = true
+	} else {
+		s.changes[*change] = struct{}{}
+	}
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+type notificationService struct {
+	mu            sync.RWMutex
+	subscriptions map[*ResourceChangeSubscription]struct{}
+}
+
+var NotificationService = notificationService{
+	subscriptions: make(map[*ResourceChangeSubscription]struct{}),
+}
+
+func (s *notificationService) usePostgresql() bool {
+	return config.YataiConfig.Server.NotificationBackend == NotificationBackendPostgresql
+}
+
+func (s *notificationService) Subscribe() *ResourceChangeSubscription {
+	subscription := &ResourceChangeSubscription{
+		changes: make(map[ResourceChange]struct{}),
+		notify:  make(chan struct{}, 1),
+	}
+	s.mu.Lock()
+	s.subscriptions[subscription] = struct{}{}
+	s.mu.Unlock()
+	return subscription
+}
+
+func (s *notificationService) Unsubscribe(subscription *ResourceChangeSubscription) {
+	s.mu.Lock()
+	delete(s.subscriptions, subscription)
+	s.mu.Unlock()
+}
+
+// dispatch delivers the change to the subscriptions of this replica, a nil change asks them to resync
+func (s *notificationService) dispatch(change *ResourceChange) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for subscription := range s.subscriptions {
+		subscription.push(change)
+	}
+}
+
+// Publish notifies the subscribers that the resource has changed once the transaction of the ctx is committed
+func (s *notificationService) Publish(ctx context.Context, resourceType modelschemas.ResourceType, resourceUid string) error {
+	change := ResourceChange{
+		ResourceType: resourceType,
+		ResourceUid:  resourceUid,
+	}
+	if !s.usePostgresql() {
+		runAfterCommit(ctx, func() {
+			s.dispatch(&change)
+		})
+		return nil
+	}
+	payload, err := json.Marshal(&change)
+	if err != nil {
+		return errors.Wrap(err, "marshal resource change")
+	}
+	// postgresql holds the notifications of a transaction until it is committed
+	err = mustGetSession(ctx).Exec("SELECT pg_notify(?, ?)", resourceChangeChannel, string(payload)).Error
+	return errors.Wrap(err, "pg_notify resource change")
+}
+
+// Listen relays the resource changes notified by all the replicas to the subscriptions of this replica, it does nothing with the memory backend
+func (s *notificationService) Listen(ctx context.Context) error {
+	switch config.YataiConfig.Server.NotificationBackend {
+	case "", NotificationBackendMemory:
+		return nil
+	case NotificationBackendPostgresql:
+	default:
+		return errors.Errorf("unknown notification backend %q, it should be %s or %s", config.YataiConfig.Server.NotificationBackend, NotificationBackendMemory, NotificationBackendPostgresql)
+	}
+	uri, err := getDBURI()
+	if err != nil {
+		return errors.Wrap(err, "get db uri")
+	}
+	logger := logrus.WithField("notification", resourceChangeChannel)
+	listener := pq.NewListener(uri, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Errorf("listener event %d: %s", event, err.Error())
+		}
+	})
+	err = listener.Listen(resourceChangeChannel)
+	if err != nil {
+		_ = listener.Close()
+		return errors.Wrapf(err, "listen to %s", resourceChangeChannel)
+	}
+	go func() {
+		defer listener.Close()
+		ticker := time.NewTicker(90 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-listener.Notify:
+				// a nil notification means the connection was re-established and notifications may have been lost
+				if notification == nil {
+					s.dispatch(nil)
+					continue
+				}
+				var change ResourceChange
+				if err := json.Unmarshal([]byte(notification.Extra), &change); err != nil {
+					logger.Errorf("unmarshal resource change %q: %s", notification.Extra, err.Error())
+					continue
+				}
+				s.dispatch(&change)
+			case <-ticker.C:
+				go func() {
+					if err := listener.Ping(); err != nil {
+						logger.Errorf("ping: %s", err.Error())
+					}
+				}()
+			}
+		}
+	}()
+	return nil
+}
@@ -50,12 +50,54 @@ type ListOrganizationOption struct {
 	Order     *string
 }
 
+// mapOrganizationConfigSecrets returns a copy of the config with the credentials mapped by f, the config is not modified
+func mapOrganizationConfigSecrets(orgConfig *modelschemas.OrganizationConfigSchema, f func(string) (string, error)) (*modelschemas.OrganizationConfigSchema, error) {
+	if orgConfig == nil {
+		return nil, nil
+	}
+	var err error
+	orgConfig_ := *orgConfig
+	if orgConfig.S3 != nil {
+		s3 := *orgConfig.S3
+		if s3.AccessKey, err = f(s3.AccessKey); err != nil {
+			return nil, errors.Wrap(err, "s3 access key")
+		}
+		if s3.SecretKey, err = f(s3.SecretKey); err != nil {
+			return nil, errors.Wrap(err, "s3 secret key")
+		}
+		orgConfig_.S3 = &s3
+	}
+	if orgConfig.AWS != nil {
+		aws := *orgConfig.AWS
+		if aws.AccessKeyId, err = f(aws.AccessKeyId); err != nil {
+			return nil, errors.Wrap(err, "aws access key id")
+		}
+		if aws.SecretAccessKey, err = f(aws.SecretAccessKey); err != nil {
+			return nil, errors.Wrap(err, "aws secret access key")
+		}
+		orgConfig_.AWS = &aws
+	}
+	if orgConfig.DockerRegistry != nil {
+		dockerRegistry := *orgConfig.DockerRegistry
+		if dockerRegistry.Password, err = f(dockerRegistry.Password); err != nil {
+			return nil, errors.Wrap(err, "docker registry password")
+		}
+		orgConfig_.DockerRegistry = &dockerRegistry
+	}
+	return &orgConfig_, nil
+}
+
 func (s *organizationService) Create(ctx context.Context, opt CreateOrganizationOption) (*models.Organization, error) {
 	errs := validation.IsDNS1035Label(opt.Name)
 	if len(errs) > 0 {
 		return nil, errors.New(strings.Join(errs, ";"))
 	}
 
+	orgConfig, err := mapOrganizationConfigSecrets(opt.Config, SecretService.Encrypt)
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt organization config")
+	}
+
 	org := models.Organization{
 		ResourceMixin: models.ResourceMixin{
 			Name: opt.Name,
@@ -64,9 +106,9 @@ func (s *organizationService) Create(ctx context.Context, opt CreateOrganization
 			CreatorId: opt.CreatorId,
 		},
 		Description: opt.Description,
-		Config:      opt.Config,
+		Config:      orgConfig,
 	}
-	err := mustGetSession(ctx).Create(&org).Error
+	err = mustGetSession(ctx).Create(&org).Error
 	if err != nil {
 		return nil, err
 	}
@@ -85,10 +127,15 @@ func (s *organizationService) Update(ctx context.Context, o *models.Organization
 		}()
 	}
 	if opt.Config != nil {
-		updaters["config"] = *opt.Config
+		var orgConfig *modelschemas.OrganizationConfigSchema
+		orgConfig, err = mapOrganizationConfigSecrets(*opt.Config, SecretService.Encrypt)
+		if err != nil {
+			return nil, errors.Wrap(err, "encrypt organization config")
+		}
+		updaters["config"] = orgConfig
 		defer func() {
 			if err == nil {
-				o.Config = *opt.Config
+				o.Config = orgConfig
 			}
 		}()
 	}
@@ -190,6 +237,11 @@ func (s *organizationService) List(ctx context.Context, opt ListOrganizationOpti
 			if err != nil {
 				return nil, 0, errors.Wrap(err, "list organization ids")
 			}
+			userGroupOrgIds, err := UserGroupMemberService.ListResourceIds(ctx, *opt.VisitorId, modelschemas.ResourceTypeOrganization)
+			if err != nil {
+				return nil, 0, err
+			}
+			orgIds = append(orgIds, userGroupOrgIds...)
 			// postgresql `in` clause cannot be empty, so push 0 to avoid it empty
 			orgIds = append(orgIds, 0)
 			query = query.Where("(creator_id = ? or id in (?))", *opt.VisitorId, orgIds)
@@ -415,9 +467,39 @@ func (s *organizationService) GetTransmissionStrategy(org *models.Organization)
 	return
 }
 
+// GetConfig returns a copy of the config of the organization with the credentials decrypted
+func (s *organizationService) GetConfig(org *models.Organization) (*modelschemas.OrganizationConfigSchema, error) {
+	orgConfig, err := mapOrganizationConfigSecrets(org.Config, SecretService.Decrypt)
+	return orgConfig, errors.Wrapf(err, "decrypt the config of organization %s", org.Name)
+}
+
+// Reencrypt encrypts the credentials of the organization with the primary key, it returns false when they already are
+func (s *organizationService) Reencrypt(ctx context.Context, org *models.Organization) (bool, error) {
+	needsReencryption := false
+	_, _ = mapOrganizationConfigSecrets(org.Config, func(value string) (string, error) {
+		needsReencryption = needsReencryption || SecretService.NeedsReencryption(value)
+		return value, nil
+	})
+	if !needsReencryption {
+		return false, nil
+	}
+	orgConfig, err := s.GetConfig(org)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.Update(ctx, org, UpdateOrganizationOption{
+		Config: &orgConfig,
+	})
+	return err == nil, err
+}
+
 func (s *organizationService) GetS3Config(ctx context.Context, org *models.Organization) (conf *S3Config, err error) {
-	if org.Config != nil && org.Config.S3 != nil && org.Config.S3.Endpoint != "" {
-		s3Config := org.Config.S3
+	orgConfig, err := s.GetConfig(org)
+	if err != nil {
+		return nil, err
+	}
+	if orgConfig != nil && orgConfig.S3 != nil && orgConfig.S3.Endpoint != "" {
+		s3Config := orgConfig.S3
 		endpoint := s3Config.Endpoint
 		scheme := "http"
 		if s3Config.Secure {
@@ -445,15 +527,15 @@ func (s *organizationService) GetS3Config(ctx context.Context, org *models.Organ
 		}
 		return
 	}
-	if org.Config != nil && org.Config.AWS != nil && org.Config.AWS.S3 != nil {
-		awsS3Conf := org.Config.AWS.S3
+	if orgConfig != nil && orgConfig.AWS != nil && orgConfig.AWS.S3 != nil {
+		awsS3Conf := orgConfig.AWS.S3
 		conf = &S3Config{
 			Endpoint:                    consts.AmazonS3Endpoint,
 			EndpointInCluster:           consts.AmazonS3Endpoint,
 			EndpointWithScheme:          fmt.Sprintf("https://%s", consts.AmazonS3Endpoint),
 			EndpointWithSchemeInCluster: fmt.Sprintf("https://%s", consts.AmazonS3Endpoint),
-			AccessKey:                   org.Config.AWS.AccessKeyId,
-			SecretKey:                   org.Config.AWS.SecretAccessKey,
+			AccessKey:                   orgConfig.AWS.AccessKeyId,
+			SecretKey:                   orgConfig.AWS.SecretAccessKey,
 			Secure:                      true,
 			Region:                      awsS3Conf.Region,
 			BentosBucketName:            awsS3Conf.BentosBucketName,
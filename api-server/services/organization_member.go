@@ -19,21 +19,24 @@ func (s *organizationMemberService) getBaseDB(ctx context.Context) *gorm.DB {
 }
 
 type CreateOrganizationMemberOption struct {
-	CreatorId      uint
-	UserId         uint
-	OrganizationId uint
-	Role           modelschemas.MemberRole
+	CreatorId        uint
+	UserId           uint
+	OrganizationId   uint
+	Role             modelschemas.MemberRole
+	IdentityProvider *string
 }
 
 type UpdateOrganizationMemberOption struct {
-	Role modelschemas.MemberRole
+	Role             modelschemas.MemberRole
+	IdentityProvider **string
 }
 
 type ListOrganizationMemberOption struct {
-	UserId         *uint
-	OrganizationId *uint
-	Roles          *[]modelschemas.MemberRole
-	Order          *string
+	UserId           *uint
+	OrganizationId   *uint
+	Roles            *[]modelschemas.MemberRole
+	Order            *string
+	IdentityProvider *string
 }
 
 func (s *organizationMemberService) Create(ctx context.Context, operatorId uint, opt CreateOrganizationMemberOption) (*models.OrganizationMember, error) {
@@ -43,7 +46,8 @@ func (s *organizationMemberService) Create(ctx context.Context, operatorId uint,
 	}
 
 	if err == nil {
-		return s.Update(ctx, oldMember, operatorId, UpdateOrganizationMemberOption{Role: opt.Role})
+		// an explicitly created membership takes over the one granted by the role mappings
+		return s.Update(ctx, oldMember, operatorId, UpdateOrganizationMemberOption{Role: opt.Role, IdentityProvider: &opt.IdentityProvider})
 	}
 
 	// nolint: ineffassign,staticcheck
@@ -62,7 +66,8 @@ func (s *organizationMemberService) Create(ctx context.Context, operatorId uint,
 		OrganizationAssociate: models.OrganizationAssociate{
 			OrganizationId: opt.OrganizationId,
 		},
-		Role: opt.Role,
+		Role:             opt.Role,
+		IdentityProvider: opt.IdentityProvider,
 	}
 	err = db.Create(member).Error
 	if err != nil {
@@ -86,6 +91,8 @@ func (s *organizationMemberService) GetBy(ctx context.Context, userId, organizat
 func (s *organizationMemberService) List(ctx context.Context, opt ListOrganizationMemberOption) ([]*models.OrganizationMember, error) {
 	members := make([]*models.OrganizationMember, 0)
 	query := getBaseQuery(ctx, s)
+	// the rows of the user groups are listed by the UserGroupMemberService
+	query = query.Where("user_group_id IS NULL")
 	if opt.OrganizationId != nil {
 		query = query.Where("organization_id = ?", *opt.OrganizationId)
 	}
@@ -95,6 +102,9 @@ func (s *organizationMemberService) List(ctx context.Context, opt ListOrganizati
 	if opt.Roles != nil {
 		query = query.Where("role in (?)", *opt.Roles)
 	}
+	if opt.IdentityProvider != nil {
+		query = query.Where("identity_provider = ?", *opt.IdentityProvider)
+	}
 	if opt.Order != nil {
 		query = query.Order(*opt.Order)
 	} else {
@@ -123,7 +133,7 @@ func (s *organizationMemberService) GetOrganization(ctx context.Context, resourc
 
 func (s *organizationMemberService) CheckRoles(ctx context.Context, userId, resourceId uint, roles []modelschemas.MemberRole) (bool, error) {
 	q := s.getBaseDB(ctx).
-		Where("user_id = ?", userId).
+		Where("(user_id = ? OR user_group_id in (?))", userId, UserGroupMemberService.userGroupIdsOfUser(ctx, userId)).
 		Where("organization_id = ?", resourceId).
 		Where("role in (?)", roles)
 	var total int64
@@ -132,11 +142,18 @@ func (s *organizationMemberService) CheckRoles(ctx context.Context, userId, reso
 }
 
 func (s *organizationMemberService) Update(ctx context.Context, m *models.OrganizationMember, operatorId uint, opt UpdateOrganizationMemberOption) (*models.OrganizationMember, error) {
-	err := s.getBaseDB(ctx).Where("id = ?", m.ID).Updates(map[string]interface{}{
+	updaters := map[string]interface{}{
 		"role": opt.Role,
-	}).Error
+	}
+	if opt.IdentityProvider != nil {
+		updaters["identity_provider"] = *opt.IdentityProvider
+	}
+	err := s.getBaseDB(ctx).Where("id = ?", m.ID).Updates(updaters).Error
 	if err == nil {
 		m.Role = opt.Role
+		if opt.IdentityProvider != nil {
+			m.IdentityProvider = *opt.IdentityProvider
+		}
 	}
 	return m, err
 }
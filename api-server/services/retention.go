@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+const (
+	RetentionKeepLabelKey   = "keep"
+	RetentionKeepLabelValue = "true"
+
+	retentionEventOperationName = "garbage collected"
+)
+
+type retentionService struct{}
+
+var RetentionService = retentionService{}
+
+// isRetained reports whether the version at the given position (newest first) is kept by the policy rules
+func (s *retentionService) isRetained(policy *models.RetentionPolicy, idx int, createdAt time.Time, now time.Time) bool {
+	if policy.IsEmpty() {
+		return true
+	}
+	if policy.KeepLastN != nil && uint(idx) < *policy.KeepLastN {
+		return true
+	}
+	if policy.KeepWithinDays != nil && createdAt.After(now.AddDate(0, 0, -int(*policy.KeepWithinDays))) {
+		return true
+	}
+	return false
+}
+
+func (s *retentionService) listKeptResourceIds(ctx context.Context, resourceType modelschemas.ResourceType, resourceIds []uint) (map[uint]struct{}, error) {
+	res := make(map[uint]struct{})
+	if len(resourceIds) == 0 {
+		return res, nil
+	}
+	labels, _, err := LabelService.List(ctx, ListLabelOption{
+		ResourceType: &resourceType,
+		ResourceIds:  &resourceIds,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list labels")
+	}
+	for _, label := range labels {
+		if label.Key == RetentionKeepLabelKey && label.Value == RetentionKeepLabelValue {
+			res[label.ResourceId] = struct{}{}
+		}
+	}
+	return res, nil
+}
+
+func (s *retentionService) ListCollectableBentos(ctx context.Context, bentoRepository *models.BentoRepository) ([]*models.Bento, error) {
+	res := make([]*models.Bento, 0)
+	if bentoRepository.RetentionPolicy.IsEmpty() {
+		return res, nil
+	}
+	bentos, _, err := BentoService.List(ctx, ListBentoOption{
+		BentoRepositoryId: &bentoRepository.ID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list bentos")
+	}
+	bentoIds := make([]uint, 0, len(bentos))
+	for _, bento := range bentos {
+		bentoIds = append(bentoIds, bento.ID)
+	}
+	keptBentoIds, err := s.listKeptResourceIds(ctx, modelschemas.ResourceTypeBento, bentoIds)
+	if err != nil {
+		return nil, err
+	}
+	// the bentos of the inactive revisions are kept for their rollbacks
+	referencedBentoIds := make(map[uint]struct{})
+	if len(bentoIds) > 0 {
+		var ids []uint
+		err = mustGetSession(ctx).Model(&models.DeploymentTarget{}).Unscoped().Where("bento_id in (?)", bentoIds).Pluck("bento_id", &ids).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "list deployment target bento ids")
+		}
+		for _, id := range ids {
+			referencedBentoIds[id] = struct{}{}
+		}
+	}
+	now := time.Now()
+	for idx, bento := range bentos {
+		if s.isRetained(bentoRepository.RetentionPolicy, idx, bento.CreatedAt, now) {
+			continue
+		}
+		if _, ok := keptBentoIds[bento.ID]; ok {
+			continue
+		}
+		if _, ok := referencedBentoIds[bento.ID]; ok {
+			continue
+		}
+		if bento.UploadStatus == modelschemas.BentoUploadStatusUploading {
+			continue
+		}
+		res = append(res, bento)
+	}
+	return res, nil
+}
+
+func (s *retentionService) ListCollectableModels(ctx context.Context, modelRepository *models.ModelRepository) ([]*models.Model, error) {
+	res := make([]*models.Model, 0)
+	if modelRepository.RetentionPolicy.IsEmpty() {
+		return res, nil
+	}
+	models_, _, err := ModelService.List(ctx, ListModelOption{
+		ModelRepositoryId: &modelRepository.ID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list models")
+	}
+	modelIds := make([]uint, 0, len(models_))
+	for _, model := range models_ {
+		modelIds = append(modelIds, model.ID)
+	}
+	keptModelIds, err := s.listKeptResourceIds(ctx, modelschemas.ResourceTypeModel, modelIds)
+	if err != nil {
+		return nil, err
+	}
+	// a model packed into a bento is in use by that bento, it can only be collected after the bento is gone
+	packedModelIds := make(map[uint]struct{})
+	if len(modelIds) > 0 {
+		var ids []uint
+		err = mustGetSession(ctx).Model(&models.BentoModelRel{}).Where("model_id in (?)", modelIds).Pluck("model_id", &ids).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "list bento model relations")
+		}
+		for _, id := range ids {
+			packedModelIds[id] = struct{}{}
+		}
+	}
+	now := time.Now()
+	for idx, model := range models_ {
+		if s.isRetained(modelRepository.RetentionPolicy, idx, model.CreatedAt, now) {
+			continue
+		}
+		if _, ok := keptModelIds[model.ID]; ok {
+			continue
+		}
+		if _, ok := packedModelIds[model.ID]; ok {
+			continue
+		}
+		if model.UploadStatus == modelschemas.ModelUploadStatusUploading {
+			continue
+		}
+		res = append(res, model)
+	}
+	return res, nil
+}
+
+func (s *retentionService) CollectBentos(ctx context.Context, bentoRepository *models.BentoRepository) ([]*models.Bento, error) {
+	bentos, err := s.ListCollectableBentos(ctx, bentoRepository)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Bento, 0, len(bentos))
+	for _, bento := range bentos {
+		err = s.collectBento(ctx, bentoRepository, bento)
+		if err != nil {
+			return res, errors.Wrapf(err, "collect bento %s:%s", bentoRepository.Name, bento.Version)
+		}
+		res = append(res, bento)
+	}
+	return res, nil
+}
+
+func (s *retentionService) collectBento(ctx context.Context, bentoRepository *models.BentoRepository, bento *models.Bento) (err error) {
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	_, err = EventService.Create(ctx, CreateEventOption{
+		CreatorId:      bentoRepository.CreatorId,
+		OrganizationId: &bentoRepository.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeBento,
+		ResourceId:     bento.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  retentionEventOperationName,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "create event")
+		return
+	}
+	_, err = BentoService.Delete(ctx, bento)
+	return
+}
+
+func (s *retentionService) CollectModels(ctx context.Context, modelRepository *models.ModelRepository) ([]*models.Model, error) {
+	models_, err := s.ListCollectableModels(ctx, modelRepository)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Model, 0, len(models_))
+	for _, model := range models_ {
+		err = s.collectModel(ctx, modelRepository, model)
+		if err != nil {
+			return res, errors.Wrapf(err, "collect model %s:%s", modelRepository.Name, model.Version)
+		}
+		res = append(res, model)
+	}
+	return res, nil
+}
+
+func (s *retentionService) collectModel(ctx context.Context, modelRepository *models.ModelRepository, model *models.Model) (err error) {
+	// nolint: ineffassign,staticcheck
+	_, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	_, err = EventService.Create(ctx, CreateEventOption{
+		CreatorId:      modelRepository.CreatorId,
+		OrganizationId: &modelRepository.OrganizationId,
+		ResourceType:   modelschemas.ResourceTypeModel,
+		ResourceId:     model.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  retentionEventOperationName,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "create event")
+		return
+	}
+	_, err = ModelService.Delete(ctx, model)
+	return
+}
+
+// CollectAll applies the retention policies of all repositories, bentos first so that the models they release can be collected in the same round
+func (s *retentionService) CollectAll(ctx context.Context) error {
+	logger := logrus.WithField("cron", "retention")
+
+	bentoRepositories, _, err := BentoRepositoryService.List(ctx, ListBentoRepositoryOption{
+		HasRetentionPolicy: utils.BoolPtr(true),
+	})
+	if err != nil {
+		return errors.Wrap(err, "list bento repositories")
+	}
+	for _, bentoRepository := range bentoRepositories {
+		bentos, err := s.CollectBentos(ctx, bentoRepository)
+		if err != nil {
+			logger.Errorf("collect bento repository %s: %s", bentoRepository.Name, err.Error())
+		}
+		if len(bentos) > 0 {
+			logger.Infof("collected %d bentos from bento repository %s", len(bentos), bentoRepository.Name)
+		}
+	}
+
+	modelRepositories, _, err := ModelRepositoryService.List(ctx, ListModelRepositoryOption{
+		HasRetentionPolicy: utils.BoolPtr(true),
+	})
+	if err != nil {
+		return errors.Wrap(err, "list model repositories")
+	}
+	for _, modelRepository := range modelRepositories {
+		models_, err := s.CollectModels(ctx, modelRepository)
+		if err != nil {
+			logger.Errorf("collect model repository %s: %s", modelRepository.Name, err.Error())
+		}
+		if len(models_) > 0 {
+			logger.Infof("collected %d models from model repository %s", len(models_), modelRepository.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai/common/envelope"
+)
+
+// secretService encrypts the sensitive columns, the kube configs of the clusters and the credentials of the organizations
+type secretService struct {
+	mu       sync.RWMutex
+	envelope *envelope.Envelope
+}
+
+var SecretService = &secretService{}
+
+// SetKeyProvider enables the encryption, the secrets are written in plain text without a key provider
+func (s *secretService) SetKeyProvider(provider envelope.KeyProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.envelope = envelope.New(provider)
+}
+
+func (s *secretService) getEnvelope() *envelope.Envelope {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.envelope
+}
+
+// Encrypt encrypts the value with the primary key, the already encrypted values are returned as they are
+func (s *secretService) Encrypt(value string) (string, error) {
+	e := s.getEnvelope()
+	if e == nil || envelope.IsEncrypted(value) {
+		return value, nil
+	}
+	return e.Encrypt(value)
+}
+
+// Decrypt returns the plain text of the value, the values written before the encryption was enabled are already plain text
+func (s *secretService) Decrypt(value string) (string, error) {
+	if !envelope.IsEncrypted(value) {
+		return value, nil
+	}
+	e := s.getEnvelope()
+	if e == nil {
+		return "", errors.New("the value is encrypted but no encryption key is configured")
+	}
+	return e.Decrypt(value)
+}
+
+// NeedsReencryption tells whether the value is not encrypted with the primary key yet
+func (s *secretService) NeedsReencryption(value string) bool {
+	e := s.getEnvelope()
+	if e == nil {
+		return false
+	}
+	return e.NeedsReencryption(value)
+}
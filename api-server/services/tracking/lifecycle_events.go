@@ -5,8 +5,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/tianweidut/cron"
-
 	"github.com/bentoml/yatai-schemas/modelschemas"
 	"github.com/bentoml/yatai/api-server/services"
 	"github.com/bentoml/yatai/api-server/version"
@@ -32,7 +30,12 @@ func init() {
 	resetYataiUpTimestamp()
 }
 
-func AddLifeCycleTrackingCron(ctx context.Context, c *cron.Cron) {
+// ICronScheduler is satisfied by *cron.Cron
+type ICronScheduler interface {
+	AddFunc(spec string, cmd func()) error
+}
+
+func AddLifeCycleTrackingCron(ctx context.Context, c ICronScheduler) {
 	TrackLifeCycle(ctx, YataiLifeCycleStartup)
 
 	var cron_schedule string
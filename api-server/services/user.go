@@ -34,6 +34,8 @@ type CreateUserOption struct {
 	Email     *string
 	Password  string
 	Perm      *modelschemas.UserPerm
+
+	IsEmailVerified bool
 }
 
 type UpdateUserOption struct {
@@ -59,11 +61,12 @@ func (s *userService) Create(ctx context.Context, opt CreateUserOption) (*models
 		ResourceMixin: models.ResourceMixin{
 			Name: opt.Name,
 		},
-		FirstName: opt.FirstName,
-		LastName:  opt.LastName,
-		Email:     opt.Email,
-		Password:  string(hashedPassword),
-		Perm:      modelschemas.UserPermDefault,
+		FirstName:       opt.FirstName,
+		LastName:        opt.LastName,
+		Email:           opt.Email,
+		Password:        string(hashedPassword),
+		Perm:            modelschemas.UserPermDefault,
+		IsEmailVerified: opt.IsEmailVerified,
 	}
 	if opt.Perm != nil {
 		user.Perm = *opt.Perm
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+)
+
+type userGroupService struct{}
+
+var UserGroupService = userGroupService{}
+
+func (s *userGroupService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.UserGroup{})
+}
+
+type CreateUserGroupOption struct {
+	CreatorId      uint
+	OrganizationId uint
+	Name           string
+}
+
+type UpdateUserGroupOption struct {
+	Name *string
+}
+
+type ListUserGroupOption struct {
+	BaseListOption
+	OrganizationId *uint
+	Ids            *[]uint
+	Names          *[]string
+	UserId         *uint
+	Order          *string
+}
+
+func (s *userGroupService) Create(ctx context.Context, opt CreateUserGroupOption) (*models.UserGroup, error) {
+	errs := validation.IsDNS1035Label(opt.Name)
+	if len(errs) > 0 {
+		return nil, errors.New(errs[0])
+	}
+	userGroup := models.UserGroup{
+		ResourceMixin: models.ResourceMixin{
+			Name: opt.Name,
+		},
+		CreatorAssociate: models.CreatorAssociate{
+			CreatorId: opt.CreatorId,
+		},
+		OrganizationAssociate: models.OrganizationAssociate{
+			OrganizationId: opt.OrganizationId,
+		},
+	}
+	err := mustGetSession(ctx).Create(&userGroup).Error
+	if err != nil {
+		return nil, err
+	}
+	return &userGroup, nil
+}
+
+func (s *userGroupService) Update(ctx context.Context, userGroup *models.UserGroup, opt UpdateUserGroupOption) (*models.UserGroup, error) {
+	var err error
+	updaters := make(map[string]interface{})
+	if opt.Name != nil {
+		errs := validation.IsDNS1035Label(*opt.Name)
+		if len(errs) > 0 {
+			return nil, errors.New(errs[0])
+		}
+		updaters["name"] = *opt.Name
+		defer func() {
+			if err == nil {
+				userGroup.Name = *opt.Name
+			}
+		}()
+	}
+	if len(updaters) == 0 {
+		return userGroup, nil
+	}
+	err = s.getBaseDB(ctx).Where("id = ?", userGroup.ID).Updates(updaters).Error
+	if err != nil {
+		return nil, err
+	}
+	return userGroup, nil
+}
+
+func (s *userGroupService) Get(ctx context.Context, id uint) (*models.UserGroup, error) {
+	var userGroup models.UserGroup
+	err := s.getBaseDB(ctx).Where("id = ?", id).First(&userGroup).Error
+	if err != nil {
+		return nil, err
+	}
+	if userGroup.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &userGroup, nil
+}
+
+func (s *userGroupService) GetByUid(ctx context.Context, uid string) (*models.UserGroup, error) {
+	var userGroup models.UserGroup
+	err := s.getBaseDB(ctx).Where("uid = ?", uid).First(&userGroup).Error
+	if err != nil {
+		return nil, err
+	}
+	if userGroup.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &userGroup, nil
+}
+
+func (s *userGroupService) GetByName(ctx context.Context, organizationId uint, name string) (*models.UserGroup, error) {
+	var userGroup models.UserGroup
+	err := s.getBaseDB(ctx).Where("organization_id = ?", organizationId).Where("name = ?", name).First(&userGroup).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get user group %s", name)
+	}
+	return &userGroup, nil
+}
+
+func (s *userGroupService) List(ctx context.Context, opt ListUserGroupOption) ([]*models.UserGroup, uint, error) {
+	query := getBaseQuery(ctx, s)
+	if opt.OrganizationId != nil {
+		query = query.Where("user_group.organization_id = ?", *opt.OrganizationId)
+	}
+	if opt.Ids != nil {
+		if len(*opt.Ids) == 0 {
+			return []*models.UserGroup{}, 0, nil
+		}
+		query = query.Where("user_group.id in (?)", *opt.Ids)
+	}
+	if opt.Names != nil {
+		if len(*opt.Names) == 0 {
+			return []*models.UserGroup{}, 0, nil
+		}
+		query = query.Where("user_group.name in (?)", *opt.Names)
+	}
+	if opt.UserId != nil {
+		query = query.Where("user_group.id in (?)", mustGetSession(ctx).Model(&models.UserGroupUserRelation{}).Select("user_group_id").Where("user_id = ?", *opt.UserId))
+	}
+	query = opt.BindQueryWithKeywords(query, "user_group")
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	userGroups := make([]*models.UserGroup, 0)
+	query = opt.BindQueryWithLimit(query)
+	if opt.Order != nil {
+		query = query.Order(*opt.Order)
+	} else {
+		query = query.Order("user_group.id DESC")
+	}
+	err = query.Find(&userGroups).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return userGroups, uint(total), nil
+}
+
+// Delete removes the user group, its user relations and the roles granted to it are removed by the foreign key cascades
+func (s *userGroupService) Delete(ctx context.Context, userGroup *models.UserGroup) (*models.UserGroup, error) {
+	err := mustGetSession(ctx).Unscoped().Delete(userGroup).Error
+	if err != nil {
+		return nil, err
+	}
+	return userGroup, nil
+}
+
+func (s *userGroupService) AddUsers(ctx context.Context, userGroup *models.UserGroup, creatorId uint, userIds []uint) (err error) {
+	if len(userIds) == 0 {
+		return nil
+	}
+	// nolint: ineffassign,staticcheck
+	db, ctx, df, err := startTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { df(err) }()
+	var existingUserIds []uint
+	err = db.Model(&models.UserGroupUserRelation{}).Where("user_group_id = ?", userGroup.ID).Where("user_id in (?)", userIds).Pluck("user_id", &existingUserIds).Error
+	if err != nil {
+		return
+	}
+	existing := make(map[uint]struct{}, len(existingUserIds))
+	for _, userId := range existingUserIds {
+		existing[userId] = struct{}{}
+	}
+	for _, userId := range userIds {
+		if _, ok := existing[userId]; ok {
+			continue
+		}
+		existing[userId] = struct{}{}
+		err = db.Create(&models.UserGroupUserRelation{
+			UserGroupAssociate: models.UserGroupAssociate{
+				UserGroupId: userGroup.ID,
+			},
+			UserAssociate: models.UserAssociate{
+				UserId: userId,
+			},
+			CreatorAssociate: models.CreatorAssociate{
+				CreatorId: creatorId,
+			},
+		}).Error
+		if err != nil {
+			err = errors.Wrapf(err, "add user %d to user group %s", userId, userGroup.Name)
+			return
+		}
+	}
+	return
+}
+
+func (s *userGroupService) RemoveUsers(ctx context.Context, userGroup *models.UserGroup, userIds []uint) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	return mustGetSession(ctx).Unscoped().Where("user_group_id = ?", userGroup.ID).Where("user_id in (?)", userIds).Delete(&models.UserGroupUserRelation{}).Error
+}
+
+func (s *userGroupService) ListUsers(ctx context.Context, userGroup *models.UserGroup) ([]*models.User, error) {
+	users := make([]*models.User, 0)
+	err := mustGetSession(ctx).Where("id in (?)", mustGetSession(ctx).Model(&models.UserGroupUserRelation{}).Select("user_id").Where("user_group_id = ?", userGroup.ID)).Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+type IUserGroupAssociate interface {
+	GetAssociatedUserGroupId() uint
+	GetAssociatedUserGroupCache() *models.UserGroup
+	SetAssociatedUserGroupCache(userGroup *models.UserGroup)
+}
+
+func (s *userGroupService) GetAssociatedUserGroup(ctx context.Context, associate IUserGroupAssociate) (*models.UserGroup, error) {
+	cache := associate.GetAssociatedUserGroupCache()
+	if cache != nil {
+		return cache, nil
+	}
+	userGroup, err := s.Get(ctx, associate.GetAssociatedUserGroupId())
+	associate.SetAssociatedUserGroupCache(userGroup)
+	return userGroup, err
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/utils"
+)
+
+// userGroupMemberService grants the roles to the user groups in the member tables of the organizations, clusters and deployments,
+// so that the roles of the users and of the user groups are kept and checked in one place
+type userGroupMemberService struct{}
+
+var UserGroupMemberService = userGroupMemberService{}
+
+// getMemberModel returns the model of the member table of the resource type and its resource column
+func (s *userGroupMemberService) getMemberModel(resourceType modelschemas.ResourceType) (interface{}, string, error) {
+	switch resourceType {
+	case modelschemas.ResourceTypeOrganization:
+		return &models.OrganizationMember{}, "organization_id", nil
+	case modelschemas.ResourceTypeCluster:
+		return &models.ClusterMember{}, "cluster_id", nil
+	case modelschemas.ResourceTypeDeployment:
+		return &models.DeploymentMember{}, "deployment_id", nil
+	}
+	return nil, "", errors.Errorf("the roles on the %s resources can not be granted to user groups", resourceType)
+}
+
+// getMemberDB returns the query of the rows of the user groups in the member table of the resource type and its resource column
+func (s *userGroupMemberService) getMemberDB(ctx context.Context, resourceType modelschemas.ResourceType) (*gorm.DB, string, error) {
+	model, resourceColumn, err := s.getMemberModel(resourceType)
+	if err != nil {
+		return nil, "", err
+	}
+	return mustGetSession(ctx).Model(model).Where("user_id IS NULL").Where("user_group_id IS NOT NULL"), resourceColumn, nil
+}
+
+type CreateUserGroupMemberOption struct {
+	CreatorId    uint
+	UserGroupId  uint
+	ResourceType modelschemas.ResourceType
+	ResourceId   uint
+	Role         modelschemas.MemberRole
+}
+
+type ListUserGroupMemberOption struct {
+	UserGroupId  *uint
+	ResourceType modelschemas.ResourceType
+	ResourceId   *uint
+	Roles        *[]modelschemas.MemberRole
+}
+
+// Create grants the role on the resource to the user group, the role of an existing grant is replaced
+func (s *userGroupMemberService) Create(ctx context.Context, opt CreateUserGroupMemberOption) (*models.UserGroupMember, error) {
+	oldMember, err := s.GetBy(ctx, opt.UserGroupId, opt.ResourceType, opt.ResourceId)
+	if err != nil && !utils.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		query, _, err := s.getMemberDB(ctx, opt.ResourceType)
+		if err != nil {
+			return nil, err
+		}
+		err = query.Where("id = ?", oldMember.ID).Updates(map[string]interface{}{
+			"role": opt.Role,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		oldMember.Role = opt.Role
+		return oldMember, nil
+	}
+
+	creator := models.CreatorAssociate{
+		CreatorId: opt.CreatorId,
+	}
+	var member interface{}
+	switch opt.ResourceType {
+	case modelschemas.ResourceTypeOrganization:
+		member = &models.OrganizationMember{
+			CreatorAssociate: creator,
+			OrganizationAssociate: models.OrganizationAssociate{
+				OrganizationId: opt.ResourceId,
+			},
+			Role:        opt.Role,
+			UserGroupId: &opt.UserGroupId,
+		}
+	case modelschemas.ResourceTypeCluster:
+		member = &models.ClusterMember{
+			CreatorAssociate: creator,
+			ClusterAssociate: models.ClusterAssociate{
+				ClusterId: opt.ResourceId,
+			},
+			Role:        opt.Role,
+			UserGroupId: &opt.UserGroupId,
+		}
+	case modelschemas.ResourceTypeDeployment:
+		member = &models.DeploymentMember{
+			CreatorAssociate: creator,
+			DeploymentAssociate: models.DeploymentAssociate{
+				DeploymentId: opt.ResourceId,
+			},
+			Role:        opt.Role,
+			UserGroupId: &opt.UserGroupId,
+		}
+	default:
+		return nil, errors.Errorf("the roles on the %s resources can not be granted to user groups", opt.ResourceType)
+	}
+	// the user_id of the row of a user group is left empty
+	err = mustGetSession(ctx).Omit("user_id").Create(member).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBy(ctx, opt.UserGroupId, opt.ResourceType, opt.ResourceId)
+}
+
+func (s *userGroupMemberService) GetBy(ctx context.Context, userGroupId uint, resourceType modelschemas.ResourceType, resourceId uint) (*models.UserGroupMember, error) {
+	members, err := s.List(ctx, ListUserGroupMemberOption{
+		UserGroupId:  &userGroupId,
+		ResourceType: resourceType,
+		ResourceId:   &resourceId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return members[0], nil
+}
+
+func (s *userGroupMemberService) List(ctx context.Context, opt ListUserGroupMemberOption) ([]*models.UserGroupMember, error) {
+	query, resourceColumn, err := s.getMemberDB(ctx, opt.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	query = query.Select("*, " + resourceColumn + " AS resource_id")
+	if opt.UserGroupId != nil {
+		query = query.Where("user_group_id = ?", *opt.UserGroupId)
+	}
+	if opt.ResourceId != nil {
+		query = query.Where(resourceColumn+" = ?", *opt.ResourceId)
+	}
+	if opt.Roles != nil {
+		query = query.Where("role in (?)", *opt.Roles)
+	}
+	members := make([]*models.UserGroupMember, 0)
+	err = query.Order("id DESC").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		member.ResourceType = opt.ResourceType
+	}
+	return members, nil
+}
+
+func (s *userGroupMemberService) Delete(ctx context.Context, m *models.UserGroupMember) (*models.UserGroupMember, error) {
+	model, _, err := s.getMemberModel(m.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	err = mustGetSession(ctx).Unscoped().Where("id = ?", m.ID).Delete(model).Error
+	return m, err
+}
+
+// userGroupIdsOfUser is the sub query of the ids of the user groups of the user, the member services use it to honour the roles of the user groups
+func (s *userGroupMemberService) userGroupIdsOfUser(ctx context.Context, userId uint) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.UserGroupUserRelation{}).Select("user_group_id").Where("user_id = ?", userId)
+}
+
+// ListResourceIds returns the ids of the resources on which one of the user groups of the user is granted a role
+func (s *userGroupMemberService) ListResourceIds(ctx context.Context, userId uint, resourceType modelschemas.ResourceType) ([]uint, error) {
+	query, resourceColumn, err := s.getMemberDB(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]uint, 0)
+	err = query.
+		Where("user_group_id in (?)", s.userGroupIdsOfUser(ctx, userId)).
+		Distinct().
+		Pluck(resourceColumn, &res).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list resource ids granted to user groups")
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bentoml/yatai/api-server/models"
+	"github.com/bentoml/yatai/common/consts"
+)
+
+type userIdentityService struct{}
+
+var UserIdentityService = userIdentityService{}
+
+func (s *userIdentityService) getBaseDB(ctx context.Context) *gorm.DB {
+	return mustGetSession(ctx).Model(&models.UserIdentity{})
+}
+
+type CreateUserIdentityOption struct {
+	UserId   uint
+	Provider string
+	Subject  string
+}
+
+type UpdateUserIdentityOption struct {
+	LatestLoginAt **time.Time
+}
+
+func (s *userIdentityService) Create(ctx context.Context, opt CreateUserIdentityOption) (*models.UserIdentity, error) {
+	userIdentity := models.UserIdentity{
+		UserAssociate: models.UserAssociate{
+			UserId: opt.UserId,
+		},
+		Provider: opt.Provider,
+		Subject:  opt.Subject,
+	}
+	err := mustGetSession(ctx).Create(&userIdentity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &userIdentity, nil
+}
+
+func (s *userIdentityService) Update(ctx context.Context, userIdentity *models.UserIdentity, opt UpdateUserIdentityOption) (*models.UserIdentity, error) {
+	var err error
+	updaters := make(map[string]interface{})
+	if opt.LatestLoginAt != nil {
+		updaters["latest_login_at"] = *opt.LatestLoginAt
+		defer func() {
+			if err == nil {
+				userIdentity.LatestLoginAt = *opt.LatestLoginAt
+			}
+		}()
+	}
+
+	if len(updaters) == 0 {
+		return userIdentity, nil
+	}
+
+	err = s.getBaseDB(ctx).Where("id = ?", userIdentity.ID).Updates(updaters).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userIdentity, err
+}
+
+func (s *userIdentityService) GetBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var userIdentity models.UserIdentity
+	err := getBaseQuery(ctx, s).Where("provider = ?", provider).Where("subject = ?", subject).First(&userIdentity).Error
+	if err != nil {
+		return nil, err
+	}
+	if userIdentity.ID == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return &userIdentity, nil
+}
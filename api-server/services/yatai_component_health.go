@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bentoml/yatai-schemas/modelschemas"
+	"github.com/bentoml/yatai/api-server/config"
+	"github.com/bentoml/yatai/api-server/models"
+)
+
+// minYataiDeploymentVersions are the first yatai-deployment versions which serve the BentoDeployment CRD versions,
+// the component does not report the CRD versions it serves so they follow the yatai-deployment releases:
+// the v1.0.0 release introduced apis/serving/v1alpha3 and the v1.1.0 release introduced apis/serving/v2alpha1,
+// the v1alpha2 CRD is served by all the releases
+var minYataiDeploymentVersions = map[string]*version.Version{
+	"v1alpha3": version.Must(version.NewVersion("1.0.0")),
+	"v2alpha1": version.Must(version.NewVersion("1.1.0")),
+}
+
+// EvaluateHealth checks the latest heartbeat of the component against the heartbeat window,
+// and the version of the yatai-deployment component against the CRD version its deployments are deployed with
+func (s *yataiComponentService) EvaluateHealth(c *models.YataiComponent, now time.Time) *models.YataiComponentHealth {
+	health := &models.YataiComponentHealth{
+		Status:    models.YataiComponentHealthStatusHealthy,
+		Reasons:   make([]string, 0),
+		CheckedAt: now,
+	}
+
+	heartbeatWindow := config.YataiConfig.Server.ComponentHeartbeatWindow
+	if c.LatestHeartbeatAt == nil {
+		health.Stale = true
+		health.Reasons = append(health.Reasons, "no heartbeat has been received")
+	} else if since := now.Sub(*c.LatestHeartbeatAt); since > heartbeatWindow {
+		health.Stale = true
+		health.Reasons = append(health.Reasons, fmt.Sprintf("no heartbeat has been received for %s, the window is %s", since.Round(time.Second), heartbeatWindow))
+	}
+
+	if c.Name == string(modelschemas.YataiComponentNameDeployment) {
+		health.ExpectedCRDVersion = DeploymentTargetService.GetExpectedCRDVersion(c)
+		minVersion, ok := minYataiDeploymentVersions[health.ExpectedCRDVersion]
+		// the development builds have no semantic version, they are not flagged
+		componentVersion, err := version.NewVersion(c.Version)
+		if ok && err == nil && componentVersion.LessThan(minVersion) {
+			health.VersionSkew = true
+			health.Reasons = append(health.Reasons, fmt.Sprintf("version %s does not serve the %s BentoDeployment CRD which the deployments are deployed with, %s or later is required", c.Version, health.ExpectedCRDVersion, minVersion))
+		}
+	}
+
+	if health.Stale || health.VersionSkew {
+		health.Status = models.YataiComponentHealthStatusUnhealthy
+	}
+	return health
+}
+
+// updateHealth only updates the health if it is still the checked one, so that a transition is only recorded by one replica
+func (s *yataiComponentService) updateHealth(ctx context.Context, c *models.YataiComponent, health *models.YataiComponentHealth) (bool, error) {
+	var previous interface{}
+	if c.Health != nil {
+		value, err := c.Health.Value()
+		if err != nil {
+			return false, err
+		}
+		previous = string(value.([]byte))
+	}
+	db := s.getBaseDB(ctx).Where("id = ?", c.ID).Where("health IS NOT DISTINCT FROM ?::jsonb", previous).Updates(map[string]interface{}{
+		"health": health,
+	})
+	if db.Error != nil {
+		return false, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return false, nil
+	}
+	c.Health = health
+	return true, nil
+}
+
+func (s *yataiComponentService) isHealthTransition(previous, current *models.YataiComponentHealth) bool {
+	if previous == nil {
+		return current.Status == models.YataiComponentHealthStatusUnhealthy
+	}
+	return previous.Status != current.Status || previous.Stale != current.Stale || previous.VersionSkew != current.VersionSkew
+}
+
+// CheckHealth evaluates and saves the health of the component, the transitions are recorded as events
+func (s *yataiComponentService) CheckHealth(ctx context.Context, c *models.YataiComponent) error {
+	previous := c.Health
+	health := s.EvaluateHealth(c, time.Now())
+	updated, err := s.updateHealth(ctx, c, health)
+	if err != nil {
+		return errors.Wrap(err, "update health")
+	}
+	if !updated || !s.isHealthTransition(previous, health) {
+		return nil
+	}
+
+	createEventOpt := CreateEventOption{
+		CreatorId:      c.CreatorId,
+		OrganizationId: &c.OrganizationId,
+		ClusterId:      &c.ClusterId,
+		ResourceType:   modelschemas.ResourceTypeYataiComponent,
+		ResourceId:     c.ID,
+		Status:         modelschemas.EventStatusSuccess,
+		OperationName:  "recovered",
+	}
+	if health.Status == models.YataiComponentHealthStatusUnhealthy {
+		createEventOpt.Status = modelschemas.EventStatusFailed
+		createEventOpt.OperationName = "became unhealthy"
+		if health.Stale {
+			createEventOpt.Name = "stale heartbeat"
+		}
+		if health.VersionSkew {
+			createEventOpt.Name = "version skew"
+		}
+		if health.Stale && health.VersionSkew {
+			createEventOpt.Name = "stale heartbeat and version skew"
+		}
+	}
+	_, err = EventService.Create(ctx, createEventOpt)
+	return errors.Wrap(err, "create event")
+}
+
+// CheckHealthAll checks the health of the components of all the clusters,
+// a component which fails to be checked is logged so that it does not hold back the others
+func (s *yataiComponentService) CheckHealthAll(ctx context.Context) error {
+	yataiComponents, err := s.List(ctx, ListYataiComponentOption{})
+	if err != nil {
+		return errors.Wrap(err, "list yatai components")
+	}
+	for _, yataiComponent := range yataiComponents {
+		err = s.CheckHealth(ctx, yataiComponent)
+		if err != nil {
+			logrus.Errorf("check health of yatai component %s of cluster %d: %s", yataiComponent.Name, yataiComponent.ClusterId, err.Error())
+		}
+	}
+	return nil
+}
@@ -74,7 +74,12 @@ func ToClusterFullSchema(ctx context.Context, cluster *models.Cluster) (*schemas
 			return nil, err
 		}
 	} else {
-		kubeConfig = &cluster.KubeConfig
+		var kubeConfig_ string
+		kubeConfig_, err = services.ClusterService.GetKubeConfig(cluster)
+		if err != nil {
+			return nil, err
+		}
+		kubeConfig = &kubeConfig_
 		config = &cluster.Config
 	}
 	grafanaRootPath, err := services.ClusterService.GetGrafanaRootPath(ctx, cluster)
@@ -0,0 +1,59 @@
+package transformersv1
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/bentoml/yatai/api-server/models"
+	apischemasv1 "github.com/bentoml/yatai/api-server/schemas/schemasv1"
+	"github.com/bentoml/yatai/api-server/services"
+)
+
+func ToDeploymentRolloutSchema(ctx context.Context, rollout *models.DeploymentRollout) (*apischemasv1.DeploymentRolloutSchema, error) {
+	if rollout == nil {
+		return nil, nil
+	}
+	ss, err := ToDeploymentRolloutSchemas(ctx, []*models.DeploymentRollout{rollout})
+	if err != nil {
+		return nil, errors.Wrap(err, "ToDeploymentRolloutSchemas")
+	}
+	return ss[0], nil
+}
+
+func ToDeploymentRolloutSchemas(ctx context.Context, rollouts []*models.DeploymentRollout) ([]*apischemasv1.DeploymentRolloutSchema, error) {
+	res := make([]*apischemasv1.DeploymentRolloutSchema, 0, len(rollouts))
+	for _, rollout := range rollouts {
+		creator, err := services.UserService.GetAssociatedCreator(ctx, rollout)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment rollout associated creator")
+		}
+		creatorSchema, err := ToUserSchema(ctx, creator)
+		if err != nil {
+			return nil, errors.Wrap(err, "ToUserSchema")
+		}
+		deploymentRevision, err := services.DeploymentRevisionService.GetAssociatedDeploymentRevision(ctx, rollout)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment rollout associated deployment revision")
+		}
+		canaryDeploymentTarget, err := services.DeploymentTargetService.Get(ctx, rollout.CanaryDeploymentTargetId)
+		if err != nil {
+			return nil, errors.Wrap(err, "get deployment rollout canary deployment target")
+		}
+		res = append(res, &apischemasv1.DeploymentRolloutSchema{
+			BaseSchema:                ToBaseSchema(rollout),
+			ResourceType:              rollout.GetResourceType(),
+			Creator:                   creatorSchema,
+			DeploymentRevisionUid:     deploymentRevision.Uid,
+			CanaryDeploymentTargetUid: canaryDeploymentTarget.Uid,
+			Steps:                     rollout.Steps,
+			CurrentStep:               rollout.CurrentStep,
+			Status:                    rollout.Status,
+			Message:                   rollout.Message,
+			StepStartedAt:             rollout.StepStartedAt,
+			NextStepAt:                rollout.NextStepAt,
+			FinishedAt:                rollout.FinishedAt,
+		})
+	}
+	return res, nil
+}
@@ -41,7 +41,12 @@ func ToOrganizationFullSchema(ctx context.Context, org *models.Organization) (*s
 			return nil, err
 		}
 	} else {
-		config = &org.Config
+		var config_ *modelschemas.OrganizationConfigSchema
+		config_, err = services.OrganizationService.GetConfig(org)
+		if err != nil {
+			return nil, err
+		}
+		config = &config_
 	}
 	return &schemasv1.OrganizationFullSchema{
 		OrganizationSchema: *s,
@@ -39,5 +39,22 @@ const (
 
 	EnvReadHeaderTimeout = "READ_HEADER_TIMEOUT"
 
+	EnvMetricsPort = "METRICS_PORT"
+
 	EnvTransmissionStrategy = "TRANSMISSION_STRATEGY"
+
+	EnvApiTokenRotationGracePeriod = "API_TOKEN_ROTATION_GRACE_PERIOD"
+
+	EnvNotificationBackend = "NOTIFICATION_BACKEND"
+
+	EnvShutdownTimeout = "SHUTDOWN_TIMEOUT"
+
+	EnvPreStopDelay = "PRE_STOP_DELAY"
+
+	EnvComponentHeartbeatWindow = "COMPONENT_HEARTBEAT_WINDOW"
+
+	EnvEncryptionStaticKeyId = "ENCRYPTION_STATIC_KEY_ID"
+	// nolint:gosec
+	EnvEncryptionStaticKey   = "ENCRYPTION_STATIC_KEY"
+	EnvEncryptionKeyringFile = "ENCRYPTION_KEYRING_FILE"
 )
@@ -11,4 +11,6 @@ var (
 	ErrEmptyData     = errors.New("data is nil")
 	ErrNoImplemented = errors.New("no implemented")
 	ErrTimeout       = errors.New("timeout")
+	// ErrConflict is returned when a resource was changed by someone else in the meantime
+	ErrConflict = errors.New("conflict")
 )
@@ -0,0 +1,148 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// prefix marks the encrypted values, the values without it are plain text written before the encryption was enabled
+const prefix = "enc:v1:"
+
+// KeyProvider wraps the data keys with its key encryption keys, the key encryption keys never leave the provider
+type KeyProvider interface {
+	// PrimaryKeyId is the id of the key used to wrap the new data keys
+	PrimaryKeyId() string
+	WrapKey(keyId string, dataKey []byte) ([]byte, error)
+	UnwrapKey(keyId string, wrappedKey []byte) ([]byte, error)
+}
+
+// Envelope encrypts every value with its own data key and stores the data key wrapped by the key provider next to the ciphertext
+type Envelope struct {
+	provider KeyProvider
+}
+
+func New(provider KeyProvider) *Envelope {
+	return &Envelope{provider: provider}
+}
+
+// IsEncrypted tells whether the value was returned by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// GetKeyId returns the id of the key encryption key of the encrypted value
+func GetKeyId(value string) (string, error) {
+	keyId, _, _, err := split(value)
+	return keyId, err
+}
+
+func split(value string) (keyId string, wrappedKey, sealed []byte, err error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, errors.New("the value is not encrypted")
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	wrappedKey, err = base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "decode wrapped data key")
+	}
+	sealed, err = base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "decode ciphertext")
+	}
+	return parts[0], wrappedKey, sealed, nil
+}
+
+// seal encrypts the plaintext with AES-256-GCM, the nonce is prepended to the ciphertext
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "generate nonce")
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("the ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// Encrypt encrypts the value with the primary key, the empty values stay empty
+func (e *Envelope) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	keyId := e.provider.PrimaryKeyId()
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", errors.Wrap(err, "generate data key")
+	}
+	wrappedKey, err := e.provider.WrapKey(keyId, dataKey)
+	if err != nil {
+		return "", errors.Wrapf(err, "wrap data key with key %s", keyId)
+	}
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", errors.Wrap(err, "encrypt")
+	}
+	return prefix + keyId + ":" + base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts the value returned by Encrypt, the plain text values are returned as they are
+func (e *Envelope) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	keyId, wrappedKey, sealed, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := e.provider.UnwrapKey(keyId, wrappedKey)
+	if err != nil {
+		return "", errors.Wrapf(err, "unwrap data key with key %s", keyId)
+	}
+	plaintext, err := open(dataKey, sealed)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt")
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption tells whether the value is plain text or is encrypted with another key than the primary one
+func (e *Envelope) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	keyId, err := GetKeyId(value)
+	if err != nil {
+		return true
+	}
+	return keyId != e.provider.PrimaryKeyId()
+}
@@ -0,0 +1,135 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring, err := NewKeyring("key-1", map[string][]byte{"key-1": newTestKey(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(keyring)
+
+	encrypted, err := e.Encrypt("apiVersion: v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "apiVersion") {
+		t.Fatalf("the value is not encrypted: %s", encrypted)
+	}
+	decrypted, err := e.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != "apiVersion: v1" {
+		t.Fatalf("%q != %q", decrypted, "apiVersion: v1")
+	}
+
+	encrypted2, err := e.Encrypt("apiVersion: v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted2 == encrypted {
+		t.Fatal("the same value is encrypted twice to the same ciphertext")
+	}
+
+	if encrypted, err = e.Encrypt(""); err != nil || encrypted != "" {
+		t.Fatalf("the empty value is encrypted to %q: %v", encrypted, err)
+	}
+	if decrypted, err = e.Decrypt("plain"); err != nil || decrypted != "plain" {
+		t.Fatalf("the plain text value is decrypted to %q: %v", decrypted, err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	keyring, err := NewKeyring("key-1", map[string][]byte{"key-1": newTestKey(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(keyring)
+	encrypted, err := e.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := strings.LastIndex(encrypted, ":")
+	sealed, err := base64.RawStdEncoding.DecodeString(encrypted[idx+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := encrypted[:idx+1] + base64.RawStdEncoding.EncodeToString(sealed)
+	if _, err = e.Decrypt(tampered); err == nil {
+		t.Fatal("the tampered value is decrypted")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKeyring, err := NewKeyring("key-1", map[string][]byte{"key-1": newTestKey(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := New(oldKeyring).Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKeyring, err := NewKeyring("key-2", map[string][]byte{"key-1": newTestKey(1), "key-2": newTestKey(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(newKeyring)
+	if !e.NeedsReencryption(encrypted) || !e.NeedsReencryption("plain") || e.NeedsReencryption("") {
+		t.Fatal("wrong NeedsReencryption")
+	}
+	decrypted, err := e.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencrypted, err := e.Encrypt(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyId, err := GetKeyId(reencrypted); err != nil || keyId != "key-2" {
+		t.Fatalf("the value is re-encrypted with key %q: %v", keyId, err)
+	}
+	if e.NeedsReencryption(reencrypted) {
+		t.Fatal("the re-encrypted value needs a re-encryption")
+	}
+
+	withoutOldKey, err := NewKeyring("key-2", map[string][]byte{"key-2": newTestKey(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = New(withoutOldKey).Decrypt(encrypted); err == nil {
+		t.Fatal("the value is decrypted without its key")
+	}
+}
+
+func TestNewFileKeyProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.yaml")
+	content := "primary_key_id: key-2\nkeys:\n  key-1: " + base64.StdEncoding.EncodeToString(newTestKey(1)) + "\n  key-2: " + base64.StdEncoding.EncodeToString(newTestKey(2)) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyring.PrimaryKeyId() != "key-2" {
+		t.Fatalf("%s != key-2", keyring.PrimaryKeyId())
+	}
+
+	if _, err = NewStaticKeyProvider("key", base64.StdEncoding.EncodeToString([]byte("too short"))); err == nil {
+		t.Fatal("a key of 9 bytes is accepted")
+	}
+}
@@ -0,0 +1,106 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// Keyring is a KeyProvider holding the key encryption keys in memory,
+// keys are rotated by adding a new primary key and keeping the old ones until the values are re-encrypted
+type Keyring struct {
+	primaryKeyId string
+	keys         map[string][]byte
+}
+
+func NewKeyring(primaryKeyId string, keys map[string][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("the keyring has no keys")
+	}
+	for keyId, key := range keys {
+		if keyId == "" || strings.Contains(keyId, ":") {
+			return nil, errors.Errorf("invalid key id %q, it must be non empty and without colons", keyId)
+		}
+		if len(key) != 32 {
+			return nil, errors.Errorf("the key %s has %d bytes, AES-256 needs 32", keyId, len(key))
+		}
+	}
+	if _, ok := keys[primaryKeyId]; !ok {
+		return nil, errors.Errorf("the primary key %s is not in the keyring", primaryKeyId)
+	}
+	return &Keyring{
+		primaryKeyId: primaryKeyId,
+		keys:         keys,
+	}, nil
+}
+
+// NewStaticKeyProvider returns a keyring of the single base64 encoded key
+func NewStaticKeyProvider(keyId, base64Key string) (*Keyring, error) {
+	key, err := base64.StdEncoding.DecodeString(base64Key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode the base64 key %s", keyId)
+	}
+	return NewKeyring(keyId, map[string][]byte{keyId: key})
+}
+
+type keyringFile struct {
+	PrimaryKeyId string `json:"primary_key_id"`
+	// Keys maps the key ids to the base64 encoded keys
+	Keys map[string]string `json:"keys"`
+}
+
+// NewFileKeyProvider loads the keyring from a YAML or JSON file of the form
+//
+//	primary_key_id: key-2
+//	keys:
+//	  key-1: <base64 key>
+//	  key-2: <base64 key>
+func NewFileKeyProvider(path string) (*Keyring, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read keyring file %s", path)
+	}
+	var file keyringFile
+	if err = yaml.Unmarshal(content, &file); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal keyring file %s", path)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for keyId, base64Key := range file.Keys {
+		keys[keyId], err = base64.StdEncoding.DecodeString(base64Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode the base64 key %s", keyId)
+		}
+	}
+	return NewKeyring(file.PrimaryKeyId, keys)
+}
+
+func (k *Keyring) PrimaryKeyId() string {
+	return k.primaryKeyId
+}
+
+func (k *Keyring) getKey(keyId string) ([]byte, error) {
+	key, ok := k.keys[keyId]
+	if !ok {
+		return nil, errors.Errorf("the key %s is not in the keyring", keyId)
+	}
+	return key, nil
+}
+
+func (k *Keyring) WrapKey(keyId string, dataKey []byte) ([]byte, error) {
+	key, err := k.getKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+	return seal(key, dataKey)
+}
+
+func (k *Keyring) UnwrapKey(keyId string, wrappedKey []byte) ([]byte, error) {
+	key, err := k.getKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+	return open(key, wrappedKey)
+}
@@ -0,0 +1,225 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+)
+
+// supportedSigningAlgs are the asymmetric algorithms, "none" and the symmetric algorithms are rejected on purpose
+var supportedSigningAlgs = []string{
+	gooidc.RS256, gooidc.RS384, gooidc.RS512,
+	gooidc.ES256, gooidc.ES384, gooidc.ES512,
+	gooidc.PS256, gooidc.PS384, gooidc.PS512,
+}
+
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to an OpenID Connect identity provider with the authorization code flow
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+	discovery  discoveryDocument
+	verifier   *gooidc.IDTokenVerifier
+	now        func() time.Time
+}
+
+// NewProvider fetches the discovery document of the issuer, a nil httpClient means http.DefaultClient
+func NewProvider(ctx context.Context, config Config, httpClient *http.Client) (*Provider, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	var discovery discoveryDocument
+	if err := getJSON(ctx, httpClient, discoveryURL, &discovery); err != nil {
+		return nil, errors.Wrap(err, "get openid configuration")
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		return nil, errors.Errorf("the issuer %s in the openid configuration does not match the configured issuer %s", discovery.Issuer, config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, errors.New("the openid configuration lacks authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	p := &Provider{
+		config:     config,
+		httpClient: httpClient,
+		discovery:  discovery,
+		now:        time.Now,
+	}
+	// the key set outlives the ctx of the discovery, it only keeps the http client from it
+	keySet := gooidc.NewRemoteKeySet(gooidc.ClientContext(context.Background(), httpClient), discovery.JwksURI)
+	p.verifier = gooidc.NewVerifier(discovery.Issuer, keySet, &gooidc.Config{
+		ClientID:             config.ClientId,
+		SupportedSigningAlgs: supportedSigningAlgs,
+		Now: func() time.Time {
+			return p.now()
+		},
+	})
+	return p, nil
+}
+
+func (p *Provider) AuthCodeURL(state, nonce string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientId)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + query.Encode()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the authorization code for tokens and returns the verified ID token claims
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (*Claims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientId), url.QueryEscape(p.config.ClientSecret))
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request token endpoint")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(err, "read token response")
+	}
+	var token tokenResponse
+	if err = json.Unmarshal(body, &token); err != nil {
+		return nil, errors.Wrapf(err, "decode token response with status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, errors.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IdToken == "" {
+		return nil, errors.New("token response does not contain an id_token")
+	}
+	return p.VerifyIdToken(ctx, token.IdToken, nonce)
+}
+
+// VerifyIdToken checks the signature against the JWKS of the issuer, the algorithm, issuer, audience, expiry and not before with go-oidc,
+// then the nonce and the subject
+func (p *Provider) VerifyIdToken(ctx context.Context, rawIdToken, nonce string) (*Claims, error) {
+	idToken, err := p.verifier.Verify(gooidc.ClientContext(ctx, p.httpClient), rawIdToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "verify id token")
+	}
+	var claims Claims
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, errors.Wrap(err, "decode id token claims")
+	}
+	if err = idToken.Claims(&claims.Raw); err != nil {
+		return nil, errors.Wrap(err, "decode id token claims")
+	}
+	claims.Audience = idToken.Audience
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id token nonce does not match")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return &claims, nil
+}
+
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Audience          []string `json:"-"`
+
+	// Raw keeps every claim so that callers can read provider specific claims such as groups
+	Raw map[string]interface{} `json:"-"`
+}
+
+// StringsClaim reads a claim holding a string or a list of strings, e.g. groups
+func (c *Claims) StringsClaim(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// StringClaim reads a claim holding a string
+func (c *Claims) StringClaim(name string) string {
+	v, _ := c.Raw[name].(string)
+	return v
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, url_ string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url_, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", url_, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	claims  map[string]interface{}
+	code    string
+	idToken string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key, code: "the-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientId, clientSecret, _ := r.BasicAuth()
+		if r.FormValue("code") != idp.code || clientId != "yatai" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken := idp.idToken
+		if idToken == "" {
+			idToken = idp.sign(t, idp.claims)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	idp.claims = map[string]interface{}{
+		"iss":                idp.server.URL,
+		"sub":                "user-1",
+		"aud":                "yatai",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              "the-nonce",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"groups":             []string{"ml", "ops"},
+	}
+	return idp
+}
+
+func (idp *stubIdP) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "stub", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (idp *stubIdP) provider(t *testing.T) *Provider {
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:       idp.server.URL,
+		ClientId:     "yatai",
+		ClientSecret: "secret",
+		RedirectURL:  "http://yatai.local/callback",
+	}, idp.server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newStubIdP(t)
+	defer idp.server.Close()
+
+	u, err := url.Parse(idp.provider(t).AuthCodeURL("the-state", "the-nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if u.Path != "/authorize" || query.Get("state") != "the-state" || query.Get("nonce") != "the-nonce" || query.Get("client_id") != "yatai" || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected auth code url %s", u)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newStubIdP(t)
+	defer idp.server.Close()
+
+	claims, err := idp.provider(t).Exchange(context.Background(), "the-code", "the-nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.PreferredUsername != "alice" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if groups := claims.StringsClaim("groups"); strings.Join(groups, ",") != "ml,ops" {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+}
+
+func TestExchangeRejectsInvalidTokens(t *testing.T) {
+	cases := map[string]func(idp *stubIdP){
+		"wrong audience": func(idp *stubIdP) {
+			idp.claims["aud"] = "someone-else"
+		},
+		"wrong issuer": func(idp *stubIdP) {
+			idp.claims["iss"] = "https://evil.example.com"
+		},
+		"expired": func(idp *stubIdP) {
+			idp.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		},
+		"not yet valid": func(idp *stubIdP) {
+			idp.claims["nbf"] = time.Now().Add(time.Hour).Unix()
+		},
+		"wrong nonce": func(idp *stubIdP) {
+			idp.claims["nonce"] = "replayed"
+		},
+		"tampered payload": func(idp *stubIdP) {
+			parts := strings.Split(idp.sign(nil, idp.claims), ".")
+			idp.claims["sub"] = "admin"
+			forged := strings.Split(idp.sign(nil, idp.claims), ".")
+			idp.idToken = parts[0] + "." + forged[1] + "." + parts[2]
+		},
+		"alg none": func(idp *stubIdP) {
+			header, _ := json.Marshal(map[string]string{"alg": "none"})
+			payload, _ := json.Marshal(idp.claims)
+			idp.idToken = base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			defer idp.server.Close()
+			provider := idp.provider(t)
+			mutate(idp)
+			if _, err := provider.Exchange(context.Background(), "the-code", "the-nonce"); err == nil {
+				t.Fatal("expected the id token to be rejected")
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsWrongCode(t *testing.T) {
+	idp := newStubIdP(t)
+	defer idp.server.Close()
+
+	if _, err := idp.provider(t).Exchange(context.Background(), "another-code", "the-nonce"); err == nil {
+		t.Fatal("expected the exchange to fail")
+	}
+}
@@ -1,12 +1,16 @@
 package scookie
 
 import (
+	"encoding/json"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	UserNameKey = "username"
+
+	identityProviderLoginStateKeyPrefix = "identity-provider-login-state:"
 )
 
 func SetUsernameToCookie(ctx *gin.Context, username string) error {
@@ -29,3 +33,39 @@ func DeleteUsernameFromCookie(ctx *gin.Context) error {
 	session.Delete(UserNameKey)
 	return session.Save()
 }
+
+// IdentityProviderLoginState is kept in the session between the redirect to the identity provider and its callback
+type IdentityProviderLoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Redirect string `json:"redirect"`
+}
+
+func SetIdentityProviderLoginStateToCookie(ctx *gin.Context, provider string, state *IdentityProviderLoginState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	session := sessions.Default(ctx)
+	session.Set(identityProviderLoginStateKeyPrefix+provider, string(content))
+	return session.Save()
+}
+
+// PopIdentityProviderLoginStateFromCookie returns the login state and removes it, so that a state can only be used once
+func PopIdentityProviderLoginStateFromCookie(ctx *gin.Context, provider string) (*IdentityProviderLoginState, error) {
+	session := sessions.Default(ctx)
+	key := identityProviderLoginStateKeyPrefix + provider
+	content, ok := session.Get(key).(string)
+	if !ok {
+		return nil, nil
+	}
+	session.Delete(key)
+	if err := session.Save(); err != nil {
+		return nil, err
+	}
+	var state IdentityProviderLoginState
+	if err := json.Unmarshal([]byte(content), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
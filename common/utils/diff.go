@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type DiffItem struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal")
+	}
+	var res interface{}
+	err = json.Unmarshal(content, &res)
+	return res, errors.Wrap(err, "unmarshal")
+}
+
+// DiffJSON compares the json representations of old and new, objects are compared by keys and arrays by indexes,
+// except the arrays of named objects such as the runners or the envs which are compared by names, their order is not meaningful.
+// A missing key and a null value are the same
+func DiffJSON(old, new interface{}) ([]*DiffItem, error) {
+	oldValue, err := toJSONValue(old)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := toJSONValue(new)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*DiffItem, 0)
+	diffJSONValue("", oldValue, newValue, &res)
+	return res, nil
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// getNamedJSONItems maps the items of the array by their names, it fails when an item is not an object with a unique name
+func getNamedJSONItems(items []interface{}) (map[string]interface{}, bool) {
+	if len(items) == 0 {
+		return map[string]interface{}{}, true
+	}
+	res := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := object["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := res[name]; ok {
+			return nil, false
+		}
+		res[name] = item
+	}
+	return res, true
+}
+
+func diffJSONValue(path string, old, new interface{}, res *[]*DiffItem) {
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		if newValue, ok := new.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(oldValue)+len(newValue))
+			for key := range oldValue {
+				keys = append(keys, key)
+			}
+			for key := range newValue {
+				if _, ok := oldValue[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				diffJSONValue(joinDiffPath(path, key), oldValue[key], newValue[key], res)
+			}
+			return
+		}
+	case []interface{}:
+		if newValue, ok := new.([]interface{}); ok {
+			oldItems, oldNamed := getNamedJSONItems(oldValue)
+			newItems, newNamed := getNamedJSONItems(newValue)
+			if oldNamed && newNamed {
+				names := make([]string, 0, len(oldItems)+len(newItems))
+				for name := range oldItems {
+					names = append(names, name)
+				}
+				for name := range newItems {
+					if _, ok := oldItems[name]; !ok {
+						names = append(names, name)
+					}
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					diffJSONValue(fmt.Sprintf("%s[%s]", path, name), oldItems[name], newItems[name], res)
+				}
+				return
+			}
+			for idx := 0; idx < len(oldValue) || idx < len(newValue); idx++ {
+				var oldItem, newItem interface{}
+				if idx < len(oldValue) {
+					oldItem = oldValue[idx]
+				}
+				if idx < len(newValue) {
+					newItem = newValue[idx]
+				}
+				diffJSONValue(fmt.Sprintf("%s[%d]", path, idx), oldItem, newItem, res)
+			}
+			return
+		}
+	case nil:
+		if new == nil {
+			return
+		}
+	default:
+		if _, isMap := new.(map[string]interface{}); !isMap {
+			if _, isSlice := new.([]interface{}); !isSlice && old == new {
+				return
+			}
+		}
+	}
+	*res = append(*res, &DiffItem{
+		Path: path,
+		Old:  old,
+		New:  new,
+	})
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	type item struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Ports  []int             `json:"ports"`
+		Note   *string           `json:"note"`
+	}
+	note := "note"
+	diff, err := DiffJSON(&item{
+		Name:   "a",
+		Labels: map[string]string{"env": "dev", "team": "ml"},
+		Ports:  []int{80, 443},
+	}, &item{
+		Name:   "a",
+		Labels: map[string]string{"env": "prod", "team": "ml"},
+		Ports:  []int{80},
+		Note:   &note,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DiffItem{
+		{Path: "labels.env", Old: "dev", New: "prod"},
+		{Path: "note", Old: nil, New: "note"},
+		{Path: "ports[1]", Old: float64(443), New: nil},
+	}
+	if len(diff) != len(expected) {
+		t.Fatalf("expected %d diff items, got %d: %+v", len(expected), len(diff), diff)
+	}
+	for idx, item := range expected {
+		if *diff[idx] != item {
+			t.Fatalf("expected %+v, got %+v", item, *diff[idx])
+		}
+	}
+
+	diff, err = DiffJSON(&item{Name: "a"}, &item{Name: "a", Labels: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Fatalf("expected no diff, got %+v", diff)
+	}
+}
+
+func TestDiffJSONNamedItems(t *testing.T) {
+	type env struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	type runner struct {
+		Name     string `json:"name"`
+		Replicas int    `json:"replicas"`
+		Envs     []env  `json:"envs"`
+	}
+	type spec struct {
+		Runners []runner `json:"runners"`
+	}
+
+	// the runners are built from a map, their order is random
+	diff, err := DiffJSON(&spec{
+		Runners: []runner{
+			{Name: "classifier", Replicas: 1, Envs: []env{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}},
+			{Name: "tokenizer", Replicas: 2},
+		},
+	}, &spec{
+		Runners: []runner{
+			{Name: "tokenizer", Replicas: 2},
+			{Name: "classifier", Replicas: 1, Envs: []env{{Name: "B", Value: "2"}, {Name: "A", Value: "1"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Fatalf("expected no diff between the reordered runners, got %+v", diff)
+	}
+
+	diff, err = DiffJSON(&spec{
+		Runners: []runner{
+			{Name: "classifier", Replicas: 1},
+			{Name: "tokenizer", Replicas: 2},
+		},
+	}, &spec{
+		Runners: []runner{
+			{Name: "tokenizer", Replicas: 3},
+			{Name: "classifier", Replicas: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DiffItem{Path: "runners[tokenizer].replicas", Old: float64(2), New: float64(3)}
+	if len(diff) != 1 || *diff[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, diff)
+	}
+}